@@ -28,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/flare"
 	dogstatsdServer "github.com/DataDog/datadog-agent/comp/dogstatsd/server"
 	dogstatsdDebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	settingshttp "github.com/DataDog/datadog-agent/pkg/config/settings/http"
@@ -49,7 +50,7 @@ import (
 )
 
 // SetupHandlers adds the specific handlers for /agent endpoints
-func SetupHandlers(r *mux.Router, flare flare.Component, server dogstatsdServer.Component, serverDebug dogstatsdDebug.Component) *mux.Router {
+func SetupHandlers(r *mux.Router, flare flare.Component, server dogstatsdServer.Component, serverDebug dogstatsdDebug.Component, forwarder defaultforwarder.Component) *mux.Router {
 	r.HandleFunc("/version", common.GetVersion).Methods("GET")
 	r.HandleFunc("/hostname", getHostname).Methods("GET")
 	r.HandleFunc("/flare", func(w http.ResponseWriter, r *http.Request) { makeFlare(w, r, flare) }).Methods("POST")
@@ -76,10 +77,22 @@ func SetupHandlers(r *mux.Router, flare flare.Component, server dogstatsdServer.
 	if server != nil && serverDebug != nil {
 		r.HandleFunc("/dogstatsd-stats", func(w http.ResponseWriter, r *http.Request) { getDogstatsdStats(w, r, server, serverDebug) }).Methods("GET")
 	}
+	if forwarder != nil {
+		r.HandleFunc("/forwarder/clear-blocked-endpoints", func(w http.ResponseWriter, r *http.Request) { clearBlockedEndpoints(w, r, forwarder) }).Methods("POST")
+	}
 
 	return r
 }
 
+func clearBlockedEndpoints(w http.ResponseWriter, r *http.Request, forwarder defaultforwarder.Component) {
+	forwarder.ClearBlockedEndpoints()
+	log.Infof("Cleared the blocked endpoints of the forwarder")
+
+	w.Header().Set("Content-Type", "application/json")
+	j, _ := json.Marshal("")
+	w.Write(j)
+}
+
 func setJSONError(w http.ResponseWriter, err error, errorCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	body, _ := json.Marshal(map[string]string{"error": err.Error()})
@@ -7,8 +7,13 @@
 package diagnose
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"time"
 
 	"go.uber.org/fx"
 
@@ -16,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core"
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	"github.com/DataDog/datadog-agent/comp/core/log"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
 	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/diagnose"
@@ -39,6 +45,9 @@ type cliParams struct {
 
 	// payloadName is the name of the payload to display
 	payloadName string
+
+	// clearBlocked is the value of the --clear-blocked flag
+	clearBlocked bool
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -78,6 +87,23 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	}
 	diagnoseDatadogConnectivityCommand.PersistentFlags().BoolVarP(&cliParams.noTrace, "no-trace", "", false, "mute extra information about connection establishment, DNS lookup and TLS handshake")
 
+	diagnoseForwarderCommand := &cobra.Command{
+		Use:   "forwarder",
+		Short: "Print the forwarder backoff policy and the retry state of its endpoints",
+		Long:  ``,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fxutil.OneShot(printForwarderBackoff,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: config.NewAgentParamsWithoutSecrets(globalParams.ConfFilePath),
+					LogParams:    log.LogForOneShot("CORE", "off", true)}),
+				core.Bundle,
+			)
+		},
+	}
+
+	diagnoseForwarderCommand.Flags().BoolVarP(&cliParams.clearBlocked, "clear-blocked", "", false, "clear the errors of all the endpoints, so that they are retried right away, before printing their state")
+
 	showPayloadCommand := &cobra.Command{
 		Use:   "show-metadata",
 		Short: "Print metadata payloads sent by the agent",
@@ -133,6 +159,7 @@ This command print the last Inventory metadata payload sent by the Agent. This p
 	}
 	diagnoseCommand.AddCommand(diagnoseMetadataAvailabilityCommand)
 	diagnoseCommand.AddCommand(diagnoseDatadogConnectivityCommand)
+	diagnoseCommand.AddCommand(diagnoseForwarderCommand)
 	diagnoseCommand.AddCommand(showPayloadCommand)
 
 	return []*cobra.Command{diagnoseCommand}
@@ -168,3 +195,61 @@ func printPayload(log log.Component, config config.Component, cliParams *cliPara
 	fmt.Println(string(r))
 	return nil
 }
+
+func printForwarderBackoff(log log.Component, config config.Component, cliParams *cliParams) error {
+	ipcAddress, err := pkgconfig.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+
+	if cliParams.clearBlocked {
+		if err := clearForwarderBlockedEndpoints(ipcAddress, config.GetInt("cmd_port")); err != nil {
+			return err
+		}
+	}
+	expvarURL := fmt.Sprintf("http://%v:%d/debug/vars", ipcAddress, config.GetInt("expvar_port"))
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Get(expvarURL)
+	if err != nil {
+		return fmt.Errorf("Could not fetch the forwarder state, is the agent running? %s", err)
+	}
+	defer resp.Body.Close()
+
+	report, err := decodeForwarderBackoff(resp.Body)
+	if err != nil {
+		return err
+	}
+	return defaultforwarder.RenderBackoffReport(color.Output, report)
+}
+
+// clearForwarderBlockedEndpoints asks the agent to forget the errors of the
+// forwarder endpoints
+func clearForwarderBlockedEndpoints(ipcAddress string, cmdPort int) error {
+	if err := util.SetAuthToken(); err != nil {
+		return err
+	}
+
+	c := util.GetClient(false)
+	urlstr := fmt.Sprintf("https://%v:%d/agent/forwarder/clear-blocked-endpoints", ipcAddress, cmdPort)
+	if _, err := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer([]byte{})); err != nil {
+		return fmt.Errorf("Could not clear the forwarder blocked endpoints: %s", err)
+	}
+	fmt.Fprintln(color.Output, "Cleared the forwarder blocked endpoints")
+	return nil
+}
+
+// decodeForwarderBackoff extracts the forwarder backoff report from the agent expvars
+func decodeForwarderBackoff(r io.Reader) (defaultforwarder.BackoffReport, error) {
+	var vars struct {
+		Forwarder struct {
+			Backoff *defaultforwarder.BackoffReport
+		} `json:"forwarder"`
+	}
+	if err := json.NewDecoder(r).Decode(&vars); err != nil {
+		return defaultforwarder.BackoffReport{}, fmt.Errorf("Could not decode the agent expvars: %s", err)
+	}
+	if vars.Forwarder.Backoff == nil {
+		return defaultforwarder.BackoffReport{}, fmt.Errorf("the forwarder backoff state is not available, the forwarder may not be started")
+	}
+	return *vars.Forwarder.Backoff, nil
+}
@@ -6,6 +6,7 @@
 package diagnose
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -67,3 +68,32 @@ func TestDatadogConnectivityCommand(t *testing.T) {
 			require.Equal(t, true, cliParams.noTrace)
 		})
 }
+
+func TestForwarderCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"diagnose", "forwarder"},
+		printForwarderBackoff,
+		func(cliParams *cliParams, coreParams core.BundleParams) {
+			require.Equal(t, false, coreParams.ConfigLoadSecrets())
+		})
+}
+
+func TestDecodeForwarderBackoff(t *testing.T) {
+	report, err := decodeForwarderBackoff(strings.NewReader(`{
+		"forwarder": {
+			"APIKeyStatus": {},
+			"Backoff": {
+				"Policy": {"MinBackoffFactor": 2, "BaseBackoffTime": 2, "MaxBackoffTime": 64, "RecoveryInterval": 2, "MaxErrors": 6},
+				"Endpoints": [{"Domain": "https://app.datadoghq.com", "Endpoint": "https://app.datadoghq.com/api/v1/series", "NbError": 1, "Blocked": true}]
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, 6, report.Policy.MaxErrors)
+	require.Len(t, report.Endpoints, 1)
+	require.True(t, report.Endpoints[0].Blocked)
+
+	_, err = decodeForwarderBackoff(strings.NewReader(`{"forwarder": {}}`))
+	require.Error(t, err)
+}
@@ -404,7 +404,7 @@ func startAgent(
 	}
 
 	// start the cmd HTTP server
-	if err = api.StartServer(configService, flare, server, capture, serverDebug); err != nil {
+	if err = api.StartServer(configService, flare, server, capture, serverDebug, sharedForwarder); err != nil {
 		return pkglog.Errorf("Error while starting api server, exiting: %v", err)
 	}
 
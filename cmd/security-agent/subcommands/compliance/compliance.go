@@ -41,6 +41,16 @@ func StartCompliance(log log.Component, config config.Component, hostname string
 		return nil, err
 	}
 
+	if socketPath := config.GetString("compliance_config.socket_reporter.path"); socketPath != "" {
+		socketReporter := event.NewSocketReporter(socketPath)
+		stopper.Add(socketReporter)
+		if config.GetBool("compliance_config.socket_reporter.disable_backend") {
+			reporter = socketReporter
+		} else {
+			reporter = event.NewMultiReporter(reporter, socketReporter)
+		}
+	}
+
 	runner := runner.NewRunner()
 	stopper.Add(runner)
 
@@ -49,21 +59,39 @@ func StartCompliance(log log.Component, config config.Component, hostname string
 
 	checkInterval := config.GetDuration("compliance_config.check_interval")
 	checkMaxEvents := config.GetInt("compliance_config.check_max_events_per_run")
+	maxInputBytes := config.GetInt("compliance_config.max_input_bytes")
 	configDir := config.GetString("compliance_config.dir")
 	metricsEnabled := config.GetBool("compliance_config.metrics.enabled")
 
 	options := []checks.BuilderOption{
 		checks.WithInterval(checkInterval),
 		checks.WithMaxEvents(checkMaxEvents),
+		checks.WithMaxInputBytes(maxInputBytes),
 		checks.WithHostname(hostname),
 		checks.WithHostRootMount(os.Getenv("HOST_ROOT")),
 		checks.MayFail(checks.WithDocker()),
 		checks.MayFail(checks.WithAudit()),
 		checks.WithConfigDir(configDir),
+		checks.WithRuntimeMatchSuite(checks.IsFrameworkEnabled(func() []string {
+			return config.GetStringSlice("compliance_config.disabled_frameworks")
+		})),
 	}
 	if metricsEnabled {
 		options = append(options, checks.WithStatsd(statsdClient))
 	}
+	if config.GetBool("compliance_config.cloud.enabled") {
+		options = append(options, checks.MayFail(checks.WithCloud()))
+	}
+	if exceptionsFile := config.GetString("compliance_config.exceptions_file"); exceptionsFile != "" {
+		options = append(options, checks.MayFail(checks.WithExceptionsFile(exceptionsFile)))
+	}
+	if config.GetBool("compliance_config.directory.enabled") {
+		options = append(options, checks.MayFail(checks.WithLDAP(
+			config.GetString("compliance_config.directory.url"),
+			config.GetString("compliance_config.directory.bind_dn"),
+			config.GetString("compliance_config.directory.bind_password"),
+		)))
+	}
 
 	agent, err := agent.New(
 		reporter,
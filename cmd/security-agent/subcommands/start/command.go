@@ -290,7 +290,10 @@ func RunAgent(ctx context.Context, log log.Component, config config.Component, f
 }
 
 func initRuntimeSettings() error {
-	return settings.RegisterRuntimeSetting(settings.LogLevelRuntimeSetting{})
+	if err := settings.RegisterRuntimeSetting(settings.LogLevelRuntimeSetting{}); err != nil {
+		return err
+	}
+	return settings.RegisterRuntimeSetting(settings.ComplianceDisabledFrameworksRuntimeSetting{})
 }
 
 // StopAgent stops the API server and clean up resources
@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+)
+
+// BackoffReport describes the backoff policy of the forwarder and the state of
+// the endpoints it sends to. It is exposed through expvar and rendered by
+// `agent diagnose forwarder`.
+type BackoffReport struct {
+	Policy    backoff.Policy
+	Endpoints []EndpointBackoff
+	// Degraded is true when the endpoints of at least one domain are
+	// reported unhealthy, see blockedEndpoints.Health
+	Degraded bool
+}
+
+// EndpointBackoff is the backoff state of a single endpoint.
+type EndpointBackoff struct {
+	Domain   string
+	Endpoint string
+	NbError  int
+	Until    time.Time
+	Blocked  bool
+	// FirstBlock is when the endpoint started failing, zero when it is not
+	// failing
+	FirstBlock time.Time
+	// LastRecoveryDuration is how long the endpoint took to recover from its
+	// last outage, zero when it never recovered
+	LastRecoveryDuration time.Duration
+	// Queued is the number of transactions to the endpoint waiting in the
+	// retry queue
+	Queued int
+	// Schedule is the range of the delays before the next retries if the
+	// endpoint keeps failing
+	Schedule []BackoffRange
+}
+
+// BackoffRange is the range of the delay applied after NbError errors.
+type BackoffRange struct {
+	NbError int
+	Min     time.Duration
+	Max     time.Duration
+}
+
+// BlockedCount returns the number of endpoints currently blocked.
+func (r BackoffReport) BlockedCount() int {
+	count := 0
+	for _, e := range r.Endpoints {
+		if e.Blocked {
+			count++
+		}
+	}
+	return count
+}
+
+// backoffSchedule returns the delays applied to the next retries of an
+// endpoint with nbError errors, until the maximum backoff is always applied or
+// the maximum number of errors is reached. The delays stay the same after that,
+// however large the maximum number of errors is.
+func backoffSchedule(policy backoff.Policy, nbError int) []BackoffRange {
+	maxBackoff := time.Duration(policy.MaxBackoffTime * float64(time.Second))
+	var schedule []BackoffRange
+	for {
+		nbError = policy.IncError(nbError)
+		min, max := policy.GetBackoffRange(nbError)
+		schedule = append(schedule, BackoffRange{NbError: nbError, Min: min, Max: max})
+		if nbError >= policy.MaxErrors || min >= maxBackoff {
+			return schedule
+		}
+	}
+}
+
+// report returns the backoff policy and the state of the endpoints known by e,
+// with the number of their transactions waiting in the retry queue taken from
+// queued.
+func (e *blockedEndpoints) report(domain string, queued map[string]int) (backoff.Policy, []EndpointBackoff) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	status := e.blockedStatus()
+	endpoints := make([]EndpointBackoff, 0, len(status))
+	for endpoint, info := range status {
+		endpoints = append(endpoints, EndpointBackoff{
+			Domain:               domain,
+			Endpoint:             endpoint,
+			NbError:              info.NbError,
+			Until:                info.Until,
+			Blocked:              info.Blocked,
+			FirstBlock:           info.FirstBlock,
+			LastRecoveryDuration: info.LastRecoveryDuration,
+			Queued:               queued[endpoint],
+			Schedule:             backoffSchedule(e.policyFor(endpoint), info.NbError),
+		})
+	}
+	return e.backoffPolicy, endpoints
+}
+
+// BackoffReport returns the backoff policy and the state of the endpoints of
+// the forwarder.
+func (f *DefaultForwarder) BackoffReport() BackoffReport {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	var report BackoffReport
+	// several domains can share the same domainForwarder
+	seen := map[*domainForwarder]struct{}{}
+	for domain, df := range f.domainForwarders {
+		if _, ok := seen[df]; ok {
+			continue
+		}
+		seen[df] = struct{}{}
+		policy, endpoints := df.blockedList.report(domain, df.retryQueue.GetTransactionCountByTarget())
+		report.Policy = policy
+		report.Endpoints = append(report.Endpoints, endpoints...)
+		if !df.blockedList.Health() {
+			report.Degraded = true
+		}
+	}
+
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		if report.Endpoints[i].Domain != report.Endpoints[j].Domain {
+			return report.Endpoints[i].Domain < report.Endpoints[j].Domain
+		}
+		return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint
+	})
+	return report
+}
+
+// BlockedEndpointsCount returns the number of endpoints of the forwarder
+// currently blocked.
+func (f *DefaultForwarder) BlockedEndpointsCount() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	count := 0
+	// several domains can share the same domainForwarder
+	seen := map[*domainForwarder]struct{}{}
+	for _, df := range f.domainForwarders {
+		if _, ok := seen[df]; ok {
+			continue
+		}
+		seen[df] = struct{}{}
+		count += df.blockedList.BlockedCount()
+	}
+	return count
+}
+
+// backlog returns, for each endpoint currently blocked, the number of its
+// transactions waiting in the retry queue, taken from queued.
+func (e *blockedEndpoints) backlog(queued map[string]int) map[string]int {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	now := e.clock.Now()
+	backlog := make(map[string]int)
+	for endpoint, b := range e.errorPerEndpoint {
+		if blocked, _ := b.blockedUntil(now, true); blocked {
+			backlog[endpoint] = queued[endpoint]
+		}
+	}
+	return backlog
+}
+
+// BlockedEndpointsBacklog returns, for each endpoint of the forwarder currently
+// blocked, the number of its transactions waiting in the retry queue. A growing
+// backlog tells a block is delaying traffic rather than a few retries.
+func (f *DefaultForwarder) BlockedEndpointsBacklog() map[string]int {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	backlog := make(map[string]int)
+	// several domains can share the same domainForwarder
+	seen := map[*domainForwarder]struct{}{}
+	for _, df := range f.domainForwarders {
+		if _, ok := seen[df]; ok {
+			continue
+		}
+		seen[df] = struct{}{}
+		for endpoint, count := range df.blockedList.backlog(df.retryQueue.GetTransactionCountByTarget()) {
+			backlog[endpoint] += count
+		}
+	}
+	return backlog
+}
+
+// ClearBlockedEndpoints forgets the errors of all the endpoints of the
+// forwarder, so that the transactions waiting for them are retried right away.
+func (f *DefaultForwarder) ClearBlockedEndpoints() {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for _, df := range f.domainForwarders {
+		df.blockedList.resetAll()
+	}
+}
+
+// RenderBackoffReport writes a human readable version of the report to w.
+func RenderBackoffReport(w io.Writer, r BackoffReport) error {
+	fmt.Fprintf(w, "Backoff policy:\n")
+	fmt.Fprintf(w, "  Base backoff time: %v\n", secondsToDuration(r.Policy.BaseBackoffTime))
+	fmt.Fprintf(w, "  Max backoff time: %v\n", secondsToDuration(r.Policy.MaxBackoffTime))
+	fmt.Fprintf(w, "  Min backoff factor: %v\n", r.Policy.MinBackoffFactor)
+	fmt.Fprintf(w, "  Recovery interval: %v\n", r.Policy.RecoveryInterval)
+	fmt.Fprintf(w, "  Max errors: %v\n", r.Policy.MaxErrors)
+	status := "OK"
+	if r.Degraded {
+		status = "Degraded"
+	}
+	fmt.Fprintf(w, "\nStatus: %s, %d/%d endpoint(s) blocked\n", status, r.BlockedCount(), len(r.Endpoints))
+
+	if len(r.Endpoints) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DOMAIN\tENDPOINT\tERRORS\tBLOCKED\tQUEUED\tUNTIL\tFAILING SINCE\tLAST RECOVERY\tNEXT RETRIES\n")
+	for _, e := range r.Endpoints {
+		until := "-"
+		if !e.Until.IsZero() {
+			until = e.Until.Format(time.RFC3339)
+		}
+		failingSince := "-"
+		if !e.FirstBlock.IsZero() {
+			failingSince = e.FirstBlock.Format(time.RFC3339)
+		}
+		lastRecovery := "-"
+		if e.LastRecoveryDuration > 0 {
+			lastRecovery = e.LastRecoveryDuration.String()
+		}
+		schedule := make([]string, 0, len(e.Schedule))
+		for _, s := range e.Schedule {
+			schedule = append(schedule, formatBackoffRange(s))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%t\t%d\t%s\t%s\t%s\t%s\n", e.Domain, e.Endpoint, e.NbError, e.Blocked, e.Queued, until, failingSince, lastRecovery, strings.Join(schedule, ", "))
+	}
+	return tw.Flush()
+}
+
+func formatBackoffRange(r BackoffRange) string {
+	if r.Min == r.Max {
+		return r.Max.String()
+	}
+	return fmt.Sprintf("%v-%v", r.Min, r.Max)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+)
+
+func TestBackoffSchedule(t *testing.T) {
+	policy := backoff.NewPolicy(2, 2, 64, 2, false)
+
+	schedule := backoffSchedule(policy, 3)
+	assert.Equal(t, []BackoffRange{
+		{NbError: 4, Min: 16 * time.Second, Max: 32 * time.Second},
+		{NbError: 5, Min: 32 * time.Second, Max: 64 * time.Second},
+		{NbError: 6, Min: 64 * time.Second, Max: 64 * time.Second},
+	}, schedule)
+
+	schedule = backoffSchedule(policy, policy.MaxErrors)
+	assert.Equal(t, []BackoffRange{
+		{NbError: 6, Min: 64 * time.Second, Max: 64 * time.Second},
+	}, schedule)
+
+	assert.Len(t, backoffSchedule(backoff.Policy{}, 0), 1)
+
+	// the schedule ends once the maximum backoff is always applied
+	policy.MaxErrors = 1000000
+	schedule = backoffSchedule(policy, 3)
+	assert.Equal(t, []BackoffRange{
+		{NbError: 4, Min: 16 * time.Second, Max: 32 * time.Second},
+		{NbError: 5, Min: 32 * time.Second, Max: 64 * time.Second},
+		{NbError: 6, Min: 64 * time.Second, Max: 64 * time.Second},
+	}, schedule)
+}
+
+func TestBlockedEndpointsReport(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	e.close("recovered")
+	e.recover("recovered")
+
+	policy, report := e.report("domain", map[string]int{"test": 3})
+	assert.Equal(t, e.backoffPolicy, policy)
+	require.Len(t, report, 2)
+	for _, endpoint := range report {
+		assert.Equal(t, "domain", endpoint.Domain)
+		switch endpoint.Endpoint {
+		case "test":
+			assert.Equal(t, 1, endpoint.NbError)
+			assert.True(t, endpoint.Blocked)
+			assert.Equal(t, 3, endpoint.Queued)
+			assert.Equal(t, e.errorPerEndpoint["test"].until, endpoint.Until)
+			assert.Equal(t, 2, endpoint.Schedule[0].NbError)
+		case "recovered":
+			assert.Equal(t, 0, endpoint.NbError)
+			assert.False(t, endpoint.Blocked)
+			assert.Equal(t, 1, endpoint.Schedule[0].NbError)
+		default:
+			t.Fatalf("unexpected endpoint %s", endpoint.Endpoint)
+		}
+	}
+}
+
+func TestRenderBackoffReport(t *testing.T) {
+	policy := backoff.NewPolicy(2, 2, 64, 2, false)
+	report := BackoffReport{
+		Policy: policy,
+		Endpoints: []EndpointBackoff{
+			{
+				Domain:     "https://app.datadoghq.com",
+				Endpoint:   "https://app.datadoghq.com/api/v1/series",
+				NbError:    3,
+				Until:      time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+				Blocked:    true,
+				Queued:     42,
+				Schedule:   backoffSchedule(policy, 3),
+				FirstBlock: time.Date(2023, 1, 2, 3, 0, 0, 0, time.UTC),
+			},
+			{
+				Domain:               "https://app.datadoghq.com",
+				Endpoint:             "https://app.datadoghq.com/api/v1/check_run",
+				Schedule:             backoffSchedule(policy, 0),
+				LastRecoveryDuration: 90 * time.Second,
+			},
+		},
+	}
+	assert.Equal(t, 1, report.BlockedCount())
+
+	var b strings.Builder
+	require.NoError(t, RenderBackoffReport(&b, report))
+	out := b.String()
+
+	assert.Contains(t, out, "Max backoff time: 1m4s")
+	assert.Contains(t, out, "Status: OK, 1/2 endpoint(s) blocked")
+
+	report.Degraded = true
+	b.Reset()
+	require.NoError(t, RenderBackoffReport(&b, report))
+	assert.Contains(t, b.String(), "Status: Degraded")
+	assert.Contains(t, out, "https://app.datadoghq.com/api/v1/series")
+	assert.Contains(t, out, "QUEUED")
+	assert.Contains(t, out, "true     42      2023-01-02T03:04:05Z  2023-01-02T03:00:00Z  -")
+	assert.Contains(t, out, "-                     1m30s")
+	assert.Contains(t, out, "16s-32s, 32s-1m4s, 1m4s")
+	assert.Contains(t, out, "2s-4s, 4s-8s, 8s-16s, 16s-32s, 32s-1m4s, 1m4s")
+}
+
+func TestClearBlockedEndpoints(t *testing.T) {
+	mockConfig := config.Mock(t)
+	forwarder := NewDefaultForwarder(mockConfig, NewOptionsWithResolvers(mockConfig, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	require.Len(t, forwarder.domainForwarders, 2)
+
+	for domain, df := range forwarder.domainForwarders {
+		df.blockedList.close(domain + "/api/v1/series")
+	}
+	assert.Equal(t, 2, forwarder.BackoffReport().BlockedCount())
+
+	forwarder.ClearBlockedEndpoints()
+	for domain, df := range forwarder.domainForwarders {
+		assert.False(t, df.blockedList.isBlock(domain+"/api/v1/series"))
+	}
+	report := forwarder.BackoffReport()
+	assert.Equal(t, 0, report.BlockedCount())
+	assert.Empty(t, report.Endpoints)
+}
+
+func TestBlockedEndpointsCount(t *testing.T) {
+	mockConfig := config.Mock(t)
+	forwarder := NewDefaultForwarder(mockConfig, NewOptionsWithResolvers(mockConfig, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	require.Len(t, forwarder.domainForwarders, 2)
+	assert.Equal(t, 0, forwarder.BlockedEndpointsCount())
+
+	for domain, df := range forwarder.domainForwarders {
+		df.blockedList.close(domain + "/api/v1/series")
+		df.blockedList.close(domain + "/api/v1/check_run")
+		// an expired block is tracked but not counted
+		df.blockedList.errorPerEndpoint[domain+"/intake/"] = &block{nbError: 1, until: time.Now().Add(-time.Minute)}
+	}
+	assert.Equal(t, 4, forwarder.BlockedEndpointsCount())
+	assert.Len(t, forwarder.BackoffReport().Endpoints, 6)
+}
+
+func TestBlockedEndpointsBacklog(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("blocked")
+	e.close("idle")
+	e.close("recovered")
+	e.recover("recovered")
+	queued := map[string]int{"blocked": 5, "recovered": 2, "unknown": 1}
+
+	assert.Equal(t, map[string]int{"blocked": 5, "idle": 0}, e.backlog(queued))
+
+	// the backlog is only reported while the endpoints are blocked
+	mock.Add(time.Hour)
+	assert.Empty(t, e.backlog(queued))
+}
+
+func TestForwarderBlockedEndpointsBacklog(t *testing.T) {
+	mockConfig := config.Mock(t)
+	forwarder := NewDefaultForwarder(mockConfig, NewOptionsWithResolvers(mockConfig, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	require.Len(t, forwarder.domainForwarders, 2)
+	assert.Empty(t, forwarder.BlockedEndpointsBacklog())
+
+	expected := map[string]int{}
+	for domain, df := range forwarder.domainForwarders {
+		series := transaction.NewHTTPTransaction()
+		series.Domain = domain
+		series.Endpoint.Route = "/api/v1/series"
+		series.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte{1})
+		checkRun := transaction.NewHTTPTransaction()
+		checkRun.Domain = domain
+		checkRun.Endpoint.Route = "/api/v1/check_run"
+		checkRun.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte{1})
+
+		df.blockedList.close(series.GetTarget())
+		df.requeueTransaction(series)
+		df.requeueTransaction(series)
+		// the transactions of the endpoints not blocked are not reported
+		df.requeueTransaction(checkRun)
+		expected[series.GetTarget()] = 2
+	}
+	assert.Equal(t, expected, forwarder.BlockedEndpointsBacklog())
+
+	for _, endpoint := range forwarder.BackoffReport().Endpoints {
+		assert.Equal(t, 2, endpoint.Queued, endpoint.Endpoint)
+	}
+}
+
+func TestBlockedEndpointsBacklogExpvar(t *testing.T) {
+	mockConfig := config.Mock(t)
+	forwarder := NewDefaultForwarder(mockConfig, NewOptionsWithResolvers(mockConfig, resolver.NewSingleDomainResolvers(monoKeysDomains)))
+	require.NoError(t, forwarder.Start())
+	defer forwarder.Stop()
+	require.Len(t, forwarder.domainForwarders, 1)
+
+	backlog := func() map[string]int {
+		var backlog map[string]int
+		require.NoError(t, json.Unmarshal([]byte(transaction.ForwarderExpvars.Get("BlockedEndpointsBacklog").String()), &backlog))
+		return backlog
+	}
+	assert.Empty(t, backlog())
+
+	for domain, df := range forwarder.domainForwarders {
+		series := transaction.NewHTTPTransaction()
+		series.Domain = domain
+		series.Endpoint.Route = "/api/v1/series"
+		series.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte{1})
+
+		df.blockedList.close(series.GetTarget())
+		df.requeueTransaction(series)
+		assert.Equal(t, map[string]int{series.GetTarget(): 1}, backlog())
+	}
+}
+
+func TestPolicySnapshot(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_backoff_factor", 4)
+	mockConfig.Set("forwarder_backoff_base", 4)
+	mockConfig.Set("forwarder_backoff_max", 128)
+	mockConfig.Set("forwarder_recovery_interval", 1)
+	e := newBlockedEndpoints(mockConfig)
+
+	policy := e.PolicySnapshot()
+	assert.Equal(t, float64(4), policy.MinBackoffFactor)
+	assert.Equal(t, float64(4), policy.BaseBackoffTime)
+	assert.Equal(t, float64(128), policy.MaxBackoffTime)
+	assert.Equal(t, 1, policy.RecoveryInterval)
+	assert.Equal(t, 6, policy.MaxErrors)
+
+	// invalid values are reported as the defaults they fall back to
+	mockConfig.Set("forwarder_backoff_factor", 1)
+	mockConfig.Set("forwarder_backoff_base", 0)
+	mockConfig.Set("forwarder_backoff_max", 0)
+	mockConfig.Set("forwarder_recovery_interval", 0)
+	e = newBlockedEndpoints(mockConfig)
+
+	data, err := json.Marshal(e.PolicySnapshot())
+	require.NoError(t, err)
+	var snapshot map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	assert.Equal(t, float64(2), snapshot["MinBackoffFactor"])
+	assert.Equal(t, float64(2), snapshot["BaseBackoffTime"])
+	assert.Equal(t, float64(64), snapshot["MaxBackoffTime"])
+	assert.Equal(t, float64(2), snapshot["RecoveryInterval"])
+	assert.Equal(t, float64(6), snapshot["MaxErrors"])
+}
@@ -6,9 +6,16 @@
 package defaultforwarder
 
 import (
+	"context"
+	"math"
+	"math/rand"
+	"net/url"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
+
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/backoff"
@@ -18,15 +25,107 @@ import (
 type block struct {
 	nbError int
 	until   time.Time
+
+	// maintenanceInterval, when set, replaces the error-driven backoff by a
+	// fixed retry interval
+	maintenanceInterval time.Duration
+
+	// firstBlock is when the endpoint started failing, zero once it recovered
+	firstBlock time.Time
+	// lastRecovery is how long the endpoint took to recover from its last outage
+	lastRecovery time.Duration
+
+	// stableUntil ends the half-open period started by the first success
+	// following errors: until then, the successes only decay the error count
+	// and an error resumes the backoff from there
+	stableUntil time.Time
+
+	// probing is set while the single transaction let through once the block
+	// expired is being sent, until probeDeadline at most, see isBlockProbe
+	probing       bool
+	probeDeadline time.Time
+
+	// non-idempotent failures are accounted separately and only block
+	// non-idempotent traffic
+	nonIdempotentErrors int
+	nonIdempotentUntil  time.Time
+
+	// token bucket limiting the send rate, see Allow
+	tokens     float64
+	lastRefill time.Time
 }
 
 type blockedEndpoints struct {
 	errorPerEndpoint map[string]*block
-	backoffPolicy    backoff.Policy
-	m                sync.RWMutex
+	// maxEndpoints is the number of endpoints above which the recovered ones
+	// are purged from errorPerEndpoint
+	maxEndpoints int
+	// purgeAfter is the earliest time a purge can forget an endpoint, so that
+	// errorPerEndpoint is not scanned again on each new endpoint while all the
+	// known ones are still failing, see schedulePurge
+	purgeAfter    time.Time
+	backoffPolicy backoff.Policy
+	// domainPolicies replace backoffPolicy for the endpoints of their domain
+	domainPolicies map[string]backoff.Policy
+	stablePeriod   time.Duration
+	// halfOpenProbe lets a single probe through once a block expired, the
+	// other transactions staying blocked until it succeeds or fails, or
+	// probeTimeout elapsed
+	halfOpenProbe bool
+	probeTimeout  time.Duration
+	clock         clock.Clock
+	// rand draws the random part of the backoff durations, it is only used
+	// with m held for writing
+	rand *rand.Rand
+	// m is a leaf lock: it is never held while acquiring another forwarder
+	// lock, sending on a channel or calling the OnBlock/OnRecover hooks, so
+	// it can be taken while holding any other forwarder lock. The context-aware
+	// methods do nothing when their context is already canceled, see
+	// lockContext.
+	m sync.RWMutex
+
+	// rate is the maximum number of sends per second to an endpoint, 0
+	// meaning no limit
+	rate float64
+
+	// health hysteresis: the endpoints are degraded once the fraction of
+	// failing endpoints exceeds degradedThreshold, and recover once it stayed
+	// below recoveredThreshold for recoveryDuration. The state is updated when
+	// an endpoint starts failing or recovers, see updateHealth.
+	degradedThreshold  float64
+	recoveredThreshold float64
+	recoveryDuration   time.Duration
+	degraded           bool
+	healthySince       time.Time
+	// known holds all the endpoints ever seen, including the ones purged from
+	// errorPerEndpoint, and failing the number of them failing since their
+	// first error, so that a purge does not change the failing fraction
+	known   map[string]struct{}
+	failing int
+
+	// hooks called, without holding m, when an endpoint starts failing and
+	// when it recovers, see OnBlock and OnRecover
+	onBlock   []func(endpoint string, until time.Time)
+	onRecover []func(endpoint string)
 }
 
 func newBlockedEndpoints(config config.Component) *blockedEndpoints {
+	return newBlockedEndpointsWithClock(config, clock.New())
+}
+
+// newBlockedEndpointsWithClock is newBlockedEndpoints, reading the current
+// time from clk to compute and check the blocks.
+func newBlockedEndpointsWithClock(config config.Component, clk clock.Clock) *blockedEndpoints {
+	return newBlockedEndpointsWith(config, rand.New(rand.NewSource(time.Now().UnixNano())), clk)
+}
+
+// newBlockedEndpointsWithRand is newBlockedEndpoints, drawing the random part
+// of the backoff durations from r.
+func newBlockedEndpointsWithRand(config config.Component, r *rand.Rand) *blockedEndpoints {
+	return newBlockedEndpointsWith(config, r, clock.New())
+}
+
+func newBlockedEndpointsWith(config config.Component, r *rand.Rand, clk clock.Clock) *blockedEndpoints {
 	backoffFactor := config.GetFloat64("forwarder_backoff_factor")
 	if backoffFactor < 2 {
 		log.Warnf("Configured forwarder_backoff_factor (%v) is less than 2; 2 will be used", backoffFactor)
@@ -45,6 +144,27 @@ func newBlockedEndpoints(config config.Component) *blockedEndpoints {
 		backoffMax = 64
 	}
 
+	backoffJitter := config.GetFloat64("forwarder_backoff_jitter")
+	if backoffJitter < 0 || backoffJitter > 1 {
+		log.Warnf("Configured forwarder_backoff_jitter (%v) is not in [0, 1]; 1 will be used", backoffJitter)
+		backoffJitter = 1
+	}
+
+	backoffMinInterval := config.GetFloat64("forwarder_backoff_min_interval")
+	if backoffMinInterval < 0 {
+		log.Warnf("Configured forwarder_backoff_min_interval (%v) is negative; 0 will be used", backoffMinInterval)
+		backoffMinInterval = 0
+	} else if backoffMinInterval > backoffMax {
+		log.Warnf("Configured forwarder_backoff_min_interval (%v) is greater than forwarder_backoff_max; %v will be used", backoffMinInterval, backoffMax)
+		backoffMinInterval = backoffMax
+	}
+
+	backoffMaxErrors := config.GetInt("forwarder_backoff_max_errors")
+	if backoffMaxErrors < 0 {
+		log.Warnf("Configured forwarder_backoff_max_errors (%v) is negative; the number of errors to reach forwarder_backoff_max will be used", backoffMaxErrors)
+		backoffMaxErrors = 0
+	}
+
 	recInterval := config.GetInt("forwarder_recovery_interval")
 	if recInterval <= 0 {
 		log.Warnf("Configured forwarder_recovery_interval (%v) is not positive; %v will be used", recInterval, pkgconfig.DefaultForwarderRecoveryInterval)
@@ -52,57 +172,741 @@ func newBlockedEndpoints(config config.Component) *blockedEndpoints {
 	}
 
 	recoveryReset := config.GetBool("forwarder_recovery_reset")
+	recoveryHalve := false
+	switch recoveryMode := config.GetString("forwarder_recovery_mode"); recoveryMode {
+	case "":
+	case "linear":
+		recoveryReset = false
+	case "reset":
+		recoveryReset = true
+	case "halve":
+		recoveryReset = false
+		recoveryHalve = true
+	default:
+		log.Warnf("Configured forwarder_recovery_mode (%v) is not one of linear, reset or halve; forwarder_recovery_reset will be used", recoveryMode)
+	}
+
+	stablePeriod := config.GetInt("forwarder_recover_stable_period")
+	if stablePeriod < 0 {
+		log.Warnf("Configured forwarder_recover_stable_period (%v) is negative; 0 will be used", stablePeriod)
+		stablePeriod = 0
+	}
+
+	probeTimeout := config.GetInt("forwarder_timeout")
+	if probeTimeout <= 0 {
+		probeTimeout = 20
+	}
+
+	rate := config.GetFloat64("forwarder_endpoint_rate")
+	if rate < 0 {
+		log.Warnf("Configured forwarder_endpoint_rate (%v) is negative; 0 will be used", rate)
+		rate = 0
+	}
+
+	maxEndpoints := config.GetInt("forwarder_blocked_endpoints_max_size")
+	if maxEndpoints <= 0 {
+		log.Warnf("Configured forwarder_blocked_endpoints_max_size (%v) is not positive; %v will be used", maxEndpoints, pkgconfig.DefaultForwarderBlockedEndpointsMaxSize)
+		maxEndpoints = pkgconfig.DefaultForwarderBlockedEndpointsMaxSize
+	}
+
+	degradedThreshold := config.GetFloat64("forwarder_health_degraded_threshold")
+	if degradedThreshold <= 0 || degradedThreshold > 1 {
+		log.Warnf("Configured forwarder_health_degraded_threshold (%v) is not in ]0, 1]; 0.5 will be used", degradedThreshold)
+		degradedThreshold = 0.5
+	}
+
+	recoveredThreshold := config.GetFloat64("forwarder_health_recovered_threshold")
+	if recoveredThreshold < 0 || recoveredThreshold > degradedThreshold {
+		log.Warnf("Configured forwarder_health_recovered_threshold (%v) is not in [0, %v]; %v will be used", recoveredThreshold, degradedThreshold, degradedThreshold)
+		recoveredThreshold = degradedThreshold
+	}
+
+	recoveryDuration := config.GetInt("forwarder_health_recovery_duration")
+	if recoveryDuration < 0 {
+		log.Warnf("Configured forwarder_health_recovery_duration (%v) is negative; 0 will be used", recoveryDuration)
+		recoveryDuration = 0
+	}
+
+	backoffPolicy := backoff.NewPolicy(backoffFactor, backoffBase, backoffMax, recInterval, recoveryReset)
+	backoffPolicy.Jitter = backoffJitter
+	backoffPolicy.HalveErrors = recoveryHalve
+	backoffPolicy.MinBackoffTime = backoffMinInterval
+	if backoffMaxErrors > 0 {
+		backoffPolicy.MaxErrors = backoffMaxErrors
+		if recoveryReset {
+			backoffPolicy.RecoveryInterval = backoffMaxErrors
+		}
+	}
 
 	return &blockedEndpoints{
-		errorPerEndpoint: make(map[string]*block),
-		backoffPolicy:    backoff.NewPolicy(backoffFactor, backoffBase, backoffMax, recInterval, recoveryReset),
+		errorPerEndpoint:   make(map[string]*block),
+		known:              make(map[string]struct{}),
+		maxEndpoints:       maxEndpoints,
+		backoffPolicy:      backoffPolicy,
+		stablePeriod:       time.Duration(stablePeriod) * time.Second,
+		halfOpenProbe:      config.GetBool("forwarder_half_open_probe"),
+		probeTimeout:       time.Duration(probeTimeout) * time.Second,
+		clock:              clk,
+		rand:               r,
+		rate:               rate,
+		degradedThreshold:  degradedThreshold,
+		recoveredThreshold: recoveredThreshold,
+		recoveryDuration:   time.Duration(recoveryDuration) * time.Second,
 	}
 }
 
-func (e *blockedEndpoints) close(endpoint string) {
+// getBlock returns the block of the endpoint, creating it if needed. When the
+// number of endpoints reaches maxEndpoints, the ones fully recovered are purged
+// first. maxEndpoints is a soft cap: the endpoints still blocked or rate
+// limited are kept even if it is exceeded. e.m must be held for writing by the
+// caller.
+func (e *blockedEndpoints) getBlock(endpoint string) *block {
+	if b, ok := e.errorPerEndpoint[endpoint]; ok {
+		return b
+	}
+
+	if len(e.errorPerEndpoint) >= e.maxEndpoints {
+		if now := e.clock.Now(); !now.Before(e.purgeAfter) {
+			e.purge(now)
+		}
+	}
+
+	b := &block{}
+	e.errorPerEndpoint[endpoint] = b
+	e.known[endpoint] = struct{}{}
+	return b
+}
+
+// noPurge is the purgeAfter of the endpoints that only a success can let be
+// forgotten
+var noPurge = time.Unix(1<<62, 0)
+
+// purge forgets the endpoints fully recovered at now, and delays the next
+// purge until one of the endpoints kept can have recovered. e.m must be held
+// for writing by the caller.
+func (e *blockedEndpoints) purge(now time.Time) {
+	e.purgeAfter = noPurge
+	for known, b := range e.errorPerEndpoint {
+		if e.isRecovered(b, now) {
+			delete(e.errorPerEndpoint, known)
+		} else {
+			e.schedulePurge(b)
+		}
+	}
+}
+
+// schedulePurge brings the next purge forward to when b can be forgotten, if
+// it does not need a success for that. It must be called once b changed in a
+// way that may let it be forgotten earlier. e.m must be held for writing by the
+// caller.
+func (e *blockedEndpoints) schedulePurge(b *block) {
+	if b.nbError > 0 || b.nonIdempotentErrors > 0 || b.maintenanceInterval > 0 || !b.firstBlock.IsZero() {
+		return
+	}
+	at := b.until
+	for _, t := range []time.Time{b.nonIdempotentUntil, b.stableUntil} {
+		if t.After(at) {
+			at = t
+		}
+	}
+	if b.probing && b.probeDeadline.After(at) {
+		at = b.probeDeadline
+	}
+	// the token bucket of the endpoint must be full again
+	if e.rate > 0 && !b.lastRefill.IsZero() {
+		if missing := math.Max(e.rate, 1) - b.tokens; missing > 0 {
+			if full := b.lastRefill.Add(time.Duration(missing / e.rate * float64(time.Second))); full.After(at) {
+				at = full
+			}
+		}
+	}
+	if at.Before(e.purgeAfter) {
+		e.purgeAfter = at
+	}
+}
+
+// isRecovered returns whether forgetting the block would not change how its
+// endpoint is handled. A failing endpoint is kept until it recovers, so that
+// it stays counted in the health.
+func (e *blockedEndpoints) isRecovered(b *block, now time.Time) bool {
+	if b.nbError > 0 || b.nonIdempotentErrors > 0 || b.maintenanceInterval > 0 || !b.firstBlock.IsZero() {
+		return false
+	}
+	if now.Before(b.until) || now.Before(b.nonIdempotentUntil) || now.Before(b.stableUntil) || b.isProbing(now) {
+		return false
+	}
+	// the token bucket of the endpoint must be full again
+	return e.rate <= 0 || b.lastRefill.IsZero() || b.tokens+now.Sub(b.lastRefill).Seconds()*e.rate >= math.Max(e.rate, 1)
+}
+
+// lockContext acquires the write lock, unless ctx is already canceled. It
+// returns whether the lock was acquired. A cancellation while waiting for the
+// lock is not noticed: m is only held for short, non-blocking updates, see the
+// lock ordering documented on m.
+func (e *blockedEndpoints) lockContext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
 	e.m.Lock()
-	defer e.m.Unlock()
+	return true
+}
 
-	var b *block
-	if knownBlock, ok := e.errorPerEndpoint[endpoint]; ok {
-		b = knownBlock
-	} else {
-		b = &block{}
+func (e *blockedEndpoints) close(endpoint string) {
+	e.closeWithRetryAfterContext(context.Background(), endpoint, 0)
+}
+
+// closeContext is close, doing nothing when ctx is canceled. It returns
+// whether the error was recorded.
+func (e *blockedEndpoints) closeContext(ctx context.Context, endpoint string) bool {
+	return e.closeWithRetryAfterContext(ctx, endpoint, 0)
+}
+
+// closeWithRetryAfter records an error for the endpoint like close, but keeps
+// it blocked for at least retryAfter when it is longer than the computed backoff.
+// retryAfter is capped to the maximum backoff of the endpoint policy.
+func (e *blockedEndpoints) closeWithRetryAfter(endpoint string, retryAfter time.Duration) {
+	e.closeWithRetryAfterContext(context.Background(), endpoint, retryAfter)
+}
+
+// closeWithRetryAfterContext is closeWithRetryAfter, doing nothing when ctx is
+// canceled. It returns whether the error was recorded.
+func (e *blockedEndpoints) closeWithRetryAfterContext(ctx context.Context, endpoint string, retryAfter time.Duration) bool {
+	if !e.lockContext(ctx) {
+		return false
 	}
+	blocked, until := e.closeLocked(endpoint, retryAfter)
+	hooks := e.onBlock
+	e.m.Unlock()
 
-	b.nbError = e.backoffPolicy.IncError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+	if blocked {
+		for _, hook := range hooks {
+			hook(endpoint, until)
+		}
+	}
+	return true
+}
 
-	e.errorPerEndpoint[endpoint] = b
+// closeLocked records an error for the endpoint. It returns whether the
+// endpoint just started failing, and the end of its block. e.m must be held
+// for writing by the caller.
+func (e *blockedEndpoints) closeLocked(endpoint string, retryAfter time.Duration) (bool, time.Time) {
+	b := e.getBlock(endpoint)
+	b.probing = false
+
+	blocked := b.firstBlock.IsZero()
+	if blocked {
+		b.firstBlock = e.clock.Now()
+		tlmEndpointBlocked.Inc(endpointDomain(endpoint))
+		e.failing++
+		e.updateHealth(b.firstBlock)
+	}
+
+	if b.maintenanceInterval > 0 {
+		b.until = e.clock.Now().Add(b.maintenanceInterval)
+		return blocked, b.until
+	}
+
+	// an error restarts the stable period with the next success
+	b.stableUntil = time.Time{}
+
+	policy := e.policyFor(endpoint)
+	b.nbError = policy.IncError(b.nbError)
+	backoffDuration := withRetryAfter(endpoint, policy, policy.GetBackoffDurationFrom(e.rand, b.nbError), retryAfter)
+	b.until = e.clock.Now().Add(backoffDuration)
+	return blocked, b.until
+}
+
+// withRetryAfter returns how long the endpoint is blocked after an error
+// answered with a Retry-After of retryAfter, when the policy computed
+// backoffDuration: retryAfter wins when it is longer, capped to the maximum
+// backoff of the policy.
+func withRetryAfter(endpoint string, policy backoff.Policy, backoffDuration, retryAfter time.Duration) time.Duration {
+	// a Retry-After beyond the longest backoff would silence the endpoint for
+	// as long as the intake asks, so it is capped like the backoff
+	if maxRetryAfter := time.Duration(policy.MaxBackoffTime * float64(time.Second)); retryAfter > maxRetryAfter {
+		log.Debugf("Retry-After of %s for %s exceeds the maximum backoff; %s will be used", retryAfter, endpoint, maxRetryAfter)
+		retryAfter = maxRetryAfter
+	}
+	if retryAfter > backoffDuration {
+		return retryAfter
+	}
+	return backoffDuration
+}
+
+// closeNonIdempotent records an error for a non-idempotent payload sent to the
+// endpoint. It only blocks non-idempotent traffic, see isBlockNonIdempotent.
+func (e *blockedEndpoints) closeNonIdempotent(endpoint string) {
+	e.closeNonIdempotentContext(context.Background(), endpoint, 0)
+}
+
+// closeNonIdempotentContext is closeNonIdempotent, doing nothing when ctx is
+// canceled. Like closeWithRetryAfter, the non-idempotent traffic stays blocked
+// for at least retryAfter. It returns whether the error was recorded.
+func (e *blockedEndpoints) closeNonIdempotentContext(ctx context.Context, endpoint string, retryAfter time.Duration) bool {
+	if !e.lockContext(ctx) {
+		return false
+	}
+	defer e.m.Unlock()
+
+	b := e.getBlock(endpoint)
+
+	policy := e.policyFor(endpoint)
+	b.nonIdempotentErrors = policy.IncError(b.nonIdempotentErrors)
+	backoffDuration := withRetryAfter(endpoint, policy, policy.GetBackoffDurationFrom(e.rand, b.nonIdempotentErrors), retryAfter)
+	b.nonIdempotentUntil = e.clock.Now().Add(backoffDuration)
+	return true
 }
 
 func (e *blockedEndpoints) recover(endpoint string) {
+	e.recoverContext(context.Background(), endpoint)
+}
+
+// recoverContext is recover, doing nothing when ctx is canceled. It returns
+// whether the success was recorded.
+func (e *blockedEndpoints) recoverContext(ctx context.Context, endpoint string) bool {
+	if !e.lockContext(ctx) {
+		return false
+	}
+	recovered := e.recoverLocked(endpoint)
+	hooks := e.onRecover
+	e.m.Unlock()
+
+	if recovered {
+		for _, hook := range hooks {
+			hook(endpoint)
+		}
+	}
+	return true
+}
+
+// recoverLocked records a success for the endpoint. It returns whether the
+// endpoint just recovered. e.m must be held for writing by the caller.
+func (e *blockedEndpoints) recoverLocked(endpoint string) bool {
+	b := e.getBlock(endpoint)
+	b.probing = false
+	policy := e.policyFor(endpoint)
+
+	if b.nonIdempotentErrors > 0 {
+		b.nonIdempotentErrors = policy.DecError(b.nonIdempotentErrors)
+		b.nonIdempotentUntil = e.clock.Now().Add(policy.GetBackoffDurationFrom(e.rand, b.nonIdempotentErrors))
+	}
+
+	now := e.clock.Now()
+	decayed := policy.DecError(b.nbError)
+	if e.stablePeriod > 0 && !b.firstBlock.IsZero() {
+		// The errors decay no faster than halving on each success, and the
+		// endpoint only recovers once the stable period elapsed without error
+		if b.stableUntil.IsZero() {
+			b.stableUntil = now.Add(e.stablePeriod)
+		}
+		if half := b.nbError / 2; decayed < half {
+			decayed = half
+		}
+	}
+	b.nbError = decayed
+	b.until = now.Add(policy.GetBackoffDurationFrom(e.rand, b.nbError))
+
+	recovered := b.nbError == 0 && !b.firstBlock.IsZero() && !now.Before(b.stableUntil)
+	if recovered {
+		b.markRecovered(endpoint, now)
+		e.failing--
+		e.updateHealth(now)
+	}
+	e.schedulePurge(b)
+	return recovered
+}
+
+// markRecovered records the recovery of the failing endpoint of b at now.
+func (b *block) markRecovered(endpoint string, now time.Time) {
+	b.lastRecovery = now.Sub(b.firstBlock)
+	b.firstBlock = time.Time{}
+	b.stableUntil = time.Time{}
+	tlmEndpointRecoveryTime.Observe(b.lastRecovery.Seconds(), endpointDomain(endpoint))
+	tlmEndpointRecovered.Inc(endpointDomain(endpoint))
+}
+
+// resetAll forgets the errors of all the endpoints, unblocking them right away.
+func (e *blockedEndpoints) resetAll() {
 	e.m.Lock()
-	defer e.m.Unlock()
+	now := e.clock.Now()
+	var recovered []string
+	for endpoint, b := range e.errorPerEndpoint {
+		if !b.firstBlock.IsZero() {
+			b.markRecovered(endpoint, now)
+			recovered = append(recovered, endpoint)
+		}
+	}
+	e.errorPerEndpoint = make(map[string]*block)
+	e.purgeAfter = time.Time{}
+	e.failing = 0
+	e.updateHealth(now)
+	hooks := e.onRecover
+	e.m.Unlock()
 
-	var b *block
-	if knownBlock, ok := e.errorPerEndpoint[endpoint]; ok {
-		b = knownBlock
-	} else {
-		b = &block{}
+	for _, endpoint := range recovered {
+		for _, hook := range hooks {
+			hook(endpoint)
+		}
 	}
+}
 
-	b.nbError = e.backoffPolicy.DecError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+// OnBlock registers a hook called when an endpoint starts failing, with the
+// end of its first block. Further errors do not call it again until the
+// endpoint recovered. Hooks are called synchronously, without holding the
+// lock of e, so they may call its methods.
+func (e *blockedEndpoints) OnBlock(hook func(endpoint string, until time.Time)) {
+	e.m.Lock()
+	defer e.m.Unlock()
 
-	e.errorPerEndpoint[endpoint] = b
+	e.onBlock = append(e.onBlock, hook)
+}
+
+// OnRecover registers a hook called when a failing endpoint recovers, see
+// OnBlock.
+func (e *blockedEndpoints) OnRecover(hook func(endpoint string)) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.onRecover = append(e.onRecover, hook)
+}
+
+// blockFor blocks the endpoint for d from now, for instance to drain it,
+// without recording an error: its backoff is left untouched, and a success or
+// an error replaces the block as usual.
+func (e *blockedEndpoints) blockFor(endpoint string, d time.Duration) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	b := e.getBlock(endpoint)
+	b.until = e.clock.Now().Add(d)
+	e.schedulePurge(b)
+}
+
+// setMaintenance puts the endpoint in maintenance mode: it is unblocked right
+// away and errors only block it for the given interval, without increasing the
+// backoff, until clearMaintenance is called.
+func (e *blockedEndpoints) setMaintenance(endpoint string, interval time.Duration) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	b := e.getBlock(endpoint)
+
+	b.maintenanceInterval = interval
+	b.until = e.clock.Now()
+}
+
+// clearMaintenance restores the error-driven backoff for the endpoint.
+func (e *blockedEndpoints) clearMaintenance(endpoint string) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if b, ok := e.errorPerEndpoint[endpoint]; ok {
+		b.maintenanceInterval = 0
+		e.schedulePurge(b)
+	}
 }
 
 func (e *blockedEndpoints) isBlock(endpoint string) bool {
+	blocked, _ := e.isBlockUntil(endpoint)
+	return blocked
+}
+
+// isBlockUntil returns whether the endpoint is blocked and, if it is, the time
+// at which it can be retried.
+func (e *blockedEndpoints) isBlockUntil(endpoint string) (bool, time.Time) {
 	e.m.RLock()
 	defer e.m.RUnlock()
 
-	if b, ok := e.errorPerEndpoint[endpoint]; ok && time.Now().Before(b.until) {
+	b, ok := e.errorPerEndpoint[endpoint]
+	if !ok {
+		return false, time.Time{}
+	}
+	return b.blockedUntil(e.clock.Now(), false)
+}
+
+// isBlockProbe is isBlock for a transaction about to be sent. In half-open
+// mode, once the block of an endpoint with errors expired, the first caller
+// gets to send its transaction as a probe: the endpoint stays blocked for the
+// others until the probe is recorded by close or recover, or times out.
+func (e *blockedEndpoints) isBlockProbe(endpoint string) bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	b, ok := e.errorPerEndpoint[endpoint]
+	if !ok {
+		return false
+	}
+	now := e.clock.Now()
+	if blocked, _ := b.blockedUntil(now, false); blocked {
 		return true
 	}
+	if e.halfOpenProbe && b.nbError > 0 {
+		b.probing = true
+		b.probeDeadline = now.Add(e.probeTimeout)
+	}
 	return false
 }
 
-func (e *blockedEndpoints) getBackoffDuration(numErrors int) time.Duration {
-	return e.backoffPolicy.GetBackoffDuration(numErrors)
+// releaseProbe lets another transaction probe the endpoint, when the probe
+// claimed by isBlockProbe was not sent or its outcome could not be recorded by
+// close or recover, instead of blocking the endpoint until the probe timeout.
+func (e *blockedEndpoints) releaseProbe(endpoint string) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if b, ok := e.errorPerEndpoint[endpoint]; ok {
+		b.probing = false
+	}
+}
+
+// isProbing returns whether a probe is being sent to the endpoint of b.
+func (b *block) isProbing(now time.Time) bool {
+	return b.probing && now.Before(b.probeDeadline)
+}
+
+// blockedUntil returns whether the endpoint of b is blocked at now and, if it
+// is, the time at which it can be retried: the end of its backoff or of the
+// probe being sent, and with nonIdempotent, of its non-idempotent backoff.
+// It is the predicate shared by the checks made before sending and by the
+// reports, which count the endpoints blocked for any payload.
+func (b *block) blockedUntil(now time.Time, nonIdempotent bool) (bool, time.Time) {
+	var until time.Time
+	if now.Before(b.until) {
+		until = b.until
+	}
+	if b.isProbing(now) && b.probeDeadline.After(until) {
+		until = b.probeDeadline
+	}
+	if nonIdempotent && now.Before(b.nonIdempotentUntil) && b.nonIdempotentUntil.After(until) {
+		until = b.nonIdempotentUntil
+	}
+	return !until.IsZero(), until
+}
+
+// isBlockNonIdempotent returns whether non-idempotent payloads should not be
+// sent to the endpoint, which is the case when it is blocked for all payloads
+// or after non-idempotent failures.
+func (e *blockedEndpoints) isBlockNonIdempotent(endpoint string) bool {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	if b, ok := e.errorPerEndpoint[endpoint]; ok {
+		blocked, _ := b.blockedUntil(e.clock.Now(), true)
+		return blocked
+	}
+	return false
+}
+
+// SetPolicy replaces the backoff policy. It applies to the backoff durations
+// computed afterwards, endpoints already blocked stay blocked until their
+// current deadline.
+func (e *blockedEndpoints) SetPolicy(p backoff.Policy) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.backoffPolicy = p
+}
+
+// PolicySnapshot returns a copy of the backoff policy in effect, with the
+// values resolved from the configuration.
+func (e *blockedEndpoints) PolicySnapshot() backoff.Policy {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	return e.backoffPolicy
+}
+
+// SetDomainPolicy registers a backoff policy for the endpoints of the domain,
+// the host of their URL, instead of the global one. Like SetPolicy, it applies
+// to the backoff durations computed afterwards.
+func (e *blockedEndpoints) SetDomainPolicy(domain string, p backoff.Policy) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.domainPolicies == nil {
+		e.domainPolicies = make(map[string]backoff.Policy)
+	}
+	e.domainPolicies[domain] = p
+}
+
+// policyFor returns the backoff policy of the endpoint: the one registered for
+// its domain if any, the global one otherwise.
+func (e *blockedEndpoints) policyFor(endpoint string) backoff.Policy {
+	if p, ok := e.domainPolicies[endpointDomain(endpoint)]; ok {
+		return p
+	}
+	return e.backoffPolicy
+}
+
+// Allow returns whether a payload can be sent to the endpoint without
+// exceeding forwarder_endpoint_rate. Each endpoint has a token bucket holding
+// up to one second of sends, refilled at the configured rate.
+func (e *blockedEndpoints) Allow(endpoint string) bool {
+	if e.rate <= 0 {
+		return true
+	}
+
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	burst := math.Max(e.rate, 1)
+	now := e.clock.Now()
+
+	b := e.getBlock(endpoint)
+	if b.lastRefill.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.lastRefill).Seconds()*e.rate)
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	e.schedulePurge(b)
+	return true
+}
+
+// updateHealth moves the health hysteresis after the number of failing
+// endpoints changed at now. e.m must be held for writing by the caller.
+func (e *blockedEndpoints) updateHealth(now time.Time) {
+	// a recovery that completed since the last change is applied first, so
+	// that a new failure does not restart it
+	if e.recovered(now) {
+		e.degraded = false
+		e.healthySince = time.Time{}
+	}
+
+	var fraction float64
+	if len(e.known) > 0 {
+		fraction = float64(e.failing) / float64(len(e.known))
+	}
+
+	switch {
+	case fraction > e.degradedThreshold:
+		e.degraded = true
+		e.healthySince = time.Time{}
+	case fraction > e.recoveredThreshold:
+		e.healthySince = time.Time{}
+	case e.degraded && e.healthySince.IsZero():
+		e.healthySince = now
+	}
+}
+
+// recovered returns whether the degraded endpoints stayed healthy long enough
+// at now to recover.
+func (e *blockedEndpoints) recovered(now time.Time) bool {
+	return e.degraded && !e.healthySince.IsZero() && now.Sub(e.healthySince) >= e.recoveryDuration
+}
+
+// Health returns whether the endpoints are healthy. They become degraded when
+// the fraction of failing endpoints exceeds the degraded threshold and only
+// recover after it stayed at or below the recovered threshold for the recovery
+// duration, so that an oscillating endpoint does not make the health flap. An
+// endpoint is failing from its first error until it recovers.
+func (e *blockedEndpoints) Health() bool {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	return !e.degraded || e.recovered(e.clock.Now())
+}
+
+// BlockedEndpointInfo describes an endpoint currently blocked.
+type BlockedEndpointInfo struct {
+	Endpoint string
+	NbError  int
+	Until    time.Time
+}
+
+// SoonestRetries returns up to limit blocked endpoints, the ones that can be
+// retried first coming first. A limit of 0 or less returns all of them.
+func (e *blockedEndpoints) SoonestRetries(limit int) []BlockedEndpointInfo {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	now := e.clock.Now()
+	var infos []BlockedEndpointInfo
+	for endpoint, b := range e.errorPerEndpoint {
+		if blocked, until := b.blockedUntil(now, true); blocked {
+			infos = append(infos, BlockedEndpointInfo{Endpoint: endpoint, NbError: b.nbError, Until: until})
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Until.Equal(infos[j].Until) {
+			return infos[i].Until.Before(infos[j].Until)
+		}
+		return infos[i].Endpoint < infos[j].Endpoint
+	})
+	if limit > 0 && len(infos) > limit {
+		infos = infos[:limit]
+	}
+	return infos
+}
+
+// BlockedCount returns the number of endpoints currently blocked, whether they
+// are blocked for all the payloads or only for the non-idempotent ones.
+func (e *blockedEndpoints) BlockedCount() int {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	now := e.clock.Now()
+	count := 0
+	for _, b := range e.errorPerEndpoint {
+		if blocked, _ := b.blockedUntil(now, true); blocked {
+			count++
+		}
+	}
+	return count
+}
+
+// BlockInfo is a snapshot of the backoff state of an endpoint.
+type BlockInfo struct {
+	NbError int
+	Until   time.Time
+	Blocked bool
+	// FirstBlock is when the endpoint started failing, zero when it is not
+	// failing
+	FirstBlock time.Time
+	// LastRecoveryDuration is how long the endpoint took to recover from its
+	// last outage, zero when it never recovered
+	LastRecoveryDuration time.Duration
+}
+
+// BlockedStatus returns a snapshot of the backoff state of the endpoints known
+// by e, blocked or not.
+func (e *blockedEndpoints) BlockedStatus() map[string]BlockInfo {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	return e.blockedStatus()
+}
+
+// blockedStatus is BlockedStatus without locking e.m, which must be held by
+// the caller.
+func (e *blockedEndpoints) blockedStatus() map[string]BlockInfo {
+	now := e.clock.Now()
+	status := make(map[string]BlockInfo, len(e.errorPerEndpoint))
+	for endpoint, b := range e.errorPerEndpoint {
+		info := BlockInfo{
+			NbError:              b.nbError,
+			Until:                b.until,
+			FirstBlock:           b.firstBlock,
+			LastRecoveryDuration: b.lastRecovery,
+		}
+		if blocked, until := b.blockedUntil(now, true); blocked {
+			info.Blocked, info.Until = true, until
+		}
+		status[endpoint] = info
+	}
+	return status
+}
+
+// endpointDomain returns the host part of the endpoint URL, used to tag telemetry
+func endpointDomain(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
 }
@@ -6,21 +6,25 @@
 package defaultforwarder
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"math/rand"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
 )
 
-func init() {
-	rand.Seed(10)
-}
-
 func TestMinBackoffFactorValid(t *testing.T) {
 	mockConfig := config.Mock(t)
 	e := newBlockedEndpoints(mockConfig)
@@ -78,6 +82,62 @@ func TestMaxBackoffTimeValid(t *testing.T) {
 	assert.Equal(t, defaultValue, e.backoffPolicy.MaxBackoffTime)
 }
 
+func TestBackoffJitterValid(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	// Verify default
+	defaultValue := e.backoffPolicy.Jitter
+	assert.Equal(t, float64(1), defaultValue)
+
+	// Verify configuration updates global var
+	mockConfig.Set("forwarder_backoff_jitter", 0)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, float64(0), e.backoffPolicy.Jitter)
+
+	// Verify no jitter makes the backoff deterministic
+	_, max := e.backoffPolicy.GetBackoffRange(3)
+	assert.Equal(t, max, e.backoffPolicy.GetBackoffDurationFrom(e.rand, 3))
+
+	// Verify invalid values recover gracefully
+	mockConfig.Set("forwarder_backoff_jitter", 1.5)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, defaultValue, e.backoffPolicy.Jitter)
+
+	mockConfig.Set("forwarder_backoff_jitter", -0.5)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, defaultValue, e.backoffPolicy.Jitter)
+}
+
+func TestBackoffMinIntervalValid(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	// Verify default
+	assert.Equal(t, float64(0), e.backoffPolicy.MinBackoffTime)
+
+	// Verify configuration updates global var
+	mockConfig.Set("forwarder_backoff_min_interval", 5)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, float64(5), e.backoffPolicy.MinBackoffTime)
+	for i := 1; i <= e.backoffPolicy.MaxErrors; i++ {
+		for j := 0; j < 10; j++ {
+			backoffDuration := e.backoffPolicy.GetBackoffDurationFrom(e.rand, i)
+			assert.True(t, backoffDuration >= 5*time.Second, "%v for %d errors", backoffDuration, i)
+		}
+	}
+	assert.Equal(t, time.Duration(0), e.backoffPolicy.GetBackoffDurationFrom(e.rand, 0))
+
+	// Verify invalid values recover gracefully
+	mockConfig.Set("forwarder_backoff_min_interval", -1)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, float64(0), e.backoffPolicy.MinBackoffTime)
+
+	mockConfig.Set("forwarder_backoff_min_interval", 100)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, e.backoffPolicy.MaxBackoffTime, e.backoffPolicy.MinBackoffTime)
+}
+
 func TestRecoveryIntervalValid(t *testing.T) {
 	mockConfig := config.Mock(t)
 	e := newBlockedEndpoints(mockConfig)
@@ -104,16 +164,56 @@ func TestRecoveryIntervalValid(t *testing.T) {
 	assert.Equal(t, e.backoffPolicy.MaxErrors, e.backoffPolicy.RecoveryInterval)
 }
 
+func TestRecoveryModeValid(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_backoff_max", 1024)
+
+	recoverAfterErrors := func(t *testing.T) []int {
+		e := newBlockedEndpoints(mockConfig)
+		for i := 0; i < 10; i++ {
+			e.close("test")
+		}
+		require.Equal(t, 10, e.errorPerEndpoint["test"].nbError)
+
+		var nbErrors []int
+		for e.errorPerEndpoint["test"].nbError > 0 {
+			e.recover("test")
+			nbErrors = append(nbErrors, e.errorPerEndpoint["test"].nbError)
+		}
+		return nbErrors
+	}
+
+	for _, tc := range []struct {
+		mode          string
+		recoveryReset bool
+		expected      []int
+	}{
+		{"", false, []int{8, 6, 4, 2, 0}},
+		{"", true, []int{0}},
+		{"linear", true, []int{8, 6, 4, 2, 0}},
+		{"reset", false, []int{0}},
+		{"halve", true, []int{5, 2, 1, 0}},
+		{"invalid", false, []int{8, 6, 4, 2, 0}},
+		{"invalid", true, []int{0}},
+	} {
+		t.Run(fmt.Sprintf("%s/%v", tc.mode, tc.recoveryReset), func(t *testing.T) {
+			mockConfig.Set("forwarder_recovery_mode", tc.mode)
+			mockConfig.Set("forwarder_recovery_reset", tc.recoveryReset)
+			assert.Equal(t, tc.expected, recoverAfterErrors(t))
+		})
+	}
+}
+
 // Test we increase delay on average
 func TestGetBackoffDurationIncrease(t *testing.T) {
 	mockConfig := config.Mock(t)
-	e := newBlockedEndpoints(mockConfig)
+	e := newBlockedEndpointsWithRand(mockConfig, rand.New(rand.NewSource(10)))
 	previousBackoffDuration := time.Duration(0) * time.Second
 	backoffIncrease := 0
 	backoffDecrease := 0
 
 	for i := 1; ; i++ {
-		backoffDuration := e.getBackoffDuration(i)
+		backoffDuration := e.backoffPolicy.GetBackoffDurationFrom(e.rand, i)
 
 		if i > 1000 {
 			assert.Truef(t, i < 1000, "Too many iterations")
@@ -135,19 +235,19 @@ func TestGetBackoffDurationIncrease(t *testing.T) {
 func TestMaxGetBackoffDuration(t *testing.T) {
 	mockConfig := config.Mock(t)
 	e := newBlockedEndpoints(mockConfig)
-	backoffDuration := e.getBackoffDuration(100)
+	backoffDuration := e.backoffPolicy.GetBackoffDurationFrom(e.rand, 100)
 
 	assert.Equal(t, time.Duration(e.backoffPolicy.MaxBackoffTime)*time.Second, backoffDuration)
 }
 
 func TestMaxErrors(t *testing.T) {
 	mockConfig := config.Mock(t)
-	e := newBlockedEndpoints(mockConfig)
+	e := newBlockedEndpointsWithRand(mockConfig, rand.New(rand.NewSource(10)))
 	previousBackoffDuration := time.Duration(0) * time.Second
 	attempts := 0
 
 	for i := 1; ; i++ {
-		backoffDuration := e.getBackoffDuration(i)
+		backoffDuration := e.backoffPolicy.GetBackoffDurationFrom(e.rand, i)
 
 		if i > 1000 {
 			assert.Truef(t, i < 1000, "Too many iterations")
@@ -162,32 +262,103 @@ func TestMaxErrors(t *testing.T) {
 	assert.Equal(t, e.backoffPolicy.MaxErrors, attempts)
 }
 
-func TestBlock(t *testing.T) {
+func TestMaxErrorsOverride(t *testing.T) {
 	mockConfig := config.Mock(t)
 	e := newBlockedEndpoints(mockConfig)
 
+	// Verify default
+	defaultValue := e.backoffPolicy.MaxErrors
+	assert.Equal(t, 6, defaultValue)
+
+	// Verify configuration updates global var
+	mockConfig.Set("forwarder_backoff_max_errors", 3)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, 3, e.backoffPolicy.MaxErrors)
+	assert.Equal(t, float64(64), e.backoffPolicy.MaxBackoffTime)
+
+	// Verify the error count stops growing at the override
+	for i := 0; i < 10; i++ {
+		e.close("test")
+	}
+	assert.Equal(t, 3, e.errorPerEndpoint["test"].nbError)
+
+	// Verify recovery reset uses the override
+	mockConfig.Set("forwarder_recovery_reset", true)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, 3, e.backoffPolicy.RecoveryInterval)
+
+	// Verify invalid values recover gracefully
+	mockConfig.Set("forwarder_recovery_reset", false)
+	mockConfig.Set("forwarder_backoff_max_errors", -1)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, defaultValue, e.backoffPolicy.MaxErrors)
+}
+
+func TestBlock(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
 	e.close("test")
-	now := time.Now()
 
 	assert.Contains(t, e.errorPerEndpoint, "test")
-	assert.True(t, now.Before(e.errorPerEndpoint["test"].until))
+	assert.True(t, mock.Now().Before(e.errorPerEndpoint["test"].until))
+}
+
+func TestBlockWithRetryAfter(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_backoff_max", 3600)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+	maxBackoffDuration := time.Duration(e.backoffPolicy.MaxBackoffTime) * time.Second
+
+	// Retry-After longer than the computed backoff wins
+	e.closeWithRetryAfter("test", 10*time.Minute)
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, now.Add(10*time.Minute), e.errorPerEndpoint["test"].until)
+
+	// Retry-After shorter than the computed backoff is ignored
+	e.errorPerEndpoint["test"].nbError = 1000000
+	e.closeWithRetryAfter("test", time.Second)
+	min, max := e.backoffPolicy.GetBackoffRange(e.backoffPolicy.MaxErrors)
+	until := e.errorPerEndpoint["test"].until
+	assert.False(t, until.Before(now.Add(min)))
+	assert.False(t, until.After(now.Add(max)))
+	assert.Equal(t, maxBackoffDuration, max)
+}
+
+func TestBlockWithRetryAfterCapped(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+	maxBackoffDuration := time.Duration(e.backoffPolicy.MaxBackoffTime) * time.Second
+
+	// A Retry-After of a year only blocks for the maximum backoff
+	e.closeWithRetryAfter("test", 365*24*time.Hour)
+	assert.Equal(t, now.Add(maxBackoffDuration), e.errorPerEndpoint["test"].until)
+
+	// and so does a Retry-After longer than the maximum backoff of a domain policy
+	e.SetDomainPolicy("example.com", backoff.NewPolicy(2, 1, 10, 2, false))
+	e.closeWithRetryAfter("https://example.com/api", time.Hour)
+	assert.Equal(t, now.Add(10*time.Second), e.errorPerEndpoint["https://example.com/api"].until)
 }
 
 func TestMaxBlock(t *testing.T) {
 	mockConfig := config.Mock(t)
-	e := newBlockedEndpoints(mockConfig)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
 	e.close("test")
 	e.errorPerEndpoint["test"].nbError = 1000000
 
 	e.close("test")
-	now := time.Now()
 
 	maxBackoffDuration := time.Duration(e.backoffPolicy.MaxBackoffTime) * time.Second
 
 	assert.Contains(t, e.errorPerEndpoint, "test")
 	assert.Equal(t, e.backoffPolicy.MaxErrors, e.errorPerEndpoint["test"].nbError)
-	assert.True(t, now.Add(maxBackoffDuration).After(e.errorPerEndpoint["test"].until) ||
-		now.Add(maxBackoffDuration).Equal(e.errorPerEndpoint["test"].until))
+	assert.False(t, e.errorPerEndpoint["test"].until.After(mock.Now().Add(maxBackoffDuration)))
 }
 
 func TestUnblock(t *testing.T) {
@@ -207,16 +378,17 @@ func TestUnblock(t *testing.T) {
 
 func TestMaxUnblock(t *testing.T) {
 	mockConfig := config.Mock(t)
-	e := newBlockedEndpoints(mockConfig)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
 
 	e.close("test")
 	e.recover("test")
 	e.recover("test")
-	now := time.Now()
 
 	assert.Contains(t, e.errorPerEndpoint, "test")
 	assert.True(t, e.errorPerEndpoint["test"].nbError == 0)
-	assert.True(t, now.After(e.errorPerEndpoint["test"].until) || now.Equal(e.errorPerEndpoint["test"].until))
+	assert.Equal(t, mock.Now(), e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlock("test"))
 }
 
 func TestUnblockUnknown(t *testing.T) {
@@ -241,17 +413,515 @@ func TestIsBlock(t *testing.T) {
 	assert.False(t, e.isBlock("test"))
 }
 
-func TestIsBlockTiming(t *testing.T) {
+func TestMaintenance(t *testing.T) {
 	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	e.close("test")
+	e.close("test")
+	require.True(t, e.isBlock("test"))
+
+	// Maintenance unblocks the endpoint right away
+	e.setMaintenance("test", time.Minute)
+	assert.False(t, e.isBlock("test"))
+
+	// Errors block for the fixed interval without increasing the backoff
+	e.close("test")
+	assert.True(t, e.isBlock("test"))
+	assert.Equal(t, 3, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, mock.Now().Add(time.Minute), e.errorPerEndpoint["test"].until)
+
+	// Once cleared, errors increase the backoff again
+	e.clearMaintenance("test")
+	e.close("test")
+	assert.Equal(t, 4, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestBlockFor(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// The endpoint stays blocked for the duration, without recording an error
+	e.blockFor("test", time.Hour)
+	assert.True(t, e.isBlock("test"))
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+	mock.Add(time.Hour - time.Nanosecond)
+	assert.True(t, e.isBlock("test"))
+	mock.Add(time.Nanosecond)
+	assert.False(t, e.isBlock("test"))
+
+	// A success ends the block
+	e.blockFor("test", time.Hour)
+	e.recover("test")
+	assert.False(t, e.isBlock("test"))
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+
+	// Errors increase the backoff from where it was
+	e.close("test")
+	e.blockFor("test", time.Hour)
+	e.close("test")
+	assert.True(t, e.isBlock("test"))
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestHalfOpenProbe(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// An endpoint that never failed is not gated
+	assert.False(t, e.isBlockProbe("test"))
+	assert.False(t, e.isBlockProbe("test"))
+
+	e.close("test")
+	assert.True(t, e.isBlockProbe("test"))
+
+	// Once the block expired, a single probe is let through
+	mock.Set(e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlockProbe("test"))
+	assert.True(t, e.isBlockProbe("test"))
+	assert.True(t, e.isBlock("test"))
+	assert.False(t, e.isRecovered(e.errorPerEndpoint["test"], mock.Now()))
+
+	// A successful probe opens the endpoint
+	e.recover("test")
+	assert.False(t, e.isBlockProbe("test"))
+	assert.False(t, e.isBlockProbe("test"))
+}
+
+func TestHalfOpenProbeFailure(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	mock.Set(e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlockProbe("test"))
+
+	// A failed probe blocks the endpoint again, with a longer backoff
+	e.close("test")
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+	assert.True(t, e.isBlockProbe("test"))
+
+	mock.Set(e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlockProbe("test"))
+	assert.True(t, e.isBlockProbe("test"))
+}
+
+func TestHalfOpenProbeTimeout(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	mockConfig.Set("forwarder_timeout", 10)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	mock.Set(e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlockProbe("test"))
+
+	// A probe whose outcome was never recorded frees the slot after the timeout
+	mock.Add(10*time.Second - time.Nanosecond)
+	assert.True(t, e.isBlockProbe("test"))
+	mock.Add(time.Nanosecond)
+	assert.False(t, e.isBlockProbe("test"))
+	assert.True(t, e.isBlockProbe("test"))
+}
+
+func TestHalfOpenProbeDisabled(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	mock.Set(e.errorPerEndpoint["test"].until)
+	assert.False(t, e.isBlockProbe("test"))
+	assert.False(t, e.isBlockProbe("test"))
+}
+
+func TestRecoveryTime(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+
+	e.close("https://example.com/api/v1/series")
+	clk.Add(10 * time.Second)
+	e.close("https://example.com/api/v1/series")
+	clk.Add(20 * time.Second)
+	assert.Equal(t, clk.Now().Add(-30*time.Second), e.errorPerEndpoint["https://example.com/api/v1/series"].firstBlock)
+
+	e.recover("https://example.com/api/v1/series")
+	b := e.errorPerEndpoint["https://example.com/api/v1/series"]
+	assert.Equal(t, 30*time.Second, b.lastRecovery)
+	assert.True(t, b.firstBlock.IsZero())
+
+	// Successes on a healthy endpoint do not record a recovery
+	clk.Add(time.Minute)
+	e.recover("https://example.com/api/v1/series")
+	assert.Equal(t, 30*time.Second, b.lastRecovery)
+}
+
+func TestRecoverStablePeriod(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	mockConfig.Set("forwarder_recover_stable_period", 60)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+
+	for i := 0; i < 4; i++ {
+		e.close("test")
+	}
+	require.Equal(t, 4, e.errorPerEndpoint["test"].nbError)
+
+	// A single success only halves the errors, despite forwarder_recovery_reset
+	e.recover("test")
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+	assert.True(t, e.isBlock("test"))
+
+	// and an error right after resumes the backoff from there
+	clk.Add(30 * time.Second)
+	e.close("test")
+	assert.Equal(t, 3, e.errorPerEndpoint["test"].nbError)
+
+	// The errors drain with the successes, but the endpoint only recovers
+	// once the stable period elapsed since the first success after the error
+	e.recover("test")
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+	e.recover("test")
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+	assert.False(t, e.isBlock("test"))
+	assert.False(t, e.errorPerEndpoint["test"].firstBlock.IsZero())
+
+	clk.Add(59 * time.Second)
+	e.recover("test")
+	assert.False(t, e.errorPerEndpoint["test"].firstBlock.IsZero())
+	clk.Add(time.Second)
+	e.recover("test")
+	assert.True(t, e.errorPerEndpoint["test"].firstBlock.IsZero())
+	assert.Equal(t, 90*time.Second, e.errorPerEndpoint["test"].lastRecovery)
+
+	// Once recovered, errors start from scratch
+	e.close("test")
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestRecoverStablePeriodDisabled(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+
+	for i := 0; i < 4; i++ {
+		e.close("test")
+	}
+	e.recover("test")
+	e.close("test")
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestNonIdempotentBlock(t *testing.T) {
+	mockConfig := config.Mock(t)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+
+	e.closeNonIdempotent("test")
+	assert.False(t, e.isBlock("test"))
+	assert.True(t, e.isBlockNonIdempotent("test"))
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nonIdempotentErrors)
+
+	// Idempotent errors block all the traffic
+	e.close("other")
+	assert.True(t, e.isBlock("other"))
+	assert.True(t, e.isBlockNonIdempotent("other"))
+
+	// Successes drain both error counts
+	e.recover("test")
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nonIdempotentErrors)
+	assert.False(t, e.isBlockNonIdempotent("test"))
+}
+
+func TestNonIdempotentBlockWithRetryAfter(t *testing.T) {
+	mockConfig := config.Mock(t)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+	now := clk.Now()
+	maxBackoffDuration := time.Duration(e.backoffPolicy.MaxBackoffTime) * time.Second
+
+	// Retry-After longer than the computed backoff wins, up to the maximum backoff
+	assert.True(t, e.closeNonIdempotentContext(context.Background(), "test", 30*time.Second))
+	assert.Equal(t, now.Add(30*time.Second), e.errorPerEndpoint["test"].nonIdempotentUntil)
+	assert.False(t, e.isBlock("test"))
+
+	assert.True(t, e.closeNonIdempotentContext(context.Background(), "test", time.Hour))
+	assert.Equal(t, now.Add(maxBackoffDuration), e.errorPerEndpoint["test"].nonIdempotentUntil)
+}
+
+func TestSetPolicy(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	until := e.errorPerEndpoint["test"].until
+	assert.True(t, until.Sub(mock.Now()) <= 4*time.Second)
+
+	// a policy without randomization, backing off 10s then capped to 60s
+	e.SetPolicy(backoff.NewPolicy(1, 5, 60, 1, false))
+	assert.Equal(t, until, e.errorPerEndpoint["test"].until, "current deadline must be kept")
+
+	e.close("test")
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, mock.Now().Add(20*time.Second), e.errorPerEndpoint["test"].until)
+
+	e.close("test")
+	e.close("test")
+	assert.Equal(t, 4, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, mock.Now().Add(60*time.Second), e.errorPerEndpoint["test"].until)
+
+	e.recover("test")
+	assert.Equal(t, 3, e.errorPerEndpoint["test"].nbError)
+	assert.Equal(t, mock.Now().Add(40*time.Second), e.errorPerEndpoint["test"].until)
+}
+
+func TestSetDomainPolicy(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// policies without randomization, capped to 60s by default and to 20s
+	// for the slow domain
+	e.SetPolicy(backoff.NewPolicy(1, 5, 60, 1, false))
+	e.SetDomainPolicy("slow.example.com", backoff.NewPolicy(1, 5, 20, 1, false))
+
+	main := "https://app.example.com/api/v1/series"
+	slow := "https://slow.example.com/api/v1/series"
+	for i := 0; i < 10; i++ {
+		e.close(main)
+		e.close(slow)
+	}
+	assert.Equal(t, mock.Now().Add(60*time.Second), e.errorPerEndpoint[main].until)
+	assert.Equal(t, mock.Now().Add(20*time.Second), e.errorPerEndpoint[slow].until)
+	assert.Equal(t, 4, e.errorPerEndpoint[main].nbError)
+	assert.Equal(t, 3, e.errorPerEndpoint[slow].nbError)
+
+	// the domain policy also applies when recovering
+	e.recover(main)
+	e.recover(slow)
+	assert.Equal(t, mock.Now().Add(40*time.Second), e.errorPerEndpoint[main].until)
+	assert.Equal(t, mock.Now().Add(20*time.Second), e.errorPerEndpoint[slow].until)
+}
+
+func TestHealthHysteresis(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_health_degraded_threshold", 0.5)
+	mockConfig.Set("forwarder_health_recovered_threshold", 0.2)
+	mockConfig.Set("forwarder_health_recovery_duration", 60)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	for _, endpoint := range []string{"a", "b", "c", "d"} {
+		e.recover(endpoint)
+	}
+	assert.True(t, e.Health())
+
+	// 2 failing endpoints out of 4 does not exceed the degraded threshold
+	e.close("a")
+	e.close("b")
+	assert.True(t, e.Health())
+
+	e.close("c")
+	assert.False(t, e.Health())
+
+	// an endpoint oscillating between failing and recovered keeps the
+	// endpoints degraded as long as it fails regularly
+	e.recover("a")
+	e.recover("b")
+	e.recover("c")
+	for i := 0; i < 10; i++ {
+		mock.Add(20 * time.Second)
+		e.close("a")
+		assert.False(t, e.Health(), "iteration %d", i)
+		mock.Add(10 * time.Second)
+		e.recover("a")
+		assert.False(t, e.Health(), "iteration %d", i)
+	}
+
+	// the endpoints recover once the failing fraction stayed low long enough,
+	// without any other change
+	mock.Add(59 * time.Second)
+	assert.False(t, e.Health())
+	mock.Add(time.Second)
+	assert.True(t, e.Health())
+
+	// and stay healthy while a single endpoint oscillates
+	for i := 0; i < 10; i++ {
+		e.close("a")
+		assert.True(t, e.Health(), "iteration %d", i)
+		e.recover("a")
+		assert.True(t, e.Health(), "iteration %d", i)
+	}
+}
+
+func TestHealthPurge(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 2)
+	mockConfig.Set("forwarder_health_degraded_threshold", 0.5)
+	e := newBlockedEndpointsWithClock(mockConfig, clock.NewMock())
+
+	for _, endpoint := range []string{"a", "b", "c"} {
+		e.recover(endpoint)
+	}
+	e.close("d")
+	assert.True(t, e.Health())
+
+	// the recovered endpoints are purged, but still count in the health
+	e.close("e")
+	require.NotContains(t, e.errorPerEndpoint, "a")
+	assert.True(t, e.Health())
+
+	e.close("f")
+	assert.True(t, e.Health())
+	e.close("g")
+	assert.False(t, e.Health())
+}
+
+func TestHealthThresholdsValid(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_health_degraded_threshold", 2)
+	mockConfig.Set("forwarder_health_recovered_threshold", 0.8)
+	mockConfig.Set("forwarder_health_recovery_duration", -1)
 	e := newBlockedEndpoints(mockConfig)
 
-	// setting an old close
-	e.errorPerEndpoint["test"] = &block{nbError: 1, until: time.Now().Add(-30 * time.Second)}
+	assert.Equal(t, 0.5, e.degradedThreshold)
+	assert.Equal(t, 0.5, e.recoveredThreshold)
+	assert.Equal(t, time.Duration(0), e.recoveryDuration)
+}
+
+func TestAllow(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_endpoint_rate", 2)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// the bucket starts full with one second of sends
+	assert.True(t, e.Allow("test"))
+	assert.True(t, e.Allow("test"))
+	assert.False(t, e.Allow("test"))
+
+	// endpoints have their own bucket
+	assert.True(t, e.Allow("other"))
+
+	// and it refills at the configured rate
+	mock.Add(250 * time.Millisecond)
+	assert.False(t, e.Allow("test"))
+	mock.Add(250 * time.Millisecond)
+	assert.True(t, e.Allow("test"))
+	assert.False(t, e.Allow("test"))
+
+	// up to one second of sends
+	mock.Add(time.Minute)
+	assert.True(t, e.Allow("test"))
+	assert.True(t, e.Allow("test"))
+	assert.False(t, e.Allow("test"))
+}
+
+func TestAllowUnlimited(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, e.Allow("test"))
+	}
+	assert.NotContains(t, e.errorPerEndpoint, "test")
+
+	mockConfig.Set("forwarder_endpoint_rate", -1)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, float64(0), e.rate)
+}
+
+func TestAllowSlowRate(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_endpoint_rate", 0.5)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	assert.True(t, e.Allow("test"))
+	assert.False(t, e.Allow("test"))
+	mock.Add(time.Second)
+	assert.False(t, e.Allow("test"))
+	mock.Add(time.Second)
+	assert.True(t, e.Allow("test"))
+}
+
+func TestEndpointDomain(t *testing.T) {
+	assert.Equal(t, "example.com", endpointDomain("https://example.com/api/v1/series"))
+	assert.Equal(t, "example.com:8080", endpointDomain("http://example.com:8080"))
+	assert.Equal(t, "test", endpointDomain("test"))
+}
+
+func TestMaintenanceUnknown(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	e.clearMaintenance("test")
+	assert.NotContains(t, e.errorPerEndpoint, "test")
+
+	e.setMaintenance("test", time.Minute)
 	assert.False(t, e.isBlock("test"))
+	e.close("test")
+	assert.True(t, e.isBlock("test"))
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+}
 
-	// setting an new close
-	e.errorPerEndpoint["test"] = &block{nbError: 1, until: time.Now().Add(30 * time.Second)}
+func TestIsBlockTiming(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	e.close("test")
+	until := e.errorPerEndpoint["test"].until
+	require.True(t, until.After(mock.Now()))
+
+	// the endpoint is blocked up to its deadline, excluded
+	mock.Set(until.Add(-time.Nanosecond))
 	assert.True(t, e.isBlock("test"))
+	mock.Set(until)
+	assert.False(t, e.isBlock("test"))
+}
+
+func TestIsBlockUntilTiming(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+
+	// setting an old close
+	e.errorPerEndpoint["test"] = &block{nbError: 1, until: now.Add(-30 * time.Second)}
+	blocked, until := e.isBlockUntil("test")
+	assert.False(t, blocked)
+	assert.True(t, until.IsZero())
+
+	// setting an new close
+	e.errorPerEndpoint["test"] = &block{nbError: 1, until: now.Add(30 * time.Second)}
+	blocked, until = e.isBlockUntil("test")
+	assert.True(t, blocked)
+	assert.Equal(t, now.Add(30*time.Second), until)
+
+	// the block expires at its deadline
+	mock.Add(30 * time.Second)
+	blocked, until = e.isBlockUntil("test")
+	assert.False(t, blocked)
+	assert.True(t, until.IsZero())
+
+	blocked, until = e.isBlockUntil("unknown")
+	assert.False(t, blocked)
+	assert.True(t, until.IsZero())
 }
 
 func TestIsblockUnknown(t *testing.T) {
@@ -260,3 +930,446 @@ func TestIsblockUnknown(t *testing.T) {
 
 	assert.False(t, e.isBlock("test"))
 }
+
+func TestBlockedCount(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+
+	assert.Equal(t, 0, e.BlockedCount())
+
+	e.errorPerEndpoint["expired"] = &block{nbError: 1, until: now.Add(-10 * time.Second)}
+	e.errorPerEndpoint["recovered"] = &block{nbError: 0, until: now}
+	e.errorPerEndpoint["soon"] = &block{nbError: 1, until: now.Add(10 * time.Second)}
+	e.errorPerEndpoint["late"] = &block{nbError: 3, until: now.Add(30 * time.Second)}
+	assert.Equal(t, 2, e.BlockedCount())
+
+	mock.Add(10 * time.Second)
+	assert.Equal(t, 1, e.BlockedCount())
+	mock.Add(20 * time.Second)
+	assert.Equal(t, 0, e.BlockedCount())
+}
+
+func TestSoonestRetries(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+
+	e.errorPerEndpoint["late"] = &block{nbError: 3, until: now.Add(30 * time.Second)}
+	e.errorPerEndpoint["soon"] = &block{nbError: 1, until: now.Add(10 * time.Second)}
+	e.errorPerEndpoint["middle"] = &block{nbError: 2, until: now.Add(20 * time.Second)}
+	e.errorPerEndpoint["expired"] = &block{nbError: 1, until: now.Add(-10 * time.Second)}
+
+	assert.Equal(t, []BlockedEndpointInfo{
+		{Endpoint: "soon", NbError: 1, Until: now.Add(10 * time.Second)},
+		{Endpoint: "middle", NbError: 2, Until: now.Add(20 * time.Second)},
+	}, e.SoonestRetries(2))
+
+	all := e.SoonestRetries(0)
+	require.Len(t, all, 3)
+	assert.Equal(t, "late", all[2].Endpoint)
+
+	mock.Add(15 * time.Second)
+	assert.Len(t, e.SoonestRetries(10), 2)
+}
+
+func TestBlockedStatus(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_backoff_jitter", 0)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	now := mock.Now()
+
+	assert.Empty(t, e.BlockedStatus())
+
+	e.close("test")
+	e.close("test")
+	assert.Equal(t, map[string]BlockInfo{
+		"test": {NbError: 2, Until: now.Add(e.backoffPolicy.GetBackoffDurationFrom(e.rand, 2)), Blocked: true, FirstBlock: now},
+	}, e.BlockedStatus())
+
+	// The snapshot does not change with the endpoint state, nor changes it
+	status := e.BlockedStatus()
+	info := status["test"]
+	info.NbError = 10
+	status["test"] = info
+	status["other"] = BlockInfo{Blocked: true}
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+	assert.NotContains(t, e.errorPerEndpoint, "other")
+
+	e.recover("test")
+	assert.Equal(t, map[string]BlockInfo{
+		"test": {NbError: 0, Until: now, Blocked: false},
+	}, e.BlockedStatus())
+	assert.Equal(t, 10, status["test"].NbError)
+
+	e.close("test")
+	mock.Add(e.backoffPolicy.GetBackoffDurationFrom(e.rand, 1))
+	assert.Equal(t, map[string]BlockInfo{
+		"test": {NbError: 1, Until: now.Add(e.backoffPolicy.GetBackoffDurationFrom(e.rand, 1)), Blocked: false, FirstBlock: now},
+	}, e.BlockedStatus())
+}
+
+func TestBlockedStatusRecoveryTime(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+	firstBlock := mock.Now()
+
+	e.close("test")
+	mock.Add(10 * time.Second)
+	e.close("test")
+	status := e.BlockedStatus()["test"]
+	assert.Equal(t, firstBlock, status.FirstBlock)
+	assert.Zero(t, status.LastRecoveryDuration)
+
+	mock.Add(20 * time.Second)
+	e.recover("test")
+	status = e.BlockedStatus()["test"]
+	assert.True(t, status.FirstBlock.IsZero())
+	assert.Equal(t, 30*time.Second, status.LastRecoveryDuration)
+
+	_, report := e.report("domain", nil)
+	require.Len(t, report, 1)
+	assert.True(t, report[0].FirstBlock.IsZero())
+	assert.Equal(t, 30*time.Second, report[0].LastRecoveryDuration)
+
+	// the next outage starts from its own first block
+	e.close("test")
+	status = e.BlockedStatus()["test"]
+	assert.Equal(t, firstBlock.Add(30*time.Second), status.FirstBlock)
+	assert.Equal(t, 30*time.Second, status.LastRecoveryDuration)
+}
+
+func TestBlockedEndpointsMaxSizeValid(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	// Verify default
+	assert.Equal(t, config.DefaultForwarderBlockedEndpointsMaxSize, e.maxEndpoints)
+
+	// Verify configuration updates global var
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 10)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, 10, e.maxEndpoints)
+
+	// Verify invalid values recover gracefully
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 0)
+	e = newBlockedEndpoints(mockConfig)
+	assert.Equal(t, config.DefaultForwarderBlockedEndpointsMaxSize, e.maxEndpoints)
+}
+
+func TestBlockedEndpointsPurgeRecovered(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 10)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// an endpoint still failing is never purged
+	e.close("failing")
+
+	for i := 0; i < 100; i++ {
+		endpoint := fmt.Sprintf("https://%d.example.com/api/v1/series", i)
+		e.close(endpoint)
+		mock.Add(e.backoffPolicy.GetBackoffDurationFrom(e.rand, 1))
+		e.recover(endpoint)
+		assert.LessOrEqual(t, len(e.errorPerEndpoint), 10)
+	}
+
+	assert.Contains(t, e.errorPerEndpoint, "failing")
+	assert.Equal(t, 1, e.errorPerEndpoint["failing"].nbError)
+	assert.Contains(t, e.errorPerEndpoint, "https://99.example.com/api/v1/series")
+}
+
+func TestBlockedEndpointsKeepBlocked(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 10)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	// the limit is exceeded rather than forgetting blocked endpoints
+	for i := 0; i < 20; i++ {
+		e.close(fmt.Sprintf("https://%d.example.com/api/v1/series", i))
+	}
+	assert.Len(t, e.errorPerEndpoint, 20)
+	for i := 0; i < 20; i++ {
+		assert.True(t, e.isBlock(fmt.Sprintf("https://%d.example.com/api/v1/series", i)))
+	}
+}
+
+func TestBlockedEndpointsPurgeDeferred(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_recovery_reset", true)
+	mockConfig.Set("forwarder_blocked_endpoints_max_size", 10)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	endpoint := func(i int) string { return fmt.Sprintf("https://%d.example.com/api/v1/series", i) }
+	for i := 0; i < 11; i++ {
+		e.close(endpoint(i))
+	}
+	// all the endpoints are failing: none can be purged before a success
+	assert.Equal(t, noPurge, e.purgeAfter)
+
+	// an endpoint drained until later can be purged once its block expired
+	e.blockFor("drained", time.Minute)
+	assert.Equal(t, mock.Now().Add(time.Minute), e.purgeAfter)
+	e.close(endpoint(11))
+	assert.Contains(t, e.errorPerEndpoint, "drained")
+	mock.Add(time.Minute)
+	e.close(endpoint(12))
+	assert.NotContains(t, e.errorPerEndpoint, "drained")
+
+	// a recovered endpoint is purged with the next new endpoint
+	mock.Add(e.backoffPolicy.GetBackoffDurationFrom(e.rand, 1))
+	e.recover(endpoint(0))
+	assert.Equal(t, mock.Now(), e.purgeAfter)
+	e.close(endpoint(13))
+	assert.NotContains(t, e.errorPerEndpoint, endpoint(0))
+	assert.Len(t, e.errorPerEndpoint, 13)
+	assert.Equal(t, noPurge, e.purgeAfter)
+}
+
+func TestResetAll(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	endpoints := []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"}
+	for _, endpoint := range endpoints {
+		e.close(endpoint)
+		e.closeNonIdempotent(endpoint)
+		require.True(t, e.isBlock(endpoint))
+	}
+
+	e.resetAll()
+	for _, endpoint := range endpoints {
+		assert.False(t, e.isBlock(endpoint))
+		assert.False(t, e.isBlockNonIdempotent(endpoint))
+	}
+	assert.Empty(t, e.BlockedStatus())
+
+	// the endpoints fail again from a fresh state
+	e.close(endpoints[0])
+	assert.Equal(t, 1, e.errorPerEndpoint[endpoints[0]].nbError)
+}
+
+// getEndpointTransitions returns the value of the forwarder__endpoint_<transition>
+// counter of the domain, scraped from the telemetry handler.
+func getEndpointTransitions(t *testing.T, transition string, domain string) float64 {
+	return getDomainMetric(t, "forwarder__endpoint_"+transition, domain)
+}
+
+// getDomainMetric returns the value of the metric of the domain, scraped from
+// the telemetry handler.
+func getDomainMetric(t *testing.T, metric string, domain string) float64 {
+	rec := httptest.NewRecorder()
+	telemetry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	prefix := fmt.Sprintf("%s{domain=%q} ", metric, domain)
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(line, prefix), 64)
+			require.NoError(t, err)
+			return value
+		}
+	}
+	return 0
+}
+
+func TestBlockTransitionTelemetry(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+	domain := "transitions.example.com"
+	endpoint := "https://" + domain + "/api/v1/series"
+
+	// the counters are global, only their increase is checked
+	blockedBase := getEndpointTransitions(t, "blocked", domain)
+	recoveredBase := getEndpointTransitions(t, "recovered", domain)
+	blocked := func() float64 { return getEndpointTransitions(t, "blocked", domain) - blockedBase }
+	recovered := func() float64 { return getEndpointTransitions(t, "recovered", domain) - recoveredBase }
+
+	e.close(endpoint)
+	e.close(endpoint)
+	e.close(endpoint)
+	assert.Equal(t, float64(1), blocked())
+	assert.Equal(t, float64(0), recovered())
+
+	for e.errorPerEndpoint[endpoint].nbError > 0 {
+		assert.Equal(t, float64(0), recovered())
+		e.recover(endpoint)
+	}
+	assert.Equal(t, float64(1), recovered())
+
+	// recovering a healthy endpoint is not a transition
+	e.recover(endpoint)
+	assert.Equal(t, float64(1), recovered())
+
+	e.close(endpoint)
+	assert.Equal(t, float64(2), blocked())
+	e.resetAll()
+	assert.Equal(t, float64(2), recovered())
+	e.resetAll()
+	assert.Equal(t, float64(2), recovered())
+}
+
+func TestResetAllRecoveryTime(t *testing.T) {
+	mockConfig := config.Mock(t)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+	domain := "reset.example.com"
+	endpoint := "https://" + domain + "/api/v1/series"
+
+	// the histogram is global, only its increase is checked
+	observed := func() float64 {
+		return getDomainMetric(t, "transactions__endpoint_recovery_seconds_count", domain)
+	}
+	base := observed()
+
+	e.close(endpoint)
+	clk.Add(30 * time.Second)
+	e.resetAll()
+	assert.Equal(t, float64(1), observed()-base)
+
+	// resetting healthy endpoints records no recovery
+	e.resetAll()
+	assert.Equal(t, float64(1), observed()-base)
+}
+
+func TestReportsCountProbesAndNonIdempotentBlocks(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	clk := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, clk)
+	now := clk.Now()
+
+	// the block of "probing" expired, its probe is being sent
+	e.errorPerEndpoint["probing"] = &block{nbError: 1, until: now}
+	require.False(t, e.isBlockProbe("probing"))
+	require.True(t, e.isBlock("probing"))
+	probeDeadline := e.errorPerEndpoint["probing"].probeDeadline
+
+	// "non-idempotent" only blocks the non-idempotent payloads
+	e.closeNonIdempotent("non-idempotent")
+	require.False(t, e.isBlock("non-idempotent"))
+	require.True(t, e.isBlockNonIdempotent("non-idempotent"))
+	nonIdempotentUntil := e.errorPerEndpoint["non-idempotent"].nonIdempotentUntil
+
+	e.errorPerEndpoint["healthy"] = &block{}
+
+	assert.Equal(t, 2, e.BlockedCount())
+	assert.ElementsMatch(t, []BlockedEndpointInfo{
+		{Endpoint: "probing", NbError: 1, Until: probeDeadline},
+		{Endpoint: "non-idempotent", NbError: 0, Until: nonIdempotentUntil},
+	}, e.SoonestRetries(0))
+	status := e.BlockedStatus()
+	assert.Equal(t, BlockInfo{NbError: 1, Until: probeDeadline, Blocked: true}, status["probing"])
+	assert.Equal(t, BlockInfo{NbError: 0, Until: nonIdempotentUntil, Blocked: true}, status["non-idempotent"])
+	assert.False(t, status["healthy"].Blocked)
+	assert.Equal(t, map[string]int{"probing": 0, "non-idempotent": 0}, e.backlog(nil))
+
+	// the probe succeeds, and the non-idempotent backoff expires
+	e.recover("probing")
+	clk.Set(nonIdempotentUntil)
+	assert.Equal(t, 0, e.BlockedCount())
+	assert.Empty(t, e.SoonestRetries(0))
+	assert.Empty(t, e.backlog(nil))
+}
+
+func TestCloseRecoverContextCanceled(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, e.closeContext(ctx, "test"))
+	assert.False(t, e.isBlock("test"))
+
+	e.close("test")
+	assert.False(t, e.recoverContext(ctx, "test"))
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+
+	assert.True(t, e.recoverContext(context.Background(), "test"))
+	assert.Equal(t, 0, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestCloseContextWaitsForLock(t *testing.T) {
+	mockConfig := config.Mock(t)
+	e := newBlockedEndpoints(mockConfig)
+
+	// another goroutine holds the lock
+	e.m.Lock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan bool)
+	go func() {
+		done <- e.closeContext(ctx, "test")
+	}()
+
+	select {
+	case <-done:
+		require.Fail(t, "closeContext must wait for the lock")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// only a cancellation before the call is noticed: the error is recorded
+	// once the lock is released
+	cancel()
+	e.m.Unlock()
+	select {
+	case closed := <-done:
+		assert.True(t, closed)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "closeContext did not return once the lock was released")
+	}
+	assert.True(t, e.isBlock("test"))
+	assert.Equal(t, 1, e.errorPerEndpoint["test"].nbError)
+}
+
+func TestOnBlockOnRecover(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mock := clock.NewMock()
+	e := newBlockedEndpointsWithClock(mockConfig, mock)
+
+	var blocked []time.Time
+	var recovered []string
+	e.OnBlock(func(endpoint string, until time.Time) {
+		assert.Equal(t, "test", endpoint)
+		// the hooks run outside of the critical section
+		assert.True(t, e.isBlock(endpoint))
+		blocked = append(blocked, until)
+	})
+	e.OnRecover(func(endpoint string) {
+		assert.False(t, e.isBlock(endpoint))
+		recovered = append(recovered, endpoint)
+	})
+
+	e.close("test")
+	until := e.errorPerEndpoint["test"].until
+	e.close("test")
+	e.close("test")
+	assert.Equal(t, []time.Time{until}, blocked)
+	assert.Empty(t, recovered)
+
+	e.recover("test")
+	assert.Empty(t, recovered, "the endpoint still has errors")
+	for e.errorPerEndpoint["test"].nbError > 0 {
+		e.recover("test")
+	}
+	assert.Equal(t, []string{"test"}, recovered)
+
+	// a healthy endpoint does not recover again
+	e.recover("test")
+	assert.Equal(t, []string{"test"}, recovered)
+
+	mock.Add(time.Minute)
+	e.close("test")
+	assert.Len(t, blocked, 2)
+	e.resetAll()
+	assert.Equal(t, []string{"test", "test"}, recovered)
+}
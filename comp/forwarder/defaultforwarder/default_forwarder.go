@@ -6,6 +6,7 @@
 package defaultforwarder
 
 import (
+	"expvar"
 	"fmt"
 	"net/http"
 	"path"
@@ -54,7 +55,13 @@ type Response struct {
 	Err        error
 }
 
-// Forwarder interface allows packages to send payload to the backend
+// Forwarder interface allows packages to send payload to the backend.
+//
+// The extra headers of the Submit methods are sent with each transaction of the
+// payloads, except transaction.NonIdempotentHTTPHeaderKey: setting it to "true"
+// marks the transactions as non-idempotent, so that their failures use a
+// separate backoff and they are only retried when forwarder_retry_non_idempotent
+// is enabled.
 type Forwarder interface {
 	Start() error
 	Stop()
@@ -75,6 +82,7 @@ type Forwarder interface {
 	SubmitConnectionChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error)
 	SubmitOrchestratorChecks(payload transaction.BytesPayloads, extra http.Header, payloadType int) (chan Response, error)
 	SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error)
+	ClearBlockedEndpoints()
 }
 
 // Compile-time check to ensure that DefaultForwarder implements the Forwarder interface
@@ -360,6 +368,16 @@ func (f *DefaultForwarder) Start() error {
 	log.Infof("Forwarder started, sending to %v endpoint(s) with %v worker(s) each: %s",
 		len(endpointLogs), f.NumberOfWorkers, strings.Join(endpointLogs, " ; "))
 
+	transaction.ForwarderExpvars.Set("Backoff", expvar.Func(func() interface{} {
+		return f.BackoffReport()
+	}))
+	transaction.ForwarderExpvars.Set("BlockedEndpoints", expvar.Func(func() interface{} {
+		return f.BlockedEndpointsCount()
+	}))
+	transaction.ForwarderExpvars.Set("BlockedEndpointsBacklog", expvar.Func(func() interface{} {
+		return f.BlockedEndpointsBacklog()
+	}))
+
 	f.healthChecker.Start()
 	f.internalState.Store(Started)
 	return nil
@@ -431,6 +449,8 @@ func (f *DefaultForwarder) createHTTPTransactions(endpoint transaction.Endpoint,
 func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.Endpoint, payloads transaction.BytesPayloads, extra http.Header, priority transaction.Priority, storableOnDisk bool) []*transaction.HTTPTransaction {
 	transactions := make([]*transaction.HTTPTransaction, 0, len(payloads)*len(f.domainForwarders))
 	allowArbitraryTags := f.config.GetBool("allow_arbitrary_tags")
+	nonIdempotent := extra.Get(transaction.NonIdempotentHTTPHeaderKey) == "true"
+	nonIdempotentKey := http.CanonicalHeaderKey(transaction.NonIdempotentHTTPHeaderKey)
 
 	for _, payload := range payloads {
 		for domain, dr := range f.domainResolvers {
@@ -441,6 +461,7 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				t.Payload = payload
 				t.Priority = priority
 				t.StorableOnDisk = storableOnDisk
+				t.NonIdempotent = nonIdempotent
 				t.Headers.Set(apiHTTPHeaderKey, apiKey)
 				t.Headers.Set(versionHTTPHeaderKey, version.AgentVersion)
 				t.Headers.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
@@ -458,6 +479,9 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				transactionsInputBytesByEndpoint.Add(endpoint.Name, int64(t.GetPayloadSize()))
 
 				for key := range extra {
+					if key == nonIdempotentKey {
+						continue
+					}
 					t.Headers.Set(key, extra.Get(key))
 				}
 				transactions = append(transactions, t)
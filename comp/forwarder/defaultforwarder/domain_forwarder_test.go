@@ -10,9 +10,16 @@ package defaultforwarder
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -318,6 +325,129 @@ forwarder_requeue_buffer_size: 1300
 	assert.Equal(t, 1300, cap(forwarder.requeuedTransaction))
 }
 
+// fakeIntake records the payloads it accepted and rejects everything with a
+// 503 while it is down.
+type fakeIntake struct {
+	m         sync.Mutex
+	down      bool
+	delivered map[string]int
+}
+
+func (i *fakeIntake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	i.m.Lock()
+	defer i.m.Unlock()
+	if i.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	i.delivered[string(body)]++
+}
+
+func (i *fakeIntake) setDown(down bool) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	i.down = down
+}
+
+func TestDomainForwarderNoDataLossAcrossBlockRecover(t *testing.T) {
+	const payloadCount = 20
+
+	intake := &fakeIntake{down: true, delivered: map[string]int{}}
+	server := httptest.NewServer(intake)
+	defer server.Close()
+
+	mockConfig := pkgconfig.Mock(t)
+	transactionRetryQueue := retry.NewTransactionRetryQueue(
+		transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true},
+		nil,
+		1<<20,
+		0,
+		retry.NewTransactionRetryQueueTelemetry("domain"),
+		retry.NewPointCountTelemetryMock())
+	forwarder := newDomainForwarder(mockConfig, "test", transactionRetryQueue, 1, 0, transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}, retry.NewPointCountTelemetry("domain", nil))
+	forwarder.init()
+	mockClock := clock.NewMock()
+	forwarder.blockedList.clock = mockClock
+
+	sent := &PointSuccessfullySentMock{}
+	w := NewWorker(mockConfig, forwarder.highPrio, forwarder.lowPrio, forwarder.requeuedTransaction, forwarder.blockedList, sent)
+
+	droppedBefore := transaction.TransactionsDropped.Value()
+
+	var transactions []*transaction.HTTPTransaction
+	for i := 0; i < payloadCount; i++ {
+		tr := transaction.NewHTTPTransaction()
+		tr.Domain = server.URL
+		tr.Endpoint.Route = "/api/v1/series"
+		tr.Payload = transaction.NewBytesPayload([]byte(fmt.Sprintf("payload-%d", i)), 1)
+		transactions = append(transactions, tr)
+		forwarder.highPrio <- tr
+	}
+	target := transactions[0].GetTarget()
+
+	// step processes the transactions handed to the worker, as the worker
+	// goroutine would, then hands the failed ones back to the retry queue, as
+	// handleFailedTransactions would
+	step := func() {
+		for {
+			select {
+			case tr := <-forwarder.highPrio:
+				w.process(context.Background(), tr)
+			case tr := <-forwarder.lowPrio:
+				w.process(context.Background(), tr)
+			default:
+				for {
+					select {
+					case tr := <-forwarder.requeuedTransaction:
+						forwarder.requeueTransaction(tr)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}
+	// advance moves the clock to the end of the current backoff and retries
+	advance := func() {
+		mockClock.Set(forwarder.blockedList.errorPerEndpoint[target].until)
+		forwarder.retryTransactions(mockClock.Now())
+		step()
+	}
+
+	step()
+	require.True(t, forwarder.blockedList.isBlock(target))
+	require.Equal(t, payloadCount, transactionRetryQueue.GetTransactionCount())
+
+	// go through several backoff periods with the intake still down
+	for i := 0; i < 5; i++ {
+		advance()
+		require.True(t, forwarder.blockedList.isBlock(target))
+		require.Equal(t, payloadCount, transactionRetryQueue.GetTransactionCount())
+	}
+	assert.Empty(t, intake.delivered)
+
+	intake.setDown(false)
+	for i := 0; i < 10*payloadCount && transactionRetryQueue.GetTransactionCount() > 0; i++ {
+		advance()
+	}
+	require.Zero(t, transactionRetryQueue.GetTransactionCount())
+
+	// the transactions retried while the endpoint was blocked were requeued
+	// without being sent, so only some of them went through failed attempts
+	require.Len(t, intake.delivered, payloadCount)
+	attempts := 0
+	for i, tr := range transactions {
+		assert.Equal(t, 1, intake.delivered[fmt.Sprintf("payload-%d", i)], "payload %d", i)
+		assert.GreaterOrEqual(t, tr.Attempts, 1, "payload %d", i)
+		attempts += tr.Attempts
+	}
+	assert.Greater(t, attempts, payloadCount)
+	assert.Equal(t, int64(payloadCount), sent.count.Load())
+	assert.Equal(t, droppedBefore, transaction.TransactionsDropped.Value())
+}
+
 func newDomainForwarderForTest(config config.Component, connectionResetInterval time.Duration) *domainForwarder {
 	sorter := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	telemetry := retry.NewTransactionRetryQueueTelemetry("domain")
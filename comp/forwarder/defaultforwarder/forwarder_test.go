@@ -336,6 +336,44 @@ func TestSubmitV1Intake(t *testing.T) {
 	}
 }
 
+func TestSubmitNonIdempotent(t *testing.T) {
+	mockConfig := pkgconfig.Mock(t)
+	forwarder := NewDefaultForwarder(mockConfig, NewOptionsWithResolvers(mockConfig, resolver.NewSingleDomainResolvers(monoKeysDomains)))
+	forwarder.Start()
+	defer forwarder.Stop()
+
+	inputQueue := make(chan transaction.Transaction, 1)
+	df := forwarder.domainForwarders[testVersionDomain]
+	bk := df.highPrio
+	df.highPrio = inputQueue
+	defer func() { df.highPrio = bk }()
+
+	p := []byte("test")
+	extra := make(http.Header)
+	extra.Set(transaction.NonIdempotentHTTPHeaderKey, "true")
+	assert.Nil(t, forwarder.SubmitV1CheckRuns(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p}), extra))
+
+	select {
+	case tr := <-df.highPrio:
+		require.NotNil(t, tr)
+		assert.False(t, tr.IsIdempotent())
+		// the header is not sent to the backend
+		assert.Empty(t, tr.(*transaction.HTTPTransaction).Headers.Get(transaction.NonIdempotentHTTPHeaderKey))
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "highPrio queue should contain a transaction")
+	}
+
+	// the payloads are idempotent unless marked otherwise
+	assert.Nil(t, forwarder.SubmitV1CheckRuns(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p}), make(http.Header)))
+	select {
+	case tr := <-df.highPrio:
+		require.NotNil(t, tr)
+		assert.True(t, tr.IsIdempotent())
+	case <-time.After(1 * time.Second):
+		require.Fail(t, "highPrio queue should contain a transaction")
+	}
+}
+
 // TestForwarderEndtoEnd is a simple test to see if a payload is well broadcast
 // between every components of the forwarder. Corner cases and error are tested
 // per component.
@@ -79,15 +79,16 @@ func (s *HTTPTransactionsSerializer) Add(transaction *transaction.HTTPTransactio
 		// If a user can update the domain for some serialized transactions, they can replace the domain
 		// by a local address like http://127.0.0.1:1234. The Agent would send the HTTP transactions to the url
 		// http://127.0.0.1:1234/intake/?api_key=API_KEY which contains the API_KEY.
-		Domain:     "",
-		Endpoint:   &EndpointProto{Route: s.replaceAPIKeys(endpoint.Route), Name: endpoint.Name},
-		Headers:    s.toHeaderProto(transaction.Headers),
-		Payload:    payload,
-		ErrorCount: int64(transaction.ErrorCount),
-		CreatedAt:  transaction.CreatedAt.Unix(),
-		Retryable:  transaction.Retryable,
-		Priority:   priority,
-		PointCount: pointCount,
+		Domain:        "",
+		Endpoint:      &EndpointProto{Route: s.replaceAPIKeys(endpoint.Route), Name: endpoint.Name},
+		Headers:       s.toHeaderProto(transaction.Headers),
+		Payload:       payload,
+		ErrorCount:    int64(transaction.ErrorCount),
+		CreatedAt:     transaction.CreatedAt.Unix(),
+		Retryable:     transaction.Retryable,
+		Priority:      priority,
+		PointCount:    pointCount,
+		NonIdempotent: transaction.NonIdempotent,
 	}
 	s.collection.Values = append(s.collection.Values, &transactionProto)
 	return nil
@@ -142,6 +143,7 @@ func (s *HTTPTransactionsSerializer) Deserialize(bytes []byte) ([]transaction.Tr
 			Retryable:      tr.Retryable,
 			StorableOnDisk: true,
 			Priority:       priority,
+			NonIdempotent:  tr.NonIdempotent,
 		}
 		tr.SetDefaultHandlers()
 		httpTransactions = append(httpTransactions, &tr)
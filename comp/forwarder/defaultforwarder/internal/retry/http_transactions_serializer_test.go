@@ -59,6 +59,26 @@ func runTestHTTPSerializeDeserializeWithResolver(t *testing.T, d string, r resol
 	a.Len(transactions, 0)
 }
 
+func TestHTTPSerializeDeserializeNonIdempotent(t *testing.T) {
+	a := assert.New(t)
+	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, nil))
+
+	for _, nonIdempotent := range []bool{true, false} {
+		tr := createHTTPTransactionTests(domain)
+		tr.NonIdempotent = nonIdempotent
+		a.NoError(serializer.Add(tr))
+	}
+	bytes, err := serializer.GetBytesAndReset()
+	a.NoError(err)
+
+	transactions, errorCount, err := serializer.Deserialize(bytes)
+	a.NoError(err)
+	a.Equal(0, errorCount)
+	a.Len(transactions, 2)
+	a.False(transactions[0].IsIdempotent())
+	a.True(transactions[1].IsIdempotent())
+}
+
 func TestPartialDeserialize(t *testing.T) {
 	a := assert.New(t)
 	initialTransaction := createHTTPTransactionTests(domain)
@@ -104,7 +124,7 @@ func TestHTTPTransactionSerializerMissingAPIKey(t *testing.T) {
 func TestHTTPTransactionFieldsCount(t *testing.T) {
 	tr := transaction.HTTPTransaction{}
 	transactionType := reflect.TypeOf(tr)
-	assert.Equalf(t, 11, transactionType.NumField(),
+	assert.Equalf(t, 15, transactionType.NumField(),
 		"A field was added or remove from HTTPTransaction. "+
 			"You probably need to update the implementation of "+
 			"HTTPTransactionsSerializer and then adjust this unit test.")
@@ -135,6 +155,7 @@ func assertTransactionEqual(a *assert.Assertions, tr1 *transaction.HTTPTransacti
 	a.Equal(tr1.Retryable, tr2.Retryable)
 	a.Equal(tr1.Priority, tr2.Priority)
 	a.Equal(tr1.ErrorCount, tr2.ErrorCount)
+	a.Equal(tr1.NonIdempotent, tr2.NonIdempotent)
 
 	a.NotNil(tr1.Payload)
 	a.NotNil(tr2.Payload)
@@ -189,6 +189,19 @@ func (tc *TransactionRetryQueue) GetTransactionCount() int {
 	return len(tc.transactions)
 }
 
+// GetTransactionCountByTarget gets the number of transactions in memory per
+// target. The transactions flushed to disk are not counted.
+func (tc *TransactionRetryQueue) GetTransactionCountByTarget() map[string]int {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, t := range tc.transactions {
+		counts[t.GetTarget()]++
+	}
+	return counts
+}
+
 // GetMaxMemSizeInBytes gets the maximum memory usage for storing transactions
 func (tc *TransactionRetryQueue) GetMaxMemSizeInBytes() int {
 	tc.mutex.RLock()
@@ -56,6 +56,29 @@ func TestTransactionRetryQueueAdd(t *testing.T) {
 	assertPayloadSizeFromExtractTransactions(a, container, nil)
 }
 
+func TestTransactionRetryQueueGetTransactionCountByTarget(t *testing.T) {
+	a := assert.New(t)
+	container := NewTransactionRetryQueue(createDropPrioritySorter(), nil, 100, 0.6, NewTransactionRetryQueueTelemetry("domain"), NewPointCountTelemetryMock())
+	a.Empty(container.GetTransactionCountByTarget())
+
+	for _, route := range []string{"/api/v1/series", "/api/v1/check_run", "/api/v1/series"} {
+		tr := transaction.NewHTTPTransaction()
+		tr.Domain = "https://example.com"
+		tr.Endpoint.Route = route
+		tr.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte{1})
+		_, err := container.Add(tr)
+		a.NoError(err)
+	}
+	a.Equal(map[string]int{
+		"https://example.com/api/v1/series":    2,
+		"https://example.com/api/v1/check_run": 1,
+	}, container.GetTransactionCountByTarget())
+
+	_, err := container.ExtractTransactions()
+	a.NoError(err)
+	a.Empty(container.GetTransactionCountByTarget())
+}
+
 func TestTransactionRetryQueueSeveralFlushToDisk(t *testing.T) {
 	a := assert.New(t)
 	q := newOnDiskRetryQueueTest(t, a)
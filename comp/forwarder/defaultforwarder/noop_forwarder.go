@@ -104,3 +104,6 @@ func (f NoopForwarder) SubmitOrchestratorChecks(payload transaction.BytesPayload
 func (f NoopForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	return nil, nil
 }
+
+// ClearBlockedEndpoints does nothing.
+func (f NoopForwarder) ClearBlockedEndpoints() {}
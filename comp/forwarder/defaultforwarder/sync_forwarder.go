@@ -159,3 +159,8 @@ func (f *SyncForwarder) SubmitOrchestratorChecks(payload transaction.BytesPayloa
 func (f *SyncForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	return f.defaultForwarder.SubmitOrchestratorManifests(payload, extra)
 }
+
+// ClearBlockedEndpoints does nothing: the sync forwarder does not block the
+// endpoints it fails to send to.
+func (f *SyncForwarder) ClearBlockedEndpoints() {
+}
@@ -40,6 +40,13 @@ var (
 		[]string{"domain", "endpoint"}, "Transaction retry count")
 	tlmTxRetryQueueSize = telemetry.NewGauge("transactions", "retry_queue_size",
 		[]string{"domain"}, "Retry queue size")
+	tlmEndpointRecoveryTime = telemetry.NewHistogram("transactions", "endpoint_recovery_seconds",
+		[]string{"domain"}, "Time between the first error on a blocked endpoint and its recovery, in seconds",
+		[]float64{1, 5, 15, 60, 300, 900, 3600, 14400})
+	tlmEndpointBlocked = telemetry.NewCounter("forwarder", "endpoint_blocked",
+		[]string{"domain"}, "Count of endpoints starting to fail")
+	tlmEndpointRecovered = telemetry.NewCounter("forwarder", "endpoint_recovered",
+		[]string{"domain"}, "Count of failing endpoints recovering")
 )
 
 func init() {
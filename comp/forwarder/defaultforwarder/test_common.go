@@ -21,9 +21,10 @@ import (
 
 type testTransaction struct {
 	mock.Mock
-	assertClient bool
-	processed    chan bool
-	pointCount   int
+	assertClient  bool
+	processed     chan bool
+	pointCount    int
+	nonIdempotent bool
 }
 
 func newTestTransaction() *testTransaction {
@@ -57,6 +58,10 @@ func (t *testTransaction) GetTarget() string {
 	return t.Called().Get(0).(string)
 }
 
+func (t *testTransaction) IsIdempotent() bool {
+	return !t.nonIdempotent
+}
+
 func (t *testTransaction) GetPriority() transaction.Priority {
 	return transaction.TransactionPriorityNormal
 }
@@ -179,3 +184,8 @@ func (tf *MockedForwarder) SubmitOrchestratorChecks(payload transaction.BytesPay
 func (tf *MockedForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	return nil, tf.Called(payload, extra).Error(0)
 }
+
+// ClearBlockedEndpoints mock
+func (tf *MockedForwarder) ClearBlockedEndpoints() {
+	tf.Called()
+}
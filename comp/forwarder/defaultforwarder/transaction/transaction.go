@@ -23,6 +23,11 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/scrubber"
 )
 
+// NonIdempotentHTTPHeaderKey is the header a caller sets to "true" in the extra
+// headers of a payload to mark its transactions as non-idempotent. It is not
+// sent to the backend.
+const NonIdempotentHTTPHeaderKey = "DD-Non-Idempotent"
+
 var (
 	// ForwarderExpvars is the root for expvars in the forwarder.
 	ForwarderExpvars = expvar.NewMap("forwarder")
@@ -69,6 +74,12 @@ var (
 		[]string{"domain", "endpoint", "error_type"}, "Count of transactions errored grouped by type of error")
 	tlmTxHTTPErrors = telemetry.NewCounter("transactions", "http_errors",
 		[]string{"domain", "endpoint", "code"}, "Count of transactions http errors per http code")
+	tlmTxDeliveryAttempts = telemetry.NewHistogram("transactions", "delivery_attempts",
+		[]string{"domain", "endpoint"}, "Number of attempts needed to deliver a transaction",
+		[]float64{1, 2, 3, 5, 10, 20, 50})
+	tlmTxDeliveryRetryWait = telemetry.NewHistogram("transactions", "delivery_retry_wait",
+		[]string{"domain", "endpoint"}, "Time in seconds a delivered transaction spent waiting between its attempts",
+		[]float64{1, 10, 60, 300, 900, 3600})
 )
 
 // Trace is an httptrace.ClientTrace instance that traces the events within HTTP client requests.
@@ -184,6 +195,21 @@ type HTTPTransaction struct {
 	// StorableOnDisk indicates whether this transaction can be stored on disk
 	StorableOnDisk bool
 
+	// NonIdempotent indicates that sending this transaction twice may duplicate data,
+	// so that its failures are accounted separately by the forwarder
+	// This field is not restored when a transaction is deserialized from the disk (the default value is used).
+	NonIdempotent bool
+
+	// Attempts is the number of times the transaction was processed
+	// This field is not restored when a transaction is deserialized from the disk (the default value is used).
+	Attempts int
+	// RetryWait is the cumulative time spent between the end of an attempt and
+	// the start of the next one, while the transaction waited to be retried
+	// This field is not restored when a transaction is deserialized from the disk (the default value is used).
+	RetryWait time.Duration
+	// lastAttemptEnd is when the previous attempt completed
+	lastAttemptEnd time.Time
+
 	// AttemptHandler will be called with a transaction before the attempting to send the request
 	// This field is not restored when a transaction is deserialized from the disk (the default value is used).
 	AttemptHandler HTTPAttemptHandler
@@ -208,6 +234,10 @@ type Transaction interface {
 	GetEndpointName() string
 	GetPayloadSize() int
 	GetPointCount() int
+	// IsIdempotent returns whether the transaction can safely be sent more
+	// than once. The failures of the other transactions are accounted
+	// separately by the forwarder.
+	IsIdempotent() bool
 
 	// This method serializes the transaction to `TransactionsSerializer`.
 	// It forces a new implementation of `Transaction` to define how to
@@ -246,6 +276,11 @@ func (t *HTTPTransaction) GetTarget() string {
 	return scrubber.ScrubLine(url) // sanitized url that can be logged
 }
 
+// IsIdempotent returns whether the transaction can safely be sent more than once
+func (t *HTTPTransaction) IsIdempotent() bool {
+	return !t.NonIdempotent
+}
+
 // GetPriority returns the priority
 func (t *HTTPTransaction) GetPriority() Priority {
 	return t.Priority
@@ -277,7 +312,13 @@ func (t *HTTPTransaction) GetPointCount() int {
 func (t *HTTPTransaction) Process(ctx context.Context, config config.Component, client *http.Client) error {
 	t.AttemptHandler(t)
 
+	if !t.lastAttemptEnd.IsZero() {
+		t.RetryWait += time.Since(t.lastAttemptEnd)
+	}
+	t.Attempts++
+
 	statusCode, body, err := t.internalProcess(ctx, config, client)
+	t.lastAttemptEnd = time.Now()
 
 	if err == nil || !t.Retryable {
 		t.CompletionHandler(t, statusCode, body, err)
@@ -362,7 +403,11 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, config config.Com
 		t.ErrorCount++
 		transactionsErrors.Add(1)
 		tlmTxErrors.Inc(t.Domain, transactionEndpointName, "gt_400")
-		return resp.StatusCode, body, fmt.Errorf("error %q while sending transaction to %q, rescheduling it: %q", resp.Status, logURL, truncateBodyForLog(body))
+		err := fmt.Errorf("error %q while sending transaction to %q, rescheduling it: %q", resp.Status, logURL, truncateBodyForLog(body))
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); retryAfter > 0 {
+			err = &RetryAfterError{Err: err, RetryAfter: retryAfter}
+		}
+		return resp.StatusCode, body, err
 	}
 
 	tlmTxSuccessCount.Inc(t.Domain, transactionEndpointName)
@@ -370,6 +415,7 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, config config.Com
 	TransactionsSuccessByEndpoint.Add(transactionEndpointName, 1)
 	transactionsSuccessBytesByEndpoint.Add(transactionEndpointName, int64(t.GetPayloadSize()))
 	transactionsSuccess.Add(1)
+	t.recordDelivery(config, logURL)
 
 	loggingFrequency := config.GetInt64("logging_frequency")
 
@@ -387,6 +433,52 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, config config.Com
 	return resp.StatusCode, body, nil
 }
 
+// recordDelivery reports the number of attempts and the time spent waiting
+// for retries of a successfully delivered transaction.
+func (t *HTTPTransaction) recordDelivery(config config.Component, logURL string) {
+	transactionEndpointName := t.GetEndpointName()
+	tlmTxDeliveryAttempts.Observe(float64(t.Attempts), t.Domain, transactionEndpointName)
+	tlmTxDeliveryRetryWait.Observe(t.RetryWait.Seconds(), t.Domain, transactionEndpointName)
+
+	if t.Attempts > 1 && config.GetBool("forwarder_log_retry_timeline") {
+		log.Infof("Payload to %q delivered after %d attempts, %v spent waiting for retries since %s",
+			logURL, t.Attempts, t.RetryWait, t.CreatedAt.Format(time.RFC3339))
+	}
+}
+
+// RetryAfterError is returned by Process when the intake answered with a
+// Retry-After header.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number
+// of seconds or an HTTP date. It returns 0 if the value is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 // SerializeTo serializes the transaction using TransactionsSerializer
 func (t *HTTPTransaction) SerializeTo(serializer TransactionsSerializer) error {
 	if t.StorableOnDisk {
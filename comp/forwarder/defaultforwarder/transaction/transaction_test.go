@@ -16,6 +16,7 @@ import (
 
 	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewHTTPTransaction(t *testing.T) {
@@ -119,6 +120,81 @@ func TestProcessHTTPError(t *testing.T) {
 	assert.Equal(t, transaction.ErrorCount, 1)
 }
 
+func TestProcessRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	transaction := NewHTTPTransaction()
+	transaction.Domain = ts.URL
+	transaction.Endpoint.Route = "/endpoint/test"
+	transaction.Payload = NewBytesPayloadWithoutMetaData([]byte("test payload"))
+
+	mockConfig := pkgconfig.Mock(t)
+	err := transaction.Process(context.Background(), mockConfig, &http.Client{})
+
+	var retryAfterErr *RetryAfterError
+	assert.ErrorAs(t, err, &retryAfterErr)
+	assert.Equal(t, 120*time.Second, retryAfterErr.RetryAfter)
+	assert.Contains(t, err.Error(), "error \"429 Too Many Requests\" while sending transaction")
+}
+
+func TestProcessRetryTimeline(t *testing.T) {
+	failures := 3
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	transaction := NewHTTPTransaction()
+	transaction.Domain = ts.URL
+	transaction.Endpoint.Route = "/endpoint/test"
+	transaction.Payload = NewBytesPayloadWithoutMetaData([]byte("test payload"))
+
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.Set("forwarder_log_retry_timeline", true)
+
+	var delivered *HTTPTransaction
+	transaction.CompletionHandler = func(tr *HTTPTransaction, statusCode int, body []byte, err error) {
+		delivered = tr
+	}
+
+	for i := 0; i < 3; i++ {
+		err := transaction.Process(context.Background(), mockConfig, &http.Client{})
+		require.Error(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, delivered)
+	assert.Equal(t, 3, transaction.Attempts)
+	waitBeforeSuccess := transaction.RetryWait
+	assert.GreaterOrEqual(t, waitBeforeSuccess, 20*time.Millisecond)
+
+	err := transaction.Process(context.Background(), mockConfig, &http.Client{})
+	require.NoError(t, err)
+	require.NotNil(t, delivered)
+	assert.Equal(t, 4, delivered.Attempts)
+	assert.Equal(t, 3, delivered.ErrorCount)
+	assert.GreaterOrEqual(t, delivered.RetryWait, waitBeforeSuccess+10*time.Millisecond)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-3", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-90*time.Second).Format(http.TimeFormat), now))
+}
+
 func TestProcessCancel(t *testing.T) {
 	transaction := NewHTTPTransaction()
 	transaction.Domain = "example.com"
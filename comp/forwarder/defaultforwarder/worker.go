@@ -7,6 +7,7 @@ package defaultforwarder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptrace"
@@ -33,6 +34,7 @@ type Worker struct {
 	// RequeueChan is the channel used to send failed transaction back to the Forwarder.
 	RequeueChan chan<- transaction.Transaction
 
+	retryNonIdempotent    bool
 	resetConnectionChan   chan struct{}
 	stopChan              chan struct{}
 	stopped               chan struct{}
@@ -59,6 +61,7 @@ func NewWorker(
 		HighPrio:              highPrioChan,
 		LowPrio:               lowPrioChan,
 		RequeueChan:           requeueChan,
+		retryNonIdempotent:    config.GetBool("forwarder_retry_non_idempotent"),
 		resetConnectionChan:   make(chan struct{}, 1),
 		stopChan:              make(chan struct{}),
 		stopped:               make(chan struct{}),
@@ -186,16 +189,67 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 
 	// Run the endpoint through our blockedEndpoints circuit breaker
 	target := t.GetTarget()
-	if w.blockedList.isBlock(target) {
+	if !t.IsIdempotent() {
+		w.processNonIdempotent(ctx, t, target, requeue)
+	} else if w.blockedList.isBlockProbe(target) {
 		requeue()
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
+	} else if !w.blockedList.Allow(target) {
+		// the probe the transaction may have claimed is not sent
+		w.blockedList.releaseProbe(target)
+		requeue()
+		log.Debugf("Send rate limit reached for endpoint '%s': retrying later", target)
 	} else if err := t.Process(ctx, w.config, w.Client); err != nil {
-		w.blockedList.close(target)
+		var retryAfterErr *transaction.RetryAfterError
+		// the errors of the transactions canceled by Stop are not recorded
+		var recorded bool
+		if errors.As(err, &retryAfterErr) {
+			recorded = w.blockedList.closeWithRetryAfterContext(ctx, target, retryAfterErr.RetryAfter)
+		} else {
+			recorded = w.blockedList.closeContext(ctx, target)
+		}
+		if !recorded {
+			w.blockedList.releaseProbe(target)
+		}
 		requeue()
 		log.Errorf("Error while processing transaction: %v", err)
 	} else {
 		w.pointSuccessfullySent.OnPointSuccessfullySent(t.GetPointCount())
-		w.blockedList.recover(target)
+		if !w.blockedList.recoverContext(ctx, target) {
+			w.blockedList.releaseProbe(target)
+		}
+	}
+}
+
+// processNonIdempotent processes a transaction that may not be safely sent twice:
+// its failures use a separate backoff, and it is dropped instead of being
+// retried when forwarder_retry_non_idempotent is disabled.
+func (w *Worker) processNonIdempotent(ctx context.Context, t transaction.Transaction, target string, requeue func()) {
+	if w.blockedList.isBlockNonIdempotent(target) {
+		requeue()
+		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
+	} else if !w.blockedList.Allow(target) {
+		requeue()
+		log.Debugf("Send rate limit reached for endpoint '%s': retrying later", target)
+	} else if err := t.Process(ctx, w.config, w.Client); err != nil {
+		var retryAfterErr *transaction.RetryAfterError
+		var retryAfter time.Duration
+		if errors.As(err, &retryAfterErr) {
+			retryAfter = retryAfterErr.RetryAfter
+		}
+		// the errors of the transactions canceled by Stop are not recorded
+		w.blockedList.closeNonIdempotentContext(ctx, target, retryAfter)
+		if w.retryNonIdempotent {
+			requeue()
+		} else {
+			transaction.TransactionsDroppedByEndpoint.Add(t.GetEndpointName(), 1)
+			transaction.TransactionsDropped.Add(1)
+			transaction.TlmTxDropped.Inc(endpointDomain(target), t.GetEndpointName())
+		}
+		log.Errorf("Error while processing non-idempotent transaction: %v", err)
+	} else {
+		w.pointSuccessfullySent.OnPointSuccessfullySent(t.GetPointCount())
+		w.blockedList.recoverContext(ctx, target)
 	}
 }
 
@@ -9,11 +9,13 @@
 package defaultforwarder
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
 
@@ -125,6 +127,156 @@ func TestWorkerRetryBlockedTransaction(t *testing.T) {
 	assert.True(t, w.blockedList.isBlock("error_url"))
 }
 
+func TestWorkerRetryRateLimitedTransaction(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.Set("forwarder_endpoint_rate", 1)
+	w := NewWorker(mockConfig, highPrio, lowPrio, requeue, newBlockedEndpoints(mockConfig), &PointSuccessfullySentMock{})
+	w.blockedList.clock = clock.NewMock()
+
+	// use the only token of the endpoint
+	assert.True(t, w.blockedList.Allow("rate_limited_url"))
+
+	mock := newTestTransaction()
+	mock.On("GetTarget").Return("rate_limited_url").Times(1)
+
+	w.Start()
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+	mock.AssertExpectations(t)
+	mock.AssertNumberOfCalls(t, "Process", 0)
+	assert.Equal(t, mock, retryTransaction)
+	assert.False(t, w.blockedList.isBlock("rate_limited_url"))
+}
+
+func TestWorkerRateLimitedProbeReleased(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.Set("forwarder_endpoint_rate", 1)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	w := NewWorker(mockConfig, highPrio, lowPrio, requeue, newBlockedEndpoints(mockConfig), &PointSuccessfullySentMock{})
+	clk := clock.NewMock()
+	w.blockedList.clock = clk
+
+	// the block of the endpoint expired, and its only token is used
+	w.blockedList.close("probe_url")
+	clk.Set(w.blockedList.errorPerEndpoint["probe_url"].until)
+	assert.True(t, w.blockedList.Allow("probe_url"))
+
+	mock := newTestTransaction()
+	mock.On("GetTarget").Return("probe_url").Times(1)
+
+	w.Start()
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+	mock.AssertExpectations(t)
+	mock.AssertNumberOfCalls(t, "Process", 0)
+	assert.Equal(t, mock, retryTransaction)
+
+	// the rate limited transaction did not keep the probe
+	assert.False(t, w.blockedList.isBlock("probe_url"))
+	assert.False(t, w.blockedList.isBlockProbe("probe_url"))
+}
+
+func TestWorkerCanceledProbeReleased(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 2)
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.Set("forwarder_half_open_probe", true)
+	w := NewWorker(mockConfig, highPrio, lowPrio, requeue, newBlockedEndpoints(mockConfig), &PointSuccessfullySentMock{})
+	clk := clock.NewMock()
+	w.blockedList.clock = clk
+
+	w.blockedList.close("probe_url")
+	clk.Set(w.blockedList.errorPerEndpoint["probe_url"].until)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the outcomes of the probes canceled by Stop are not recorded
+	failed := newTestTransaction()
+	failed.On("Process", w.Client).Return(fmt.Errorf("canceled")).Times(1)
+	failed.On("GetTarget").Return("probe_url").Times(1)
+	w.process(ctx, failed)
+	assert.Equal(t, 1, w.blockedList.errorPerEndpoint["probe_url"].nbError)
+	assert.False(t, w.blockedList.isBlock("probe_url"))
+
+	sent := newTestTransaction()
+	sent.On("Process", w.Client).Return(nil).Times(1)
+	sent.On("GetTarget").Return("probe_url").Times(1)
+	w.process(ctx, sent)
+	assert.Equal(t, 1, w.blockedList.errorPerEndpoint["probe_url"].nbError)
+	assert.False(t, w.blockedList.isBlock("probe_url"))
+
+	failed.AssertExpectations(t)
+	sent.AssertExpectations(t)
+	assert.Len(t, requeue, 1)
+}
+
+func TestWorkerNonIdempotentNotRetried(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 2)
+	mockConfig := pkgconfig.Mock(t)
+	mockConfig.Set("forwarder_retry_non_idempotent", false)
+	w := NewWorker(mockConfig, highPrio, lowPrio, requeue, newBlockedEndpoints(mockConfig), &PointSuccessfullySentMock{})
+
+	nonIdempotent := newTestTransaction()
+	nonIdempotent.nonIdempotent = true
+	nonIdempotent.On("Process", w.Client).Return(fmt.Errorf("timeout")).Times(1)
+	nonIdempotent.On("GetTarget").Return("error_url").Times(1)
+
+	idempotent := newTestTransaction()
+	idempotent.On("Process", w.Client).Return(fmt.Errorf("timeout")).Times(1)
+	idempotent.On("GetTarget").Return("error_url").Times(1)
+
+	w.Start()
+	highPrio <- nonIdempotent
+	<-nonIdempotent.processed
+
+	// non-idempotent errors only block non-idempotent traffic
+	assert.True(t, w.blockedList.isBlockNonIdempotent("error_url"))
+	assert.False(t, w.blockedList.isBlock("error_url"))
+
+	highPrio <- idempotent
+	retryTransaction := <-requeue
+	w.Stop(false)
+
+	nonIdempotent.AssertExpectations(t)
+	idempotent.AssertExpectations(t)
+	assert.Equal(t, idempotent, retryTransaction)
+	assert.Len(t, requeue, 0)
+	assert.True(t, w.blockedList.isBlock("error_url"))
+}
+
+func TestWorkerNonIdempotentRetried(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	mockConfig := pkgconfig.Mock(t)
+	w := NewWorker(mockConfig, highPrio, lowPrio, requeue, newBlockedEndpoints(mockConfig), &PointSuccessfullySentMock{})
+
+	mock := newTestTransaction()
+	mock.nonIdempotent = true
+	mock.On("Process", w.Client).Return(fmt.Errorf("timeout")).Times(1)
+	mock.On("GetTarget").Return("error_url").Times(1)
+
+	w.Start()
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+	mock.AssertExpectations(t)
+	assert.Equal(t, mock, retryTransaction)
+	assert.True(t, w.blockedList.isBlockNonIdempotent("error_url"))
+}
+
 func TestWorkerResetConnections(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
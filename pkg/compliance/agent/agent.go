@@ -11,6 +11,8 @@ import (
 	"expvar"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/compliance"
@@ -87,6 +89,12 @@ func RunChecks(reporter event.Reporter, configDir string, options ...checks.Buil
 
 // RunChecksFromFile runs checks from the specified file with no scheduling
 func RunChecksFromFile(reporter event.Reporter, file string, options ...checks.BuilderOption) error {
+	return RunChecksFromFileContext(context.Background(), reporter, file, options...)
+}
+
+// RunChecksFromFileContext is RunChecksFromFile, not running the remaining
+// checks once ctx is done. The check running at that time is not interrupted.
+func RunChecksFromFileContext(ctx context.Context, reporter event.Reporter, file string, options ...checks.BuilderOption) error {
 	builder, err := checks.NewBuilder(
 		reporter,
 		options...,
@@ -101,7 +109,7 @@ func RunChecksFromFile(reporter event.Reporter, file string, options ...checks.B
 		builder: builder,
 	}
 
-	return agent.RunChecksFromFile(file)
+	return agent.RunChecksFromFileContext(ctx, file)
 }
 
 // Run starts the Compliance Agent
@@ -158,8 +166,23 @@ func (a *Agent) RunChecks() error {
 
 // RunChecksFromFile runs checks from the specified file with no scheduling
 func (a *Agent) RunChecksFromFile(file string) error {
+	return a.RunChecksFromFileContext(context.Background(), file)
+}
+
+// RunChecksFromFileContext is RunChecksFromFile, not running the remaining
+// checks once ctx is done, in which case it returns the error of ctx.
+func (a *Agent) RunChecksFromFileContext(ctx context.Context, file string) error {
 	log.Infof("Loading compliance rules from %s", file)
-	return a.builder.ChecksFromFile(file, runCheck)
+	err := a.builder.ChecksFromFile(file, func(rule *compliance.RuleCommon, check compliance.Check, err error) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		return runCheck(rule, check, err)
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
 }
 
 // Stop stops the Compliance Agent
@@ -177,9 +200,7 @@ func (a *Agent) Stop() {
 
 func (a *Agent) buildChecks(onCheck compliance.CheckVisitor) error {
 	log.Infof("Loading compliance rules from %s", a.configDir)
-	pattern := path.Join(a.configDir, "*.yaml")
-	files, err := filepath.Glob(pattern)
-
+	files, err := policyFiles(a.configDir)
 	if err != nil {
 		return err
 	}
@@ -194,6 +215,33 @@ func (a *Agent) buildChecks(onCheck compliance.CheckVisitor) error {
 	return nil
 }
 
+// policyFiles lists the suite files of configDir, gzipped or not. A gzipped
+// suite is skipped when its plain copy is present.
+func policyFiles(configDir string) ([]string, error) {
+	files, err := filepath.Glob(path.Join(configDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	gzipped, err := filepath.Glob(path.Join(configDir, "*.yaml.gz"))
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		plain[file] = struct{}{}
+	}
+	for _, file := range gzipped {
+		if _, ok := plain[strings.TrimSuffix(file, ".gz")]; ok {
+			log.Debugf("Skipping %s, superseded by its uncompressed copy", file)
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // GetStatus returns the agent status
 func (a *Agent) GetStatus() map[string]interface{} {
 	return map[string]interface{}{
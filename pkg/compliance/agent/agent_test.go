@@ -9,6 +9,9 @@
 package agent
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -27,6 +30,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type tempEnv struct {
@@ -356,3 +360,120 @@ func TestRunChecksFromFile(t *testing.T) {
 	)
 	assert.NoError(err)
 }
+
+func TestRunChecksFromFileContextCanceled(t *testing.T) {
+	e := enterTempEnv(t, true)
+	defer e.leave()
+
+	// no check runs once the context is canceled, so nothing is reported
+	reporter := &mocks.Reporter{}
+	defer reporter.AssertExpectations(t)
+
+	dockerClient := &mocks.DockerClient{}
+	dockerClient.On("Close").Return(nil).Once()
+	defer dockerClient.AssertExpectations(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := RunChecksFromFileContext(
+		ctx,
+		reporter,
+		filepath.Join(e.dir, "cis-kubernetes.yaml"),
+		checks.WithHostname("the-host"),
+		checks.WithHostRootMount(e.dir),
+		checks.WithDockerClient(dockerClient),
+		checks.WithKubernetesClient(&mocks.KubeClient{}, "kube_system_uuid"),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func gzipFile(t *testing.T, file string) {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(file+".gz", buf.Bytes(), 0o644))
+}
+
+func TestPolicyFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml", "b.yaml.gz", "c.yaml.gz", "d.rego.gz", "e.yml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	files, err := policyFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "b.yaml"),
+		filepath.Join(dir, "c.yaml.gz"),
+	}, files)
+}
+
+func TestRunChecksGzipped(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := aggregator.DefaultAgentDemultiplexerOptions()
+	opts.DontStartForwarders = true
+	forwarder := fxutil.Test[defaultforwarder.Component](t, defaultforwarder.MockModule, config.MockModule)
+	aggregator.InitAndStartAgentDemultiplexer(forwarder, opts, "foo")
+
+	e := enterTempEnv(t, false)
+	defer e.leave()
+
+	for _, name := range []string{"cis-docker.yaml", "cis-docker-1.rego"} {
+		file := filepath.Join(e.dir, name)
+		gzipFile(t, file)
+		require.NoError(t, os.Remove(file))
+	}
+	// only one of the copies of the kubernetes suite is loaded
+	gzipFile(t, filepath.Join(e.dir, "cis-kubernetes.yaml"))
+
+	files, err := policyFiles(e.dir)
+	assert.NoError(err)
+	assert.Equal([]string{
+		filepath.Join(e.dir, "cis-docker.yaml.gz"),
+		filepath.Join(e.dir, "cis-kubernetes.yaml"),
+	}, files)
+
+	reporter := &mocks.Reporter{}
+
+	reporter.On(
+		"Report",
+		mock.MatchedBy(
+			eventMatcher(
+				eventMatch{
+					ruleID:       "cis-docker-1",
+					frameworkID:  "cis-docker",
+					resourceID:   "the-host_daemon",
+					resourceType: "docker_daemon",
+					result:       "passed",
+					path:         "/files/daemon.json",
+					permissions:  0644,
+				},
+			),
+		),
+	).Once()
+
+	defer reporter.AssertExpectations(t)
+
+	dockerClient := &mocks.DockerClient{}
+	dockerClient.On("Close").Return(nil).Once()
+	defer dockerClient.AssertExpectations(t)
+
+	err = RunChecks(
+		reporter,
+		e.dir,
+		checks.WithMatchSuite(checks.IsFramework("cis-docker")),
+		checks.WithMatchRule(checks.IsRuleID("cis-docker-1")),
+		checks.WithHostname("the-host"),
+		checks.WithHostRootMount(e.dir),
+		checks.WithDockerClient(dockerClient),
+	)
+	assert.NoError(err)
+}
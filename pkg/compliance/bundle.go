@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compliance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadPolicyFile reads a suite or rego file of a policy bundle. The bundles
+// may be shipped gzipped: when path does not exist, path with a ".gz" suffix
+// is read instead, and gzipped content is detected and decompressed.
+func ReadPolicyFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		var gzErr error
+		if content, gzErr = os.ReadFile(path + ".gz"); gzErr == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(content, gzipMagic) {
+		return content, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress %s: %w", path, err)
+	}
+	defer r.Close()
+	content, err = io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress %s: %w", path, err)
+	}
+	return content, nil
+}
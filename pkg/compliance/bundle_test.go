@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compliance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReadPolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("package datadog\n")
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		return path
+	}
+
+	plain := write("plain.rego", content)
+	gzippedName := write("gzipped.rego", gzipped(t, content))
+	write("suffixed.rego.gz", gzipped(t, content))
+	corrupted := write("corrupted.rego", gzipped(t, content)[:12])
+
+	for _, path := range []string{plain, gzippedName, filepath.Join(dir, "suffixed.rego"), filepath.Join(dir, "suffixed.rego.gz")} {
+		actual, err := ReadPolicyFile(path)
+		if assert.NoError(t, err, path) {
+			assert.Equal(t, content, actual, path)
+		}
+	}
+
+	_, err := ReadPolicyFile(filepath.Join(dir, "missing.rego"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = ReadPolicyFile(corrupted)
+	assert.ErrorContains(t, err, "could not decompress")
+}
+
+func TestParseGzippedSuite(t *testing.T) {
+	content, err := os.ReadFile("./testdata/cis-docker.yaml")
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "cis-docker.yaml.gz")
+	require.NoError(t, os.WriteFile(file, gzipped(t, content), 0o644))
+
+	expected, err := ParseSuite("./testdata/cis-docker.yaml")
+	require.NoError(t, err)
+	expected.Meta.Source = file
+
+	actual, err := ParseSuite(file)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 	"time"
@@ -26,6 +27,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
 	"github.com/DataDog/datadog-agent/pkg/compliance/rego"
 	"github.com/DataDog/datadog-agent/pkg/compliance/resources/audit"
+	"github.com/DataDog/datadog-agent/pkg/compliance/resources/cloud"
+	"github.com/DataDog/datadog-agent/pkg/compliance/resources/directory"
 	"github.com/DataDog/datadog-agent/pkg/compliance/resources/file"
 	commandutils "github.com/DataDog/datadog-agent/pkg/compliance/utils/command"
 	dockerutils "github.com/DataDog/datadog-agent/pkg/compliance/utils/docker"
@@ -81,6 +84,86 @@ func WithMaxEvents(max int) BuilderOption {
 	}
 }
 
+// WithMaxInputBytes configures the maximum size of rego inputs, 0 meaning no limit
+func WithMaxInputBytes(max int) BuilderOption {
+	return func(b *builder) error {
+		b.maxInputBytes = max
+		return nil
+	}
+}
+
+// WithHermeticRegoEval rejects the rules calling rego built-ins that can
+// access the network, such as http.send
+func WithHermeticRegoEval() BuilderOption {
+	return func(b *builder) error {
+		b.regoEvalHermetic = true
+		return nil
+	}
+}
+
+// WithExceptions configures the resources on which the failures of rules are
+// reported as exceptions
+func WithExceptions(exceptions ...compliance.Exception) BuilderOption {
+	return func(b *builder) error {
+		if b.exceptions == nil {
+			b.exceptions = make(map[exceptionKey]string)
+		}
+		for _, e := range exceptions {
+			b.exceptions[exceptionKey{ruleID: e.RuleID, resourceID: e.ResourceID}] = e.Justification
+		}
+		return nil
+	}
+}
+
+// WithExceptionsFile configures the exceptions listed in a YAML file
+func WithExceptionsFile(path string) BuilderOption {
+	return func(b *builder) error {
+		exceptions, err := compliance.ParseExceptions(path)
+		if err != nil {
+			return fmt.Errorf("unable to load exceptions from %s: %w", path, err)
+		}
+		return WithExceptions(exceptions...)(b)
+	}
+}
+
+// WithClock configures the function returning the current time of rego
+// evaluations, as seen by built-ins like time.now_ns
+func WithClock(now func() time.Time) BuilderOption {
+	return func(b *builder) error {
+		b.regoEvalClock = now
+		return nil
+	}
+}
+
+// WithRegoPrintHook configures a hook receiving the output of the print calls
+// of the rego rules, in addition to the logs
+func WithRegoPrintHook(hook env.RegoPrintHook) BuilderOption {
+	return func(b *builder) error {
+		b.regoPrintHook = hook
+		return nil
+	}
+}
+
+// WithStableEventOrder sorts the events reported by each check run by rule ID
+// and then resource ID, instead of reporting failures first in the order of
+// the findings. It is meant for tests and diagnostics comparing events, at the
+// cost of sorting them.
+func WithStableEventOrder() BuilderOption {
+	return func(b *builder) error {
+		b.stableEventOrder = true
+		return nil
+	}
+}
+
+// WithResourceFilter configures a filter applied on resolved resources before
+// they are passed to rego evaluation
+func WithResourceFilter(filter env.ResourceFilter) BuilderOption {
+	return func(b *builder) error {
+		b.resourceFilter = filter
+		return nil
+	}
+}
+
 // WithStatsd configures the statsd client for compliance metrics
 func WithStatsd(client statsd.ClientInterface) BuilderOption {
 	return func(b *builder) error {
@@ -105,6 +188,16 @@ func WithConfigDir(configDir string) BuilderOption {
 	}
 }
 
+// WithFileSystem configures the file system read by the file resources,
+// instead of the files of the host. Its paths are the absolute paths of the
+// host, without their leading slash.
+func WithFileSystem(fsys fs.FS) BuilderOption {
+	return func(b *builder) error {
+		b.fileSystem = fsys
+		return nil
+	}
+}
+
 // WithHostRootMount defines host root filesystem mount location
 func WithHostRootMount(hostRootMount string) BuilderOption {
 	return func(b *builder) error {
@@ -157,6 +250,53 @@ func WithAuditClient(cli env.AuditClient) BuilderOption {
 	}
 }
 
+// WithCloud configures using cloud provider resources
+func WithCloud() BuilderOption {
+	return func(b *builder) error {
+		cli, err := cloud.NewAWSClient()
+		if err == nil {
+			b.cloudClient = cli
+		}
+		return err
+	}
+}
+
+// WithCloudClient configures using specific cloud client
+func WithCloudClient(cli env.CloudClient) BuilderOption {
+	return func(b *builder) error {
+		b.cloudClient = cli
+		return nil
+	}
+}
+
+// WithLDAP configures using entries from the LDAP directory at url
+func WithLDAP(url, bindDN, bindPassword string) BuilderOption {
+	return func(b *builder) error {
+		cli, err := directory.NewLDAPClient(url, bindDN, bindPassword)
+		if err == nil {
+			b.directoryClient = cli
+		}
+		return err
+	}
+}
+
+// WithDirectoryClient configures using specific directory client
+func WithDirectoryClient(cli env.DirectoryClient) BuilderOption {
+	return func(b *builder) error {
+		b.directoryClient = cli
+		return nil
+	}
+}
+
+// WithProcessClient configures using specific process client instead of
+// the processes running on the host
+func WithProcessClient(cli env.ProcessClient) BuilderOption {
+	return func(b *builder) error {
+		b.processClient = cli
+		return nil
+	}
+}
+
 type kubeClient struct {
 	dynamic.Interface
 	clusterID string
@@ -214,6 +354,16 @@ func WithMatchSuite(matcher SuiteMatcher) BuilderOption {
 	}
 }
 
+// WithRuntimeMatchSuite configures builder to use a suite matcher evaluated
+// before every check run. Unlike WithMatchSuite, checks are still scheduled for
+// non-matching suites so that they can be enabled again without a restart.
+func WithRuntimeMatchSuite(matcher SuiteMatcher) BuilderOption {
+	return func(b *builder) error {
+		b.runtimeSuiteMatcher = matcher
+		return nil
+	}
+}
+
 // RuleMatcher checks if a compliance rule is included
 type RuleMatcher func(*compliance.RuleCommon) bool
 
@@ -270,6 +420,19 @@ func IsFramework(framework string) SuiteMatcher {
 	}
 }
 
+// IsFrameworkEnabled matches a compliance suite whose framework is not part of
+// the list returned by disabledFrameworks. The list is queried on every match.
+func IsFrameworkEnabled(disabledFrameworks func() []string) SuiteMatcher {
+	return func(s *compliance.SuiteMeta) bool {
+		for _, framework := range disabledFrameworks() {
+			if s.Framework == framework {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 // IsRuleID matches a compliance rule by ID
 func IsRuleID(ruleID string) RuleMatcher {
 	return func(r *compliance.RuleCommon) bool {
@@ -304,6 +467,7 @@ func NewBuilder(reporter event.Reporter, options ...BuilderOption) (Builder, err
 type builder struct {
 	checkInterval   time.Duration
 	maxEventsPerRun int
+	maxInputBytes   int
 
 	reporter     event.Reporter
 	valueCache   *cache.Cache
@@ -313,18 +477,31 @@ type builder struct {
 	pathMapper   *fileutils.PathMapper
 	etcGroupPath string
 	configDir    string
+	fileSystem   fs.FS
 
-	suiteMatcher SuiteMatcher
-	ruleMatcher  RuleMatcher
+	suiteMatcher        SuiteMatcher
+	ruleMatcher         RuleMatcher
+	runtimeSuiteMatcher SuiteMatcher
 
-	dockerClient env.DockerClient
-	auditClient  env.AuditClient
-	kubeClient   *kubeClient
-	isLeaderFunc func() bool
+	dockerClient    env.DockerClient
+	auditClient     env.AuditClient
+	kubeClient      *kubeClient
+	cloudClient     env.CloudClient
+	directoryClient env.DirectoryClient
+	processClient   env.ProcessClient
+	isLeaderFunc    func() bool
 
 	regoInputOverride map[string]eval.RegoInputMap
 	regoInputDumpPath string
 	regoEvalSkip      bool
+	regoEvalHermetic  bool
+	regoEvalClock     func() time.Time
+	regoPrintHook     env.RegoPrintHook
+
+	stableEventOrder bool
+
+	exceptions     map[exceptionKey]string
+	resourceFilter env.ResourceFilter
 
 	status *status
 }
@@ -508,6 +685,10 @@ func (b *builder) checkFromRegoRule(meta *compliance.SuiteMeta, rule *compliance
 		if checkInterval, err = time.ParseDuration(rule.Period); err != nil {
 			return nil, fmt.Errorf("invalid period: %w", err)
 		}
+	} else if meta.Interval != "" {
+		if checkInterval, err = time.ParseDuration(meta.Interval); err != nil {
+			return nil, fmt.Errorf("invalid suite interval: %w", err)
+		}
 	}
 
 	// We capture err as configuration error but do not prevent check creation
@@ -524,7 +705,11 @@ func (b *builder) checkFromRegoRule(meta *compliance.SuiteMeta, rule *compliance
 		scope:           ruleScope,
 		checkable:       regoCheck,
 
-		eventNotify: notify,
+		eventNotify:  notify,
+		suiteMatcher: b.runtimeSuiteMatcher,
+		exceptions:   b.exceptions,
+
+		stableEventOrder: b.stableEventOrder,
 	}, nil
 }
 
@@ -559,6 +744,18 @@ func (b *builder) AuditClient() env.AuditClient {
 	return b.auditClient
 }
 
+func (b *builder) CloudClient() env.CloudClient {
+	return b.cloudClient
+}
+
+func (b *builder) DirectoryClient() env.DirectoryClient {
+	return b.directoryClient
+}
+
+func (b *builder) ProcessClient() env.ProcessClient {
+	return b.processClient
+}
+
 func (b *builder) KubeClient() env.KubeClient {
 	return b.kubeClient
 }
@@ -571,10 +768,29 @@ func (b *builder) DumpInputPath() string {
 	return b.regoInputDumpPath
 }
 
+func (b *builder) ResourceFilter() env.ResourceFilter {
+	return b.resourceFilter
+}
+
 func (b *builder) ShouldSkipRegoEval() bool {
 	return b.regoEvalSkip
 }
 
+func (b *builder) HermeticRegoEval() bool {
+	return b.regoEvalHermetic
+}
+
+func (b *builder) RegoEvalTime() time.Time {
+	if b.regoEvalClock == nil {
+		return time.Time{}
+	}
+	return b.regoEvalClock()
+}
+
+func (b *builder) RegoPrintHook() env.RegoPrintHook {
+	return b.regoPrintHook
+}
+
 func (b *builder) Hostname() string {
 	return b.hostname
 }
@@ -583,6 +799,10 @@ func (b *builder) ConfigDir() string {
 	return b.configDir
 }
 
+func (b *builder) FileSystem() fs.FS {
+	return b.fileSystem
+}
+
 func (b *builder) EtcGroupPath() string {
 	return b.etcGroupPath
 }
@@ -591,6 +811,10 @@ func (b *builder) MaxEventsPerRun() int {
 	return b.maxEventsPerRun
 }
 
+func (b *builder) MaxInputBytes() int {
+	return b.maxInputBytes
+}
+
 func (b *builder) NormalizeToHostRoot(path string) string {
 	if b.pathMapper == nil {
 		return path
@@ -20,8 +20,10 @@ import (
 
 	// Register compliance resources
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/audit"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/cloud"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/command"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/constants"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/directory"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/docker"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/file"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/group"
@@ -51,6 +53,23 @@ type complianceCheck struct {
 	checkable Checkable
 
 	eventNotify eventNotify
+
+	// suiteMatcher is evaluated before each run to know if the suite of
+	// the check is still enabled
+	suiteMatcher SuiteMatcher
+
+	// exceptions holds the justifications of the accepted failures per rule
+	// and resource
+	exceptions map[exceptionKey]string
+
+	// stableEventOrder sorts the events of a run by rule ID and resource ID
+	// before reporting them, see WithStableEventOrder
+	stableEventOrder bool
+}
+
+type exceptionKey struct {
+	ruleID     string
+	resourceID string
 }
 
 func (c *complianceCheck) Stop() {
@@ -126,6 +145,11 @@ func (c *complianceCheck) Run() error {
 		return nil
 	}
 
+	if c.suiteMatcher != nil && !c.suiteMatcher(c.suiteMeta) {
+		log.Debugf("%s: skipping check run - suite %s/%s is disabled", c.ruleID, c.suiteMeta.Name, c.suiteMeta.Version)
+		return nil
+	}
+
 	var err error
 
 	reports := c.checkable.Check(c)
@@ -133,6 +157,7 @@ func (c *complianceCheck) Run() error {
 
 	resourceQuadIDs := make(map[resourceQuadID]bool)
 
+	var events []*event.Event
 	for _, report := range reports {
 		ruleID := c.ruleID
 
@@ -167,6 +192,12 @@ func (c *complianceCheck) Run() error {
 		}
 		resourceQuadIDs[quadID] = true
 
+		if result == event.Failed {
+			if justification, ok := c.exceptions[exceptionKey{ruleID: ruleID, resourceID: resource.ID}]; ok {
+				data, result = exceptionEventData(data, justification), event.Exception
+			}
+		}
+
 		evaluator := report.Evaluator
 		if evaluator == "" {
 			evaluator = "legacy"
@@ -183,8 +214,20 @@ func (c *complianceCheck) Run() error {
 			Evaluator:        evaluator,
 			ExpireAt:         c.computeExpireAt(),
 		}
+		events = append(events, e)
+	}
 
-		log.Debugf("%s: reporting [%s] [%s] [%s]", ruleID, e.Result, e.ResourceID, e.ResourceType)
+	if c.stableEventOrder {
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].AgentRuleID != events[j].AgentRuleID {
+				return events[i].AgentRuleID < events[j].AgentRuleID
+			}
+			return events[i].ResourceID < events[j].ResourceID
+		})
+	}
+
+	for _, e := range events {
+		log.Debugf("%s: reporting [%s] [%s] [%s]", e.AgentRuleID, e.Result, e.ResourceID, e.ResourceType)
 
 		c.Reporter().Report(e)
 		if c.eventNotify != nil {
@@ -236,6 +279,16 @@ func reportToEventData(report *compliance.Report) (event.Data, string) {
 	return data, eventResult(passed, report.Error)
 }
 
+// exceptionEventData returns a copy of data with the justification of the exception
+func exceptionEventData(data event.Data, justification string) event.Data {
+	exceptionData := make(event.Data, len(data)+1)
+	for k, v := range data {
+		exceptionData[k] = v
+	}
+	exceptionData["exception_justification"] = justification
+	return exceptionData
+}
+
 func eventResult(passed bool, err error) string {
 	if err != nil {
 		return event.Error
@@ -17,6 +17,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
 	"github.com/DataDog/datadog-agent/pkg/compliance/mocks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/settings"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
 
@@ -175,3 +177,145 @@ func TestCheckRunNoLeader(t *testing.T) {
 	err := check.Run()
 	assert.Nil(err)
 }
+
+func TestCheckRunDisabledSuite(t *testing.T) {
+	const (
+		ruleID       = "rule-id"
+		frameworkID  = "cis"
+		resourceType = "resource-type"
+		resourceID   = "resource-id"
+	)
+
+	assert := assert.New(t)
+
+	env := &mocks.Env{}
+	defer env.AssertExpectations(t)
+
+	reporter := &mocks.Reporter{}
+	defer reporter.AssertExpectations(t)
+
+	checkable := &mockCheckable{}
+	defer checkable.AssertExpectations(t)
+
+	var disabledFrameworks []string
+	check := &complianceCheck{
+		Env: env,
+
+		ruleID:    ruleID,
+		checkable: checkable,
+		scope:     resourceType,
+
+		suiteMeta: &compliance.SuiteMeta{Framework: frameworkID},
+		suiteMatcher: IsFrameworkEnabled(func() []string {
+			return disabledFrameworks
+		}),
+	}
+
+	env.On("Hostname").Return(resourceID)
+	env.On("IsLeader").Return(true)
+	env.On("Reporter").Return(reporter)
+	env.On("StatsdClient").Return(nil)
+	reporter.On("Report", mock.Anything).Once()
+	checkable.On("Check", check).Return([]*compliance.Report{{Passed: true}}).Once()
+
+	// First run with the framework enabled
+	assert.NoError(check.Run())
+
+	// Disabling the framework between runs
+	disabledFrameworks = []string{frameworkID}
+	assert.NoError(check.Run())
+}
+
+func TestCheckRunStableEventOrder(t *testing.T) {
+	reports := []*compliance.Report{
+		{Passed: false, Resource: compliance.ReportResource{ID: "b", Type: "file"}},
+		{Passed: true, Resource: compliance.ReportResource{ID: "a", Type: "file"}},
+		{Passed: false, Resource: compliance.ReportResource{ID: "c", Type: "file"}},
+	}
+
+	run := func(stableEventOrder bool) []string {
+		env := &mocks.Env{}
+		reporter := &mocks.Reporter{}
+		checkable := &mockCheckable{}
+
+		check := &complianceCheck{
+			Env: env,
+
+			ruleID:          "rule-id",
+			checkable:       checkable,
+			resourceHandler: fallthroughReporter,
+			suiteMeta:       &compliance.SuiteMeta{Framework: "cis"},
+
+			stableEventOrder: stableEventOrder,
+		}
+
+		var ids []string
+		env.On("IsLeader").Return(true)
+		env.On("Reporter").Return(reporter)
+		env.On("StatsdClient").Return(nil)
+		reporter.On("Report", mock.Anything).Run(func(args mock.Arguments) {
+			ids = append(ids, args.Get(0).(*event.Event).ResourceID)
+		})
+		checkable.On("Check", check).Return(append([]*compliance.Report{}, reports...)).Once()
+
+		assert.NoError(t, check.Run())
+		return ids
+	}
+
+	// By default, the failures are reported first
+	assert.Equal(t, []string{"b", "c", "a"}, run(false))
+	assert.Equal(t, []string{"a", "b", "c"}, run(true))
+}
+
+func TestCheckRunDisabledFrameworksRuntimeSetting(t *testing.T) {
+	const (
+		ruleID       = "rule-id"
+		frameworkID  = "cis"
+		resourceType = "resource-type"
+		resourceID   = "resource-id"
+	)
+
+	assert := assert.New(t)
+	config.Mock(t)
+
+	env := &mocks.Env{}
+	defer env.AssertExpectations(t)
+
+	reporter := &mocks.Reporter{}
+	defer reporter.AssertExpectations(t)
+
+	checkable := &mockCheckable{}
+	defer checkable.AssertExpectations(t)
+
+	check := &complianceCheck{
+		Env: env,
+
+		ruleID:    ruleID,
+		checkable: checkable,
+		scope:     resourceType,
+
+		suiteMeta: &compliance.SuiteMeta{Framework: frameworkID},
+		suiteMatcher: IsFrameworkEnabled(func() []string {
+			return config.Datadog.GetStringSlice("compliance_config.disabled_frameworks")
+		}),
+	}
+
+	env.On("Hostname").Return(resourceID)
+	env.On("IsLeader").Return(true)
+	env.On("Reporter").Return(reporter)
+	env.On("StatsdClient").Return(nil)
+	reporter.On("Report", mock.Anything).Twice()
+	checkable.On("Check", check).Return([]*compliance.Report{{Passed: true}}).Twice()
+
+	setting := settings.ComplianceDisabledFrameworksRuntimeSetting{}
+	assert.NoError(check.Run())
+
+	// Disabling the framework as `config set` does skips the next runs
+	assert.NoError(setting.Set(frameworkID))
+	assert.NoError(check.Run())
+	assert.NoError(check.Run())
+
+	// Enabling it back resumes the runs
+	assert.NoError(setting.Set(""))
+	assert.NoError(check.Run())
+}
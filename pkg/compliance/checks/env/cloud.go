@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package env
+
+import "context"
+
+// CloudResource describes a resource fetched from a cloud provider API
+type CloudResource struct {
+	Type       string
+	ID         string
+	Attributes map[string]interface{}
+}
+
+// CloudClient defines the interface for listing cloud provider resources
+type CloudClient interface {
+	ListResources(ctx context.Context, resourceType string) ([]*CloudResource, error)
+}
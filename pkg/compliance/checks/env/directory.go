@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package env
+
+import "context"
+
+// DirectoryEntry describes an entry fetched from an LDAP directory
+type DirectoryEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// DirectoryClient defines the interface for searching directory entries
+type DirectoryClient interface {
+	Search(ctx context.Context, baseDN, filter string) ([]*DirectoryEntry, error)
+}
@@ -6,6 +6,9 @@
 package env
 
 import (
+	"io/fs"
+	"time"
+
 	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
 	"github.com/DataDog/datadog-go/v5/statsd"
@@ -25,6 +28,9 @@ type Clients interface {
 	DockerClient() DockerClient
 	AuditClient() AuditClient
 	KubeClient() KubeClient
+	CloudClient() CloudClient
+	DirectoryClient() DirectoryClient
+	ProcessClient() ProcessClient
 }
 
 // RegoConfiguration provides the rego specific configuration
@@ -32,16 +38,36 @@ type RegoConfiguration interface {
 	ProvidedInput(ruleID string) eval.RegoInputMap
 	DumpInputPath() string
 	ShouldSkipRegoEval() bool
+	HermeticRegoEval() bool
+	RegoEvalTime() time.Time
+	RegoPrintHook() RegoPrintHook
+	ResourceFilter() ResourceFilter
 }
 
+// RegoPrintHook receives the output of the print calls of the rego of a rule
+type RegoPrintHook func(ruleID, output string)
+
+// ResourceFilter reports whether a resolved resource should be part of the rego input
+type ResourceFilter func(resourceType, resourceID string) bool
+
 // Configuration provides an abstraction for various environment methods used by checks
 type Configuration interface {
 	Hostname() string
 	MaxEventsPerRun() int
+	MaxInputBytes() int
 	EtcGroupPath() string
 	NormalizeToHostRoot(path string) string
 	RelativeToHostRoot(path string) string
 	EvaluateFromCache(e eval.Evaluatable) (interface{}, error)
 	IsLeader() bool
 	ConfigDir() string
+	FileSystem() fs.FS
+}
+
+// FileOwner is the owner of a file of the FileSystem, when returned by the Sys
+// method of its fs.FileInfo. The owner of the other files is read from their
+// stat_t.
+type FileOwner struct {
+	User  string
+	Group string
 }
@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package env
+
+import processutils "github.com/DataDog/datadog-agent/pkg/compliance/utils/process"
+
+// ProcessClient defines the interface for listing the running processes
+type ProcessClient interface {
+	FindProcessesByName(name string) (processutils.Processes, error)
+}
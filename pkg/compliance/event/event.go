@@ -14,6 +14,8 @@ const (
 	Failed = "failed"
 	// Error is used to report result of a rule check that resulted in an error (unable to evaluate condition)
 	Error = "error"
+	// Exception is used to report unsuccessful result of a rule check on a resource for which the risk was accepted
+	Exception = "exception"
 )
 
 // Data defines a key value map for storing attributes of a reported rule event
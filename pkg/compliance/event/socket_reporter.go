@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package event
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const socketReporterWriteTimeout = 5 * time.Second
+
+// SocketReporter reports events as newline delimited JSON on a local Unix socket.
+// The connection is established lazily and re-established after a write failure,
+// so that the reader may start after the agent.
+type SocketReporter struct {
+	path string
+	conn net.Conn
+	m    sync.Mutex
+}
+
+// NewSocketReporter returns a reporter writing events to the Unix socket at path
+func NewSocketReporter(path string) *SocketReporter {
+	return &SocketReporter{path: path}
+}
+
+// Report writes the event as a single JSON line
+func (r *SocketReporter) Report(event *Event) {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("Failed to serialize rule event for rule %s", event.AgentRuleID)
+		return
+	}
+	r.ReportRaw(buf, "")
+}
+
+// ReportRaw writes the content followed by a newline
+func (r *SocketReporter) ReportRaw(content []byte, service string, tags ...string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.conn == nil {
+		conn, err := net.Dial("unix", r.path)
+		if err != nil {
+			log.Warnf("Failed to connect to compliance events socket %s: %v", r.path, err)
+			return
+		}
+		r.conn = conn
+	}
+
+	line := make([]byte, 0, len(content)+1)
+	line = append(line, content...)
+	line = append(line, '\n')
+
+	_ = r.conn.SetWriteDeadline(time.Now().Add(socketReporterWriteTimeout))
+	if _, err := r.conn.Write(line); err != nil {
+		log.Warnf("Failed to write to compliance events socket %s: %v", r.path, err)
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// Stop closes the connection to the socket
+func (r *SocketReporter) Stop() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+type multiReporter []Reporter
+
+// NewMultiReporter returns a reporter forwarding events to all the given reporters
+func NewMultiReporter(reporters ...Reporter) Reporter {
+	return multiReporter(reporters)
+}
+
+func (m multiReporter) Report(event *Event) {
+	for _, r := range m {
+		r.Report(event)
+	}
+}
+
+func (m multiReporter) ReportRaw(content []byte, service string, tags ...string) {
+	for _, r := range m {
+		r.ReportRaw(content, service, tags...)
+	}
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package event
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+
+	reporter := NewSocketReporter(path)
+	defer reporter.Stop()
+
+	// Events are dropped while nobody listens
+	reporter.Report(&Event{AgentRuleID: "dropped"})
+
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	lines := make(chan string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	reporter.Report(&Event{AgentRuleID: "rule1", Result: "passed"})
+	reporter.Report(&Event{AgentRuleID: "rule2", Result: "failed"})
+	reporter.Stop()
+
+	var received []*Event
+	for line := range lines {
+		var evt Event
+		require.NoError(t, json.Unmarshal([]byte(line), &evt))
+		received = append(received, &evt)
+	}
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "rule1", received[0].AgentRuleID)
+	assert.Equal(t, "passed", received[0].Result)
+	assert.Equal(t, "rule2", received[1].AgentRuleID)
+	assert.Equal(t, "failed", received[1].Result)
+}
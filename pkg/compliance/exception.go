@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compliance
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Exception accepts the risk of a rule failing on a resource. The failed
+// findings of the rule on the resource are reported as exceptions instead.
+type Exception struct {
+	RuleID        string `yaml:"rule_id"`
+	ResourceID    string `yaml:"resource_id"`
+	Justification string `yaml:"justification"`
+}
+
+// ParseExceptions loads a list of exceptions from a YAML file
+func ParseExceptions(path string) ([]Exception, error) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceptions []Exception
+	if err := yaml.Unmarshal(f, &exceptions); err != nil {
+		return nil, err
+	}
+
+	for i, e := range exceptions {
+		if e.RuleID == "" || e.ResourceID == "" {
+			return nil, fmt.Errorf("exception %d: rule_id and resource_id are required", i)
+		}
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compliance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExceptions(t *testing.T) {
+	exceptions, err := ParseExceptions("./testdata/exceptions.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []Exception{
+		{
+			RuleID:        "cis-docker-1",
+			ResourceID:    "3ec1b4d2a5f6",
+			Justification: "legacy image kept until the migration is over",
+		},
+		{
+			RuleID:     "cis-docker-2",
+			ResourceID: "/etc/docker/daemon.json",
+		},
+	}, exceptions)
+
+	_, err = ParseExceptions("./testdata/exceptions-invalid.yaml")
+	assert.EqualError(t, err, "exception 0: rule_id and resource_id are required")
+}
@@ -28,6 +28,38 @@ func (_m *Clients) AuditClient() env.AuditClient {
 	return r0
 }
 
+// CloudClient provides a mock function with given fields:
+func (_m *Clients) CloudClient() env.CloudClient {
+	ret := _m.Called()
+
+	var r0 env.CloudClient
+	if rf, ok := ret.Get(0).(func() env.CloudClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.CloudClient)
+		}
+	}
+
+	return r0
+}
+
+// DirectoryClient provides a mock function with given fields:
+func (_m *Clients) DirectoryClient() env.DirectoryClient {
+	ret := _m.Called()
+
+	var r0 env.DirectoryClient
+	if rf, ok := ret.Get(0).(func() env.DirectoryClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.DirectoryClient)
+		}
+	}
+
+	return r0
+}
+
 // DockerClient provides a mock function with given fields:
 func (_m *Clients) DockerClient() env.DockerClient {
 	ret := _m.Called()
@@ -60,6 +92,22 @@ func (_m *Clients) KubeClient() env.KubeClient {
 	return r0
 }
 
+// ProcessClient provides a mock function with given fields:
+func (_m *Clients) ProcessClient() env.ProcessClient {
+	ret := _m.Called()
+
+	var r0 env.ProcessClient
+	if rf, ok := ret.Get(0).(func() env.ProcessClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.ProcessClient)
+		}
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewClients interface {
 	mock.TestingT
 	Cleanup(func())
@@ -4,6 +4,8 @@ package mocks
 
 import (
 	eval "github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	fs "io/fs"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -66,6 +68,22 @@ func (_m *Configuration) EvaluateFromCache(e eval.Evaluatable) (interface{}, err
 	return r0, r1
 }
 
+// FileSystem provides a mock function with given fields:
+func (_m *Configuration) FileSystem() fs.FS {
+	ret := _m.Called()
+
+	var r0 fs.FS
+	if rf, ok := ret.Get(0).(func() fs.FS); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(fs.FS)
+		}
+	}
+
+	return r0
+}
+
 // Hostname provides a mock function with given fields:
 func (_m *Configuration) Hostname() string {
 	ret := _m.Called()
@@ -108,6 +126,20 @@ func (_m *Configuration) MaxEventsPerRun() int {
 	return r0
 }
 
+// MaxInputBytes provides a mock function with given fields:
+func (_m *Configuration) MaxInputBytes() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // NormalizeToHostRoot provides a mock function with given fields: path
 func (_m *Configuration) NormalizeToHostRoot(path string) string {
 	ret := _m.Called(path)
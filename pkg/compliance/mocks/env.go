@@ -8,9 +8,13 @@ import (
 
 	event "github.com/DataDog/datadog-agent/pkg/compliance/event"
 
+	fs "io/fs"
+
 	mock "github.com/stretchr/testify/mock"
 
 	statsd "github.com/DataDog/datadog-go/v5/statsd"
+
+	time "time"
 )
 
 // Env is an autogenerated mock type for the Env type
@@ -34,6 +38,22 @@ func (_m *Env) AuditClient() env.AuditClient {
 	return r0
 }
 
+// CloudClient provides a mock function with given fields:
+func (_m *Env) CloudClient() env.CloudClient {
+	ret := _m.Called()
+
+	var r0 env.CloudClient
+	if rf, ok := ret.Get(0).(func() env.CloudClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.CloudClient)
+		}
+	}
+
+	return r0
+}
+
 // ConfigDir provides a mock function with given fields:
 func (_m *Env) ConfigDir() string {
 	ret := _m.Called()
@@ -48,6 +68,22 @@ func (_m *Env) ConfigDir() string {
 	return r0
 }
 
+// DirectoryClient provides a mock function with given fields:
+func (_m *Env) DirectoryClient() env.DirectoryClient {
+	ret := _m.Called()
+
+	var r0 env.DirectoryClient
+	if rf, ok := ret.Get(0).(func() env.DirectoryClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.DirectoryClient)
+		}
+	}
+
+	return r0
+}
+
 // DockerClient provides a mock function with given fields:
 func (_m *Env) DockerClient() env.DockerClient {
 	ret := _m.Called()
@@ -118,6 +154,36 @@ func (_m *Env) EvaluateFromCache(e eval.Evaluatable) (interface{}, error) {
 	return r0, r1
 }
 
+// FileSystem provides a mock function with given fields:
+func (_m *Env) FileSystem() fs.FS {
+	ret := _m.Called()
+
+	var r0 fs.FS
+	if rf, ok := ret.Get(0).(func() fs.FS); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(fs.FS)
+		}
+	}
+
+	return r0
+}
+
+// HermeticRegoEval provides a mock function with given fields:
+func (_m *Env) HermeticRegoEval() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Hostname provides a mock function with given fields:
 func (_m *Env) Hostname() string {
 	ret := _m.Called()
@@ -176,6 +242,20 @@ func (_m *Env) MaxEventsPerRun() int {
 	return r0
 }
 
+// MaxInputBytes provides a mock function with given fields:
+func (_m *Env) MaxInputBytes() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // NormalizeToHostRoot provides a mock function with given fields: path
 func (_m *Env) NormalizeToHostRoot(path string) string {
 	ret := _m.Called(path)
@@ -190,6 +270,22 @@ func (_m *Env) NormalizeToHostRoot(path string) string {
 	return r0
 }
 
+// ProcessClient provides a mock function with given fields:
+func (_m *Env) ProcessClient() env.ProcessClient {
+	ret := _m.Called()
+
+	var r0 env.ProcessClient
+	if rf, ok := ret.Get(0).(func() env.ProcessClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.ProcessClient)
+		}
+	}
+
+	return r0
+}
+
 // ProvidedInput provides a mock function with given fields: ruleID
 func (_m *Env) ProvidedInput(ruleID string) eval.RegoInputMap {
 	ret := _m.Called(ruleID)
@@ -206,6 +302,36 @@ func (_m *Env) ProvidedInput(ruleID string) eval.RegoInputMap {
 	return r0
 }
 
+// RegoEvalTime provides a mock function with given fields:
+func (_m *Env) RegoEvalTime() time.Time {
+	ret := _m.Called()
+
+	var r0 time.Time
+	if rf, ok := ret.Get(0).(func() time.Time); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	return r0
+}
+
+// RegoPrintHook provides a mock function with given fields:
+func (_m *Env) RegoPrintHook() env.RegoPrintHook {
+	ret := _m.Called()
+
+	var r0 env.RegoPrintHook
+	if rf, ok := ret.Get(0).(func() env.RegoPrintHook); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.RegoPrintHook)
+		}
+	}
+
+	return r0
+}
+
 // RelativeToHostRoot provides a mock function with given fields: path
 func (_m *Env) RelativeToHostRoot(path string) string {
 	ret := _m.Called(path)
@@ -236,6 +362,22 @@ func (_m *Env) Reporter() event.Reporter {
 	return r0
 }
 
+// ResourceFilter provides a mock function with given fields:
+func (_m *Env) ResourceFilter() env.ResourceFilter {
+	ret := _m.Called()
+
+	var r0 env.ResourceFilter
+	if rf, ok := ret.Get(0).(func() env.ResourceFilter); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.ResourceFilter)
+		}
+	}
+
+	return r0
+}
+
 // ShouldSkipRegoEval provides a mock function with given fields:
 func (_m *Env) ShouldSkipRegoEval() bool {
 	ret := _m.Called()
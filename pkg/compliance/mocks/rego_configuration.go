@@ -3,8 +3,11 @@
 package mocks
 
 import (
+	env "github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
 	eval "github.com/DataDog/datadog-agent/pkg/compliance/eval"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // RegoConfiguration is an autogenerated mock type for the RegoConfiguration type
@@ -26,6 +29,20 @@ func (_m *RegoConfiguration) DumpInputPath() string {
 	return r0
 }
 
+// HermeticRegoEval provides a mock function with given fields:
+func (_m *RegoConfiguration) HermeticRegoEval() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // ProvidedInput provides a mock function with given fields: ruleID
 func (_m *RegoConfiguration) ProvidedInput(ruleID string) eval.RegoInputMap {
 	ret := _m.Called(ruleID)
@@ -42,6 +59,52 @@ func (_m *RegoConfiguration) ProvidedInput(ruleID string) eval.RegoInputMap {
 	return r0
 }
 
+// RegoEvalTime provides a mock function with given fields:
+func (_m *RegoConfiguration) RegoEvalTime() time.Time {
+	ret := _m.Called()
+
+	var r0 time.Time
+	if rf, ok := ret.Get(0).(func() time.Time); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	return r0
+}
+
+// RegoPrintHook provides a mock function with given fields:
+func (_m *RegoConfiguration) RegoPrintHook() env.RegoPrintHook {
+	ret := _m.Called()
+
+	var r0 env.RegoPrintHook
+	if rf, ok := ret.Get(0).(func() env.RegoPrintHook); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.RegoPrintHook)
+		}
+	}
+
+	return r0
+}
+
+// ResourceFilter provides a mock function with given fields:
+func (_m *RegoConfiguration) ResourceFilter() env.ResourceFilter {
+	ret := _m.Called()
+
+	var r0 env.ResourceFilter
+	if rf, ok := ret.Get(0).(func() env.ResourceFilter); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(env.ResourceFilter)
+		}
+	}
+
+	return r0
+}
+
 // ShouldSkipRegoEval provides a mock function with given fields:
 func (_m *RegoConfiguration) ShouldSkipRegoEval() bool {
 	ret := _m.Called()
@@ -40,10 +40,19 @@ var (
 	// ErrResourceKindNotSupported is returned in case resource kind is not supported by evaluator
 	ErrResourceKindNotSupported = errors.New("resource kind not supported")
 
+	// ErrInputTooLarge is returned when the rego input exceeds the configured maximum size
+	ErrInputTooLarge = errors.New("rego input too large")
+
 	// ErrResourceFailedToResolve is returned when a resource failed to resolve to any instances for evaluation
 	ErrResourceFailedToResolve = errors.New("failed to resolve resource")
 )
 
+// networkBuiltins are the rego built-ins rejected by hermetic evaluations
+var networkBuiltins = map[string]struct{}{
+	"http.send":          {},
+	"net.lookup_ip_addr": {},
+}
+
 type regoInput struct {
 	compliance.RegoInput
 	regoModuleArgs []func(*rego.Rego)
@@ -77,7 +86,7 @@ func importModule(importPath, parentDir string, required bool) (string, error) {
 		importPath = filepath.Join(parentDir, importPath)
 	}
 
-	mod, err := os.ReadFile(importPath)
+	mod, err := compliance.ReadPolicyFile(importPath)
 	if err != nil {
 		if required {
 			return "", err
@@ -307,7 +316,16 @@ func (r *regoCheck) buildNormalInput(env env.Env) (eval.RegoInputMap, error) {
 			ctx, cancel := context.WithTimeout(context.Background(), regoEvalTimeout)
 			defer cancel()
 
-			regoMod := rego.New(append(regoInput.regoModuleArgs, rego.Input(input))...)
+			args := make([]func(*rego.Rego), len(regoInput.regoModuleArgs), len(regoInput.regoModuleArgs)+3)
+			copy(args, regoInput.regoModuleArgs)
+			args = append(args, rego.Input(input))
+			if env.HermeticRegoEval() {
+				args = append(args, rego.UnsafeBuiltins(networkBuiltins))
+			}
+			if now := env.RegoEvalTime(); !now.IsZero() {
+				args = append(args, rego.Time(now))
+			}
+			regoMod := rego.New(args...)
 			results, err := regoMod.Eval(ctx)
 			if err != nil {
 				return err
@@ -360,6 +378,17 @@ func (r *regoCheck) buildNormalInput(env env.Env) (eval.RegoInputMap, error) {
 				return fmt.Errorf("internal error, wrong input type `%s`", inputType)
 			}
 		case resources.ResolvedInstance:
+			if !keepResource(env, res) {
+				switch inputType {
+				case "array":
+					if err := addArrayInput(tagName, nil); err != nil {
+						return err
+					}
+				case "object":
+					addObjectInput(tagName, &struct{}{})
+				}
+				break
+			}
 			switch inputType {
 			case "array":
 				if err := addArrayInput(tagName, res.RegoInput()); err != nil {
@@ -383,12 +412,18 @@ func (r *regoCheck) buildNormalInput(env env.Env) (eval.RegoInputMap, error) {
 			var instance eval.Instance
 			var instanceCount int
 			it := res
-			for ; !it.Done(); instanceCount++ {
-				instance, err = it.Next()
+			for !it.Done() {
+				next, err := it.Next()
 				if err != nil {
 					return err
 				}
 
+				if resolved, ok := next.(resources.ResolvedInstance); ok && !keepResource(env, resolved) {
+					continue
+				}
+				instance = next
+				instanceCount++
+
 				if inputType == "array" {
 					if err := addArrayInput(tagName, instance.RegoInput()); err != nil {
 						return err
@@ -416,6 +451,12 @@ func (r *regoCheck) buildNormalInput(env env.Env) (eval.RegoInputMap, error) {
 	return input, nil
 }
 
+// keepResource applies the resource filter configured in the environment, if any
+func keepResource(env env.Env, resource resources.ResolvedInstance) bool {
+	filter := env.ResourceFilter()
+	return filter == nil || filter(resource.Type(), resource.ID())
+}
+
 func extractTagName(input *compliance.RegoInput) string {
 	tagName := input.TagName
 	if tagName == "" {
@@ -571,6 +612,10 @@ func (r *regoCheck) Check(env env.Env) compliance.Reports {
 		return buildRegoErrorReports(err)
 	}
 
+	if maxInputBytes := env.MaxInputBytes(); maxInputBytes > 0 && parsedInputSize > maxInputBytes {
+		return buildRegoErrorReports(fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", ErrInputTooLarge, parsedInputSize, maxInputBytes))
+	}
+
 	if statsClient := env.StatsdClient(); statsClient != nil {
 		tags := []string{"rule_id:" + r.ruleID, "agent_version:" + version.AgentVersion}
 		if err := statsClient.Gauge(metrics.MetricInputsSize, float64(parsedInputSize), tags, 1.0); err != nil {
@@ -581,9 +626,18 @@ func (r *regoCheck) Check(env env.Env) compliance.Reports {
 	ctx, cancel := context.WithTimeout(context.Background(), regoEvalTimeout)
 	defer cancel()
 
-	args := make([]func(*rego.Rego), len(r.regoModuleArgs), len(r.regoModuleArgs)+2)
+	args := make([]func(*rego.Rego), len(r.regoModuleArgs), len(r.regoModuleArgs)+4)
 	copy(args, r.regoModuleArgs)
 	args = append(args, rego.ParsedInput(parsedInput))
+	if env.HermeticRegoEval() {
+		args = append(args, rego.UnsafeBuiltins(networkBuiltins))
+	}
+	if now := env.RegoEvalTime(); !now.IsZero() {
+		args = append(args, rego.Time(now))
+	}
+	if hook := env.RegoPrintHook(); hook != nil {
+		args = append(args, rego.PrintHook(&regoPrintHook{ruleID: r.ruleID, hook: hook}))
+	}
 
 	regoMod := rego.New(args...)
 	results, err := regoMod.Eval(ctx)
@@ -733,10 +787,18 @@ func buildRegoErrorReports(err error) []*compliance.Report {
 	return []*compliance.Report{report}
 }
 
-type regoPrintHook struct{}
+// regoPrintHook logs the output of the print calls, and passes it to hook
+// when set
+type regoPrintHook struct {
+	ruleID string
+	hook   env.RegoPrintHook
+}
 
 func (h *regoPrintHook) Print(_ print.Context, value string) error {
 	log.Infof("Rego print output: %s", value)
+	if h.hook != nil {
+		h.hook(h.ruleID, value)
+	}
 	return nil
 }
 
@@ -76,6 +76,12 @@ func (f *regoInputFixture) run(t *testing.T) {
 
 	env := &mocks.Env{}
 	env.On("MaxEventsPerRun").Return(30).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("ProvidedInput", mock.Anything).Return(nil).Once()
 	env.On("Hostname").Return("hostname_test").Once()
 	env.On("DumpInputPath").Return(tf.Name()).Once()
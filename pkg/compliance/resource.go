@@ -36,6 +36,10 @@ const (
 	KindCustom = ResourceKind("custom")
 	// KindXccdf is used for a XCCDF check
 	KindXccdf = ResourceKind("xccdf")
+	// KindCloud is used for a CloudResource
+	KindCloud = ResourceKind("cloud")
+	// KindDirectory is used for a DirectoryResource
+	KindDirectory = ResourceKind("directory")
 )
 
 // ResourceCommon describes the base fields of resource types
@@ -50,6 +54,8 @@ type ResourceCommon struct {
 	Constants     *ConstantsResource  `yaml:"constants,omitempty"`
 	Custom        *Custom             `yaml:"custom,omitempty"`
 	Xccdf         *Xccdf              `yaml:"xccdf,omitempty"`
+	Cloud         *CloudResource      `yaml:"cloud,omitempty"`
+	Directory     *DirectoryResource  `yaml:"directory,omitempty"`
 }
 
 // RegoInput describes supported resource types observed by a Rego Rule
@@ -95,6 +101,10 @@ func (r *ResourceCommon) Kind() ResourceKind {
 		return KindCustom
 	case r.Xccdf != nil:
 		return KindXccdf
+	case r.Cloud != nil:
+		return KindCloud
+	case r.Directory != nil:
+		return KindDirectory
 	default:
 		return KindInvalid
 	}
@@ -303,3 +313,27 @@ type Xccdf struct {
 	Rule    string   `yaml:"rule"`
 	Rules   []string `yaml:"rules,omitempty"`
 }
+
+// Fields available for CloudResource
+const (
+	CloudResourceFieldType       = "cloud.type"
+	CloudResourceFieldID         = "cloud.id"
+	CloudResourceFieldAttributes = "cloud.attributes"
+)
+
+// CloudResource describes resources fetched from a cloud provider API
+type CloudResource struct {
+	Type string `yaml:"type"`
+}
+
+// Fields available for DirectoryResource
+const (
+	DirectoryFieldDN         = "directory.dn"
+	DirectoryFieldAttributes = "directory.attributes"
+)
+
+// DirectoryResource describes entries searched in an LDAP directory
+type DirectoryResource struct {
+	BaseDN string `yaml:"baseDN"`
+	Filter string `yaml:"filter,omitempty"`
+}
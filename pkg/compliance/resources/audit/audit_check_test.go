@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
@@ -145,6 +146,12 @@ func TestAuditCheck(t *testing.T) {
 			env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 			env.On("DumpInputPath").Return("").Maybe()
 			env.On("ShouldSkipRegoEval").Return(false).Maybe()
+			env.On("MaxInputBytes").Return(0).Maybe()
+			env.On("ResourceFilter").Return(nil).Maybe()
+			env.On("HermeticRegoEval").Return(false).Maybe()
+			env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+			env.On("RegoPrintHook").Return(nil).Maybe()
+			env.On("ProcessClient").Return(nil).Maybe()
 			env.On("Hostname").Return("test-host").Maybe()
 			env.On("NormalizeToHostRoot", mock.AnythingOfType("string")).Return(test.hostPath)
 			env.On("StatsdClient").Return(nil).Maybe()
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build ec2
+// +build ec2
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+// AWSS3BucketType is the cloud resource type of AWS S3 buckets
+const AWSS3BucketType = "aws_s3_bucket"
+
+type awsClient struct {
+	s3 *s3.S3
+}
+
+// NewAWSClient returns a cloud client listing resources from the AWS API,
+// using the default credentials chain
+func NewAWSClient() (env.CloudClient, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create AWS session: %w", err)
+	}
+	return &awsClient{
+		s3: s3.New(sess),
+	}, nil
+}
+
+func (c *awsClient) ListResources(ctx context.Context, resourceType string) ([]*env.CloudResource, error) {
+	switch resourceType {
+	case AWSS3BucketType:
+		return c.listS3Buckets(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported AWS resource type %q", resourceType)
+	}
+}
+
+func (c *awsClient) listS3Buckets(ctx context.Context) ([]*env.CloudResource, error) {
+	output, err := c.s3.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []*env.CloudResource
+	for _, bucket := range output.Buckets {
+		name := aws.StringValue(bucket.Name)
+		attributes := map[string]interface{}{
+			"name": name,
+		}
+
+		publicAccessBlock, err := c.s3.GetPublicAccessBlockWithContext(ctx, &s3.GetPublicAccessBlockInput{
+			Bucket: bucket.Name,
+		})
+		var awsErr awserr.Error
+		switch {
+		case err == nil:
+			conf := publicAccessBlock.PublicAccessBlockConfiguration
+			attributes["public_access_block"] = map[string]interface{}{
+				"block_public_acls":       aws.BoolValue(conf.BlockPublicAcls),
+				"block_public_policy":     aws.BoolValue(conf.BlockPublicPolicy),
+				"ignore_public_acls":      aws.BoolValue(conf.IgnorePublicAcls),
+				"restrict_public_buckets": aws.BoolValue(conf.RestrictPublicBuckets),
+			}
+		case errors.As(err, &awsErr) && awsErr.Code() == "NoSuchPublicAccessBlockConfiguration":
+			attributes["public_access_block"] = nil
+		default:
+			return nil, fmt.Errorf("unable to get public access block of bucket %s: %w", name, err)
+		}
+
+		buckets = append(buckets, &env.CloudResource{
+			Type:       AWSS3BucketType,
+			ID:         "arn:aws:s3:::" + name,
+			Attributes: attributes,
+		})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build ec2
+// +build ec2
+
+package cloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSClientUnsupportedResourceType(t *testing.T) {
+	client, err := NewAWSClient()
+	require.NoError(t, err)
+
+	_, err = client.ListResources(context.Background(), "aws_ec2_instance")
+	assert.EqualError(t, err, `unsupported AWS resource type "aws_ec2_instance"`)
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/compliance/resources"
+)
+
+var reportedFields = []string{
+	compliance.CloudResourceFieldType,
+	compliance.CloudResourceFieldID,
+}
+
+func resolve(ctx context.Context, e env.Env, ruleID string, res compliance.ResourceCommon, rego bool) (resources.Resolved, error) {
+	if res.Cloud == nil {
+		return nil, fmt.Errorf("%s: expecting cloud resource in cloud check", ruleID)
+	}
+
+	client := e.CloudClient()
+	if client == nil {
+		return nil, fmt.Errorf("cloud client not configured")
+	}
+
+	cloudResources, err := client.ListResources(ctx, res.Cloud.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to list cloud resources of type %s: %w", ruleID, res.Cloud.Type, err)
+	}
+
+	var instances []resources.ResolvedInstance
+	for _, r := range cloudResources {
+		instance := eval.NewInstance(
+			eval.VarMap{
+				compliance.CloudResourceFieldType:       r.Type,
+				compliance.CloudResourceFieldID:         r.ID,
+				compliance.CloudResourceFieldAttributes: r.Attributes,
+			},
+			nil,
+			eval.RegoInputMap{
+				"type":       r.Type,
+				"id":         r.ID,
+				"attributes": r.Attributes,
+			},
+		)
+		instances = append(instances, resources.NewResolvedInstance(instance, r.ID, r.Type))
+	}
+
+	if len(instances) == 0 && rego {
+		return nil, nil
+	}
+
+	return resources.NewResolvedInstances(instances), nil
+}
+
+func init() {
+	resources.RegisterHandler("cloud", resolve, reportedFields)
+}
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !ec2
+// +build !ec2
+
+package cloud
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+// NewAWSClient returns a new AWS cloud client
+func NewAWSClient() (env.CloudClient, error) {
+	return nil, errors.New("AWS cloud client requires ec2 build flag")
+}
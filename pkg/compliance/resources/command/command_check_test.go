@@ -14,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
@@ -106,6 +107,12 @@ func (f *commandFixture) run(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package directory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/compliance/resources"
+)
+
+// DefaultFilter is the search filter used when the resource does not specify one
+const DefaultFilter = "(objectClass=*)"
+
+var reportedFields = []string{
+	compliance.DirectoryFieldDN,
+}
+
+func resolve(ctx context.Context, e env.Env, ruleID string, res compliance.ResourceCommon, rego bool) (resources.Resolved, error) {
+	if res.Directory == nil {
+		return nil, fmt.Errorf("%s: expecting directory resource in directory check", ruleID)
+	}
+
+	client := e.DirectoryClient()
+	if client == nil {
+		return nil, fmt.Errorf("directory client not configured")
+	}
+
+	filter := res.Directory.Filter
+	if filter == "" {
+		filter = DefaultFilter
+	}
+
+	entries, err := client.Search(ctx, res.Directory.BaseDN, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to search directory entries under %s: %w", ruleID, res.Directory.BaseDN, err)
+	}
+
+	var instances []resources.ResolvedInstance
+	for _, entry := range entries {
+		instance := eval.NewInstance(
+			eval.VarMap{
+				compliance.DirectoryFieldDN:         entry.DN,
+				compliance.DirectoryFieldAttributes: entry.Attributes,
+			},
+			nil,
+			eval.RegoInputMap{
+				"dn":         entry.DN,
+				"attributes": entry.Attributes,
+			},
+		)
+		instances = append(instances, resources.NewResolvedInstance(instance, entry.DN, "directory_entry"))
+	}
+
+	if len(instances) == 0 && rego {
+		return nil, nil
+	}
+
+	return resources.NewResolvedInstances(instances), nil
+}
+
+func init() {
+	resources.RegisterHandler("directory", resolve, reportedFields)
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build ldap
+// +build ldap
+
+package directory
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+const ldapTimeout = 30 * time.Second
+
+type ldapClient struct {
+	url          string
+	bindDN       string
+	bindPassword string
+}
+
+// NewLDAPClient returns a directory client searching the LDAP server at url,
+// binding as bindDN when it is set and anonymously otherwise
+func NewLDAPClient(url, bindDN, bindPassword string) (env.DirectoryClient, error) {
+	if url == "" {
+		return nil, fmt.Errorf("LDAP server URL not configured")
+	}
+	return &ldapClient{
+		url:          url,
+		bindDN:       bindDN,
+		bindPassword: bindPassword,
+	}, nil
+}
+
+func (c *ldapClient) Search(ctx context.Context, baseDN, filter string) ([]*env.DirectoryEntry, error) {
+	timeout := ldapTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err := ldap.DialURL(c.url, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", c.url, err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if c.bindDN != "" {
+		if err := conn.Bind(c.bindDN, c.bindPassword); err != nil {
+			return nil, fmt.Errorf("unable to bind as %s: %w", c.bindDN, err)
+		}
+	}
+
+	req := ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(timeout.Seconds()), false, filter, nil, nil)
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*env.DirectoryEntry, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		attributes := make(map[string][]string, len(e.Attributes))
+		for _, attr := range e.Attributes {
+			attributes[attr.Name] = attr.Values
+		}
+		entries = append(entries, &env.DirectoryEntry{
+			DN:         e.DN,
+			Attributes: attributes,
+		})
+	}
+	return entries, nil
+}
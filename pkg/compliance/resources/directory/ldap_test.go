@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build ldap
+// +build ldap
+
+package directory
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLDAPClientRequiresURL(t *testing.T) {
+	_, err := NewLDAPClient("", "", "")
+	assert.Error(t, err)
+}
+
+func TestLDAPClientSearchUnreachable(t *testing.T) {
+	// reserve a port and release it so that nothing listens on it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	client, err := NewLDAPClient("ldap://"+addr, "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.Search(ctx, "dc=example,dc=com", "(objectClass=*)")
+	assert.ErrorContains(t, err, "unable to connect to ldap://"+addr)
+}
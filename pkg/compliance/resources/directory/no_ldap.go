@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !ldap
+// +build !ldap
+
+package directory
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+// NewLDAPClient returns a new LDAP directory client
+func NewLDAPClient(url, bindDN, bindPassword string) (env.DirectoryClient, error) {
+	return nil, errors.New("LDAP directory client requires ldap build flag")
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 
@@ -102,6 +103,12 @@ func TestDockerImageCheck(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 
@@ -180,6 +187,12 @@ func TestDockerNetworkCheck(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 	module := `package datadog
@@ -318,6 +331,12 @@ func TestDockerContainerCheck(t *testing.T) {
 			env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 			env.On("DumpInputPath").Return("").Maybe()
 			env.On("ShouldSkipRegoEval").Return(false).Maybe()
+			env.On("MaxInputBytes").Return(0).Maybe()
+			env.On("ResourceFilter").Return(nil).Maybe()
+			env.On("HermeticRegoEval").Return(false).Maybe()
+			env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+			env.On("RegoPrintHook").Return(nil).Maybe()
+			env.On("ProcessClient").Return(nil).Maybe()
 			env.On("Hostname").Return("test-host").Maybe()
 			env.On("StatsdClient").Return(nil).Maybe()
 
@@ -394,6 +413,12 @@ func TestDockerInfoCheck(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 
@@ -467,6 +492,12 @@ func TestDockerVersionCheck(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 
@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -60,7 +61,8 @@ func resolve(_ context.Context, e env.Env, ruleID string, res compliance.Resourc
 		return nil, err
 	}
 
-	paths, err := filepath.Glob(e.NormalizeToHostRoot(path))
+	files := hostFiles{env: e, fsys: e.FileSystem()}
+	paths, err := files.glob(path)
 	if err != nil {
 		return nil, err
 	}
@@ -69,8 +71,8 @@ func resolve(_ context.Context, e env.Env, ruleID string, res compliance.Resourc
 
 	for _, path := range paths {
 		// Re-computing relative after glob filtering
-		relPath := e.RelativeToHostRoot(path)
-		fi, err := os.Stat(path)
+		relPath := files.relative(path)
+		fi, err := files.stat(path)
 		if err != nil {
 			// This is not a failure unless we don't have any paths to act on
 			log.Debugf("%s: file check failed to stat %s [%s]", ruleID, path, relPath)
@@ -90,34 +92,33 @@ func resolve(_ context.Context, e env.Env, ruleID string, res compliance.Resourc
 			"permissions": filePermissions,
 		}
 
-		content, err := readContent(path, fileContentParser)
+		content, err := readContent(files, path, fileContentParser)
 		if err == nil {
 			vars[compliance.FileFieldContent] = content
 			regoInput["content"] = content
+			if raw, ok := content.(string); ok && fileContentParser == "raw" {
+				regoInput["lines"] = splitLines(raw)
+			}
 		} else {
 			log.Errorf("error reading file: %v", err)
 		}
 
-		user, err := getFileUser(fi)
+		user, group, err := getFileOwner(fi)
 		if err == nil {
 			vars[compliance.FileFieldUser] = user
 			regoInput["user"] = user
-		}
-
-		group, err := getFileGroup(fi)
-		if err == nil {
 			vars[compliance.FileFieldGroup] = group
 			regoInput["group"] = group
 		}
 
 		functions := eval.FunctionMap{
-			compliance.FileFuncJQ:     fileJQ(path),
-			compliance.FileFuncYAML:   fileYAML(path),
-			compliance.FileFuncRegexp: fileRegexp(path),
+			compliance.FileFuncJQ:     fileJQ(files, path),
+			compliance.FileFuncYAML:   fileYAML(files, path),
+			compliance.FileFuncRegexp: fileRegexp(files, path),
 		}
 
 		instance := eval.NewInstance(vars, functions, regoInput)
-		resolvedInstance := resources.NewResolvedInstance(instance, path, "file")
+		resolvedInstance := resources.NewResolvedInstance(instance, files.id(path), "file")
 
 		if file.Path != "" {
 			return resolvedInstance, nil
@@ -140,7 +141,67 @@ func resolve(_ context.Context, e env.Env, ruleID string, res compliance.Resourc
 	return resources.NewResolvedInstances(instances), nil
 }
 
-func fileQuery(path string, get fileutils.Getter) eval.Function {
+// hostFiles reads the files of the host, or the files of the file system of
+// the env when it has one.
+type hostFiles struct {
+	env  env.Env
+	fsys fs.FS
+}
+
+// glob returns the paths matching pattern, an absolute path of the host. They
+// are the host root mount paths, or the paths of the file system.
+func (f hostFiles) glob(pattern string) ([]string, error) {
+	if f.fsys == nil {
+		return filepath.Glob(f.env.NormalizeToHostRoot(pattern))
+	}
+	return fs.Glob(f.fsys, strings.TrimPrefix(filepath.ToSlash(pattern), "/"))
+}
+
+// relative returns the absolute path on the host of a path returned by glob.
+func (f hostFiles) relative(path string) string {
+	if f.fsys == nil {
+		return f.env.RelativeToHostRoot(path)
+	}
+	return "/" + path
+}
+
+// id returns the resource ID of a path returned by glob.
+func (f hostFiles) id(path string) string {
+	if f.fsys == nil {
+		return path
+	}
+	return f.relative(path)
+}
+
+func (f hostFiles) stat(path string) (fs.FileInfo, error) {
+	if f.fsys == nil {
+		return os.Stat(path)
+	}
+	return fs.Stat(f.fsys, path)
+}
+
+func (f hostFiles) readFile(path string) ([]byte, error) {
+	if f.fsys == nil {
+		return os.ReadFile(path)
+	}
+	return fs.ReadFile(f.fsys, path)
+}
+
+// getFileOwner returns the user and the group owning the file, from its
+// env.FileOwner when set.
+func getFileOwner(fi fs.FileInfo) (string, string, error) {
+	if owner, ok := fi.Sys().(*env.FileOwner); ok {
+		return owner.User, owner.Group, nil
+	}
+	user, err := getFileUser(fi)
+	if err != nil {
+		return "", "", err
+	}
+	group, err := getFileGroup(fi)
+	return user, group, err
+}
+
+func fileQuery(files hostFiles, path string, get fileutils.Getter) eval.Function {
 	return func(_ eval.Instance, args ...interface{}) (interface{}, error) {
 		if len(args) != 1 {
 			return nil, fmt.Errorf(`invalid number of arguments, expecting 1 got %d`, len(args))
@@ -149,20 +210,24 @@ func fileQuery(path string, get fileutils.Getter) eval.Function {
 		if !ok {
 			return nil, fmt.Errorf(`expecting string value for query argument`)
 		}
-		return QueryValueFromFile(path, query, get)
+		data, err := files.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return get(data, query)
 	}
 }
 
-func fileJQ(path string) eval.Function {
-	return fileQuery(path, fileutils.JSONGetter)
+func fileJQ(files hostFiles, path string) eval.Function {
+	return fileQuery(files, path, fileutils.JSONGetter)
 }
 
-func fileYAML(path string) eval.Function {
-	return fileQuery(path, fileutils.YAMLGetter)
+func fileYAML(files hostFiles, path string) eval.Function {
+	return fileQuery(files, path, fileutils.YAMLGetter)
 }
 
-func fileRegexp(path string) eval.Function {
-	return fileQuery(path, fileutils.RegexpGetter)
+func fileRegexp(files hostFiles, path string) eval.Function {
+	return fileQuery(files, path, fileutils.RegexpGetter)
 }
 
 type contentParser func([]byte) (interface{}, error)
@@ -200,6 +265,21 @@ func parseYAMLContent(data []byte) (interface{}, error) {
 	return content, nil
 }
 
+// splitLines splits raw content in lines so that rego rules can report the
+// line number of their findings. Both LF and CRLF line endings are supported
+// and a trailing newline does not produce an extra empty line.
+func splitLines(content string) []string {
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return []string{}
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
 func validateParserKind(parser string) (string, error) {
 	if parser == "" {
 		return "", nil
@@ -213,18 +293,12 @@ func validateParserKind(parser string) (string, error) {
 }
 
 // readContent unmarshal file
-func readContent(filePath, parser string) (interface{}, error) {
+func readContent(files hostFiles, filePath, parser string) (interface{}, error) {
 	if parser == "" {
 		return "", nil
 	}
 
-	f, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	data, err := io.ReadAll(f)
+	data, err := files.readFile(filePath)
 	if err != nil {
 		return "", err
 	}
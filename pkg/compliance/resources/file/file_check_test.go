@@ -32,6 +32,12 @@ func setDefaultHooks(env *mocks.Env) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 }
@@ -39,6 +45,7 @@ func setDefaultHooks(env *mocks.Env) {
 func normalizePath(t *testing.T, env *mocks.Env, file *compliance.File) {
 	t.Helper()
 	env.On("MaxEventsPerRun").Return(30).Maybe()
+	env.On("FileSystem").Return(nil).Maybe()
 	env.On("NormalizeToHostRoot", file.Path).Return(file.Path)
 	env.On("RelativeToHostRoot", file.Path).Return(file.Path)
 	setDefaultHooks(env)
@@ -209,6 +216,8 @@ func TestFileCheck(t *testing.T) {
 				_, filePaths := createTempFiles(t, 1)
 
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return(filePaths[0])
 				env.On("RelativeToHostRoot", filePaths[0]).Return(file.Path)
 				setDefaultHooks(env)
@@ -232,6 +241,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(arrayModule, "true"),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 
 				tempDir, filePaths := createTempFiles(t, 2)
 				for _, filePath := range filePaths {
@@ -282,6 +292,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["log-driver"] == "json-file"`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/daemon.json")
 				env.On("RelativeToHostRoot", "./testdata/daemon.json").Return(file.Path)
 				setDefaultHooks(env)
@@ -308,6 +319,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["experimental"] == true`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/daemon.json")
 				env.On("RelativeToHostRoot", "./testdata/daemon.json").Return(file.Path)
 				setDefaultHooks(env)
@@ -348,6 +360,7 @@ func TestFileCheck(t *testing.T) {
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				path := "/etc/docker/daemon.json"
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", path).Return("./testdata/daemon.json")
 				env.On("RelativeToHostRoot", "./testdata/daemon.json").Return(path)
 				setDefaultHooks(env)
@@ -375,6 +388,7 @@ func TestFileCheck(t *testing.T) {
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				path := "/etc/docker/daemon.json"
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("EvaluateFromCache", mock.Anything).Return(path, nil)
 				env.On("NormalizeToHostRoot", path).Return("./testdata/daemon.json")
 				env.On("RelativeToHostRoot", "./testdata/daemon.json").Return(path)
@@ -402,6 +416,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["experimental"] == false`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("EvaluateFromCache", mock.Anything).Return("", nil)
 				setDefaultHooks(env)
 			},
@@ -422,6 +437,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["experimental"] == false`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("EvaluateFromCache", mock.Anything).Return(true, nil)
 				setDefaultHooks(env)
 			},
@@ -445,6 +461,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["experimental"] == false`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("EvaluateFromCache", mock.Anything).Return(nil, errors.New("1:1: unknown function process.unknown()"))
 				setDefaultHooks(env)
 			},
@@ -465,6 +482,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["default-ulimits"].nofile.Hard == 64000`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/daemon.json")
 				env.On("RelativeToHostRoot", "./testdata/daemon.json").Return(file.Path)
 				setDefaultHooks(env)
@@ -491,6 +509,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `file.content["apiVersion"] == "v1"`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/pod.yaml")
 				env.On("RelativeToHostRoot", "./testdata/pod.yaml").Return(file.Path)
 				setDefaultHooks(env)
@@ -517,6 +536,7 @@ func TestFileCheck(t *testing.T) {
 			module: fmt.Sprintf(objectModule, `regex.match("[a-zA-Z0-9-_/]+ /boot/efi [a-zA-Z0-9-_/]+", file.content)`),
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
+				env.On("FileSystem").Return(nil).Maybe()
 				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/mounts")
 				env.On("RelativeToHostRoot", "./testdata/mounts").Return(file.Path)
 				setDefaultHooks(env)
@@ -581,3 +601,21 @@ func TestFileCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"", []string{}},
+		{"\n", []string{}},
+		{"a", []string{"a"}},
+		{"a\nb\n", []string{"a", "b"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+		{"a\n\nb", []string{"a", "", "b"}},
+		{"a\nb\n\n", []string{"a", "b", ""}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, splitLines(test.content), "content %q", test.content)
+	}
+}
@@ -35,7 +35,12 @@ func resolve(_ context.Context, e env.Env, id string, res compliance.ResourceCom
 		return nil, fmt.Errorf("%s: expecting process resource in process check", id)
 	}
 
-	matchedProcesses, err := processutils.FindProcessesByName(res.Process.Name)
+	findProcessesByName := processutils.FindProcessesByName
+	if client := e.ProcessClient(); client != nil {
+		findProcessesByName = client.FindProcessesByName
+	}
+
+	matchedProcesses, err := findProcessesByName(res.Process.Name)
 	if err != nil {
 		return nil, log.Errorf("%s: Unable to fetch processes: %v", id, err)
 	}
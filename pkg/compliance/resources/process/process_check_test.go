@@ -97,6 +97,12 @@ func (f *processFixture) run(t *testing.T) {
 	env.On("ProvidedInput", "rule-id").Return(nil).Maybe()
 	env.On("DumpInputPath").Return("").Maybe()
 	env.On("ShouldSkipRegoEval").Return(false).Maybe()
+	env.On("MaxInputBytes").Return(0).Maybe()
+	env.On("ResourceFilter").Return(nil).Maybe()
+	env.On("HermeticRegoEval").Return(false).Maybe()
+	env.On("RegoEvalTime").Return(time.Time{}).Maybe()
+	env.On("RegoPrintHook").Return(nil).Maybe()
+	env.On("ProcessClient").Return(nil).Maybe()
 	env.On("Hostname").Return("test-host").Maybe()
 	env.On("StatsdClient").Return(nil).Maybe()
 
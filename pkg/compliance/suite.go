@@ -7,7 +7,6 @@ package compliance
 
 import (
 	"errors"
-	"os"
 
 	"github.com/Masterminds/semver"
 	"gopkg.in/yaml.v2"
@@ -30,6 +29,7 @@ type SuiteMeta struct {
 	Framework string      `yaml:"framework,omitempty"`
 	Version   string      `yaml:"version,omitempty"`
 	Tags      []string    `yaml:"tags,omitempty"`
+	Interval  string      `yaml:"interval,omitempty"` // default period of the rules of the suite
 	Source    string      `yaml:"-"`
 }
 
@@ -46,7 +46,7 @@ func ParseSuite(config string) (*Suite, error) {
 		return nil, err
 	}
 
-	f, err := os.ReadFile(config)
+	f, err := ReadPolicyFile(config)
 	if err != nil {
 		return nil, err
 	}
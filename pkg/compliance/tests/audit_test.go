@@ -8,7 +8,76 @@
 
 package tests
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/audit"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func TestAuditInput(t *testing.T) {
 }
+
+const auditRego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	count(input.audit) > 0
+	audit := input.audit[_]
+	f := dd.passed_finding("audit", audit.path, {"permissions": audit.permissions})
+}
+
+findings[f] {
+	count(input.audit) == 0
+	f := dd.failing_finding("audit", "none", {})
+}
+`
+
+func TestAuditRules(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		rules  func(path string) []AuditRule
+		assert func(r *assertedRule, path string)
+	}{
+		{
+			name: "FileWatchPresent",
+			rules: func(path string) []AuditRule {
+				return []AuditRule{{Path: "/etc/other"}, {Path: path, Permissions: "rwa"}}
+			},
+			assert: func(r *assertedRule, path string) {
+				r.AssertPassedEvent(func(t eventT, evt *event.Event) {
+					assert.Equal(t, path, evt.ResourceID)
+					assert.Equal(t, "rwa", evt.Data.(event.Data)["permissions"])
+				})
+			},
+		},
+		{
+			name: "FileWatchMissing",
+			rules: func(path string) []AuditRule {
+				return []AuditRule{{Path: "/etc/other", Permissions: "wa"}}
+			},
+			assert: func(r *assertedRule, path string) {
+				r.AssertFailedEvent(nil)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewTestBench(t)
+			defer b.Run()
+
+			path := b.WriteTempFile(t, "{}")
+			b.WithAuditRules(tc.rules(path)...)
+			r := b.AddRule(tc.name).
+				WithInput(`
+- audit:
+		path: %s
+	type: array
+`, path).
+				WithRego(auditRego)
+			tc.assert(r, path)
+		})
+	}
+}
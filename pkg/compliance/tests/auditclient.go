@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"github.com/elastic/go-libaudit/rule"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+// AuditRule is a file watch audit rule, as set by `auditctl -w Path -p
+// Permissions`. Permissions is made of the r, w, x and a letters.
+type AuditRule struct {
+	Path        string
+	Permissions string
+}
+
+// NewFakeAuditClient returns an audit client listing the given file watch
+// rules. Without rules, the client reports that no file is watched.
+func NewFakeAuditClient(rules ...AuditRule) env.AuditClient {
+	client := &fakeAuditClient{}
+	for _, r := range rules {
+		watch := &rule.FileWatchRule{
+			Type: rule.FileWatchRuleType,
+			Path: r.Path,
+		}
+		for _, p := range r.Permissions {
+			switch p {
+			case 'r':
+				watch.Permissions = append(watch.Permissions, rule.ReadAccessType)
+			case 'w':
+				watch.Permissions = append(watch.Permissions, rule.WriteAccessType)
+			case 'x':
+				watch.Permissions = append(watch.Permissions, rule.ExecuteAccessType)
+			case 'a':
+				watch.Permissions = append(watch.Permissions, rule.AttributeChangeAccessType)
+			default:
+				panic("unknown audit permission " + string(p))
+			}
+		}
+		client.rules = append(client.rules, watch)
+	}
+	return client
+}
+
+type fakeAuditClient struct {
+	rules []*rule.FileWatchRule
+}
+
+func (c *fakeAuditClient) GetFileWatchRules() ([]*rule.FileWatchRule, error) {
+	return c.rules, nil
+}
+
+func (c *fakeAuditClient) Close() error {
+	return nil
+}
+
+// WithAuditRules makes the suite use an audit client listing the given file
+// watch rules. See NewFakeAuditClient.
+func (s *suite) WithAuditRules(rules ...AuditRule) *suite {
+	return s.WithAuditClient(NewFakeAuditClient(rules...))
+}
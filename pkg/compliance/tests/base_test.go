@@ -6,7 +6,20 @@
 package tests
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	"github.com/DataDog/datadog-agent/pkg/compliance/mocks"
+	"github.com/stretchr/testify/assert"
 
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/constants"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/file"
@@ -21,6 +34,11 @@ func TestBase(t *testing.T) {
 		WithRego(``).
 		AssertError()
 
+	b.AddRule("BadYamlSuiteInputMatches").
+		WithInput(`zsd`).
+		WithRego(``).
+		AssertErrorMatches("cannot unmarshal !!str `zsd`")
+
 	b.AddRule("DuplicatedTag").
 		WithInput(`
 - file:
@@ -127,3 +145,1170 @@ findings[f] {
 		WithRego(`{`).
 		AssertErrorEvent()
 }
+
+func TestDisabledFrameworks(t *testing.T) {
+	var disabledFrameworks []string
+
+	b := NewTestBench(t).
+		WithSuiteMatcher(checks.IsFrameworkEnabled(func() []string {
+			return disabledFrameworks
+		}))
+	defer b.Run()
+
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+
+	b.AddRule("FrameworkEnabled").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertPassedEvent(nil)
+
+	b.AddRule("FrameworkDisabled").
+		Setup(func(t *testing.T, ctx context.Context) {
+			disabledFrameworks = []string{"framework_FrameworkDisabled"}
+		}).
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertNoEvent()
+}
+
+func TestRegoVersions(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Versions").
+		WithInput(`
+- constants:
+		max_age: 90
+`).
+		WithRegoVersion("v1", `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.max_age <= 90
+	f := dd.passed_finding("foo", "bar", {})
+}
+`, func(r *assertedRule) {
+			r.AssertPassedEvent(nil)
+		}).
+		WithRegoVersion("v2", `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.max_age > 60
+	f := dd.failing_finding("foo", "bar", {})
+}
+`, func(r *assertedRule) {
+			r.AssertFailedEvent(nil)
+		})
+}
+
+func TestValidate(t *testing.T) {
+	b := NewTestBench(t)
+
+	b.AddRule("Specified").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		AssertNoEvent()
+
+	b.AddRule("Unspecified1").
+		WithInput(`
+- constants:
+		foo: bar
+`)
+
+	b.AddRule("Unspecified2").
+		WithRegoVersion("v1", ``, func(r *assertedRule) {})
+
+	err := b.Validate()
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "Specified,")
+		assert.Contains(t, err.Error(), "Unspecified1, Unspecified2")
+	}
+}
+
+func TestInputTooLarge(t *testing.T) {
+	b := NewTestBench(t).WithMaxInputBytes(512)
+	defer b.Run()
+
+	b.AddRule("SmallInput").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertPassedEvent(nil)
+
+	b.AddRule("LargeInput").
+		WithInput(`
+- constants:
+		foo: %s
+`, strings.Repeat("a", 1024)).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertInputTooLarge()
+}
+
+func TestNoDisallowedBuiltins(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("NoHTTP").
+		WithInput(`
+- constants:
+		url: https://example.com
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	startswith(input.constants.url, "https://")
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertNoDisallowedBuiltins("http.send", "opa.runtime").
+		AssertPassedEvent(nil)
+}
+
+func TestHermetic(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Hermetic").
+		WithInput(`
+- constants:
+		url: https://example.com
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	startswith(input.constants.url, "https://")
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertHermetic().
+		AssertPassedEvent(nil)
+}
+
+func TestHostProfiles(t *testing.T) {
+	dockerClient := &mocks.DockerClient{}
+	dockerClient.On("Close").Return(nil)
+
+	b := NewTestBench(t).
+		WithHostProfile("docker", checks.WithDockerClient(dockerClient)).
+		WithHostProfile("bare")
+	defer b.Run()
+
+	b.AddRule("DockerOnly").
+		WithScope("docker").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertEventCountsPerProfile(map[string]int{
+			"docker": 1,
+			"bare":   0,
+		})
+
+	b.AddRule("Unscoped").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertEventCountsPerProfile(map[string]int{
+			"docker": 1,
+			"bare":   1,
+		})
+}
+
+func TestRegoRuntimeError(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Conflict").
+		WithInput(`
+- constants:
+		passed: true
+		failed: true
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+status = "passed" { input.constants.passed }
+status = "failed" { input.constants.failed }
+
+findings[f] {
+	f := dd.passed_finding("foo", status, {})
+}
+`).
+		AssertRegoRuntimeError("complete rules must not produce multiple outputs")
+}
+
+func TestAssertSingleErrorEvent(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Unavailable").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.error_finding("foo", "bar", "docker client unavailable")
+}
+`).
+		AssertSingleErrorEvent("client unavailable")
+
+	evt := &event.Event{Result: event.Error, Data: event.Data{"error": "permission denied"}}
+	p := probe(b.rules[0].asserts[0], evt)
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`expected an error event caused by "client unavailable" but got cause: permission denied`}, p.messages)
+
+	p = probe(b.rules[0].asserts[0], &event.Event{Result: event.Passed, Data: event.Data{}})
+	assert.True(t, p.failed)
+}
+
+func TestRegoCompileErrorRe(t *testing.T) {
+	assert.True(t, regoCompileErrorRe.MatchString("1 error occurred: Rule.rego:6: rego_type_error: undefined function foo"))
+	assert.True(t, regoCompileErrorRe.MatchString("1 error occurred: Rule.rego:3: rego_parse_error: unexpected eof token"))
+	assert.False(t, regoCompileErrorRe.MatchString("Rule.rego:5: eval_conflict_error: complete rules must not produce multiple outputs"))
+}
+
+func TestSetupWithCleanup(t *testing.T) {
+	var calls []string
+	t.Run("bench", func(t *testing.T) {
+		b := NewTestBench(t)
+		defer b.Run()
+
+		b.AddRule("Cleanup").
+			SetupWithCleanup(func(t *testing.T, ctx context.Context) func() {
+				calls = append(calls, "setup1")
+				return func() { calls = append(calls, "cleanup1") }
+			}).
+			Setup(func(t *testing.T, ctx context.Context) {
+				calls = append(calls, "setup2")
+			}).
+			SetupWithCleanup(func(t *testing.T, ctx context.Context) func() {
+				calls = append(calls, "setup3")
+				return func() { calls = append(calls, "cleanup3") }
+			}).
+			WithInput(`
+- constants:
+		foo: bar
+`).
+			WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("a", "b", {})
+}
+`).
+			AssertPassedEvent(func(t eventT, evt *event.Event) {
+				calls = append(calls, "assert")
+			})
+	})
+	assert.Equal(t, []string{"setup1", "setup2", "setup3", "assert", "cleanup3", "cleanup1"}, calls)
+}
+
+func TestRegoPrintCapture(t *testing.T) {
+	var b *suite
+	t.Run("bench", func(t *testing.T) {
+		b = NewTestBench(t).WithRegoPrintCapture()
+		defer b.Run()
+
+		b.AddRule("Print").
+			WithInput(`
+- constants:
+		foo: bar
+`).
+			WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	print("foo is", input.constants.foo)
+	f := dd.passed_finding("a", "b", {})
+}
+`).
+			AssertPassedEvent(nil)
+	})
+	assert.Equal(t, []string{"foo is bar"}, b.regoPrints.outputs["Print"])
+}
+
+func TestRuleTimeout(t *testing.T) {
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Quick").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		WithTimeout(time.Minute).
+		AssertPassedEvent(nil)
+
+	stuck := NewTestBench(t).
+		AddRule("Stuck").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		WithTimeout(10 * time.Millisecond).
+		Setup(func(t *testing.T, ctx context.Context) {
+			<-ctx.Done()
+		})
+	_, err := stuck.runChecks(t, nil)
+	assert.EqualError(t, err, `rule "Stuck" exceeded timeout 10ms`)
+
+	// the events reported by the abandoned checks are dropped
+	stuck.Report(&event.Event{AgentRuleID: "Stuck"})
+	stuck.ReportRaw([]byte("{}"), "")
+	assert.Empty(t, stuck.events)
+	assert.Empty(t, stuck.rawReports)
+}
+
+func TestSingleSuite(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.RunAsSingleSuite()
+
+	const input = `
+- constants:
+		foo: bar
+`
+
+	b.AddRule("First").
+		WithInput(input).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("first", "first", {})
+}
+`).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "First", evt.AgentRuleID)
+			assert.Equal(t, "framework_SingleSuite", evt.AgentFrameworkID)
+		})
+
+	b.AddRule("Second").
+		WithInput(input).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.failing_finding("second", "second", {})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "Second", evt.AgentRuleID)
+		})
+
+	b.AddRule("Third").
+		WithInput(input).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "baz"
+	f := dd.passed_finding("third", "third", {})
+}
+`).
+		AssertNoEvent()
+}
+
+func TestSingleSuiteEnv(t *testing.T) {
+	first := NewTestBench(t).AddRule("First").WithEnv(map[string]string{"FOO": "foo", "BAR": "bar"})
+	second := NewTestBench(t).AddRule("Second").WithEnv(map[string]string{"FOO": "foo"})
+
+	env, err := singleSuiteEnv([]*assertedRule{first, second})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FOO": "foo", "BAR": "bar"}, env)
+
+	second.WithEnv(map[string]string{"BAR": "baz"})
+	_, err = singleSuiteEnv([]*assertedRule{first, second})
+	assert.EqualError(t, err, `rule "Second" sets BAR="baz" but rule "First" sets it to "bar": the rules of a single suite share their environment variables`)
+}
+
+func TestAssertPassedEventWithResource(t *testing.T) {
+	r := NewTestBench(t).AddRule("Resource").
+		AssertPassedEventWithResource("file", "/etc/passwd", nil)
+
+	p := probe(r.asserts[0], &event.Event{Result: "passed", ResourceType: "file", ResourceID: "/etc/passwd"})
+	assert.False(t, p.failed)
+
+	p = probe(r.asserts[0], &event.Event{Result: "passed", ResourceType: "file", ResourceID: "/etc/shadow"})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`expected an event on resource file "/etc/passwd" but it was reported on file "/etc/shadow"`}, p.messages)
+}
+
+func TestAssertPassedEventWithTag(t *testing.T) {
+	r := NewTestBench(t).AddRule("Tags").
+		AssertPassedEventWithTag("kube_namespace:default", nil).
+		AssertPassedEventWithTag("image_id", nil).
+		AssertPassedEventWithoutTag("image_id", nil)
+
+	tagged := &event.Event{Result: "passed", Tags: []string{"kube_namespace:default", "image_id:sha256:1234"}}
+	untagged := &event.Event{Result: "passed", Tags: []string{"kube_namespace:kube-system"}}
+
+	assert.False(t, probe(r.asserts[0], tagged).failed)
+	assert.False(t, probe(r.asserts[1], tagged).failed)
+	assert.True(t, probe(r.asserts[2], tagged).failed)
+
+	p := probe(r.asserts[0], untagged)
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`expected an event with tag "kube_namespace:default" but it has tags [kube_namespace:kube-system]`}, p.messages)
+	assert.True(t, probe(r.asserts[1], untagged).failed)
+	assert.False(t, probe(r.asserts[2], untagged).failed)
+}
+
+func TestAssertFailedEventContains(t *testing.T) {
+	r := NewTestBench(t).AddRule("Reason").
+		AssertFailedEventContains("world-writable")
+
+	assert.False(t, probe(r.asserts[0], &event.Event{Result: "failed", Data: event.Data{"reason": "file is world-writable"}}).failed)
+	assert.False(t, probe(r.asserts[0], &event.Event{Result: "failed", Data: event.Data{"reason": "mode 0777", "message": "/tmp is world-writable"}}).failed)
+	assert.True(t, probe(r.asserts[0], &event.Event{Result: "passed", Data: event.Data{"reason": "file is world-writable"}}).failed)
+
+	p := probe(r.asserts[0], &event.Event{Result: "failed", Data: event.Data{"reason": "mode 0777", "error": "stat failed"}})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{"expected a failed event with a reason containing \"world-writable\" but got:\n\treason: \"mode 0777\"\n\terror: \"stat failed\""}, p.messages)
+
+	p = probe(r.asserts[0], &event.Event{Result: "failed", Data: event.Data{"mode": 511}})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`expected a failed event with a reason containing "world-writable" but it has no reason/message/error field: map[mode:511]`}, p.messages)
+}
+
+func TestEventsDiff(t *testing.T) {
+	r := NewTestBench(t).AddRule("Diff").
+		AssertPassedEvent(nil).
+		AssertFailedEvent(nil).
+		AssertNoErrorEvent()
+
+	diff := r.eventsDiff([]*event.Event{
+		{Result: "passed", ResourceType: "file", ResourceID: "/etc/passwd"},
+		{Result: "error", ResourceType: "file", ResourceID: "/etc/shadow"},
+	})
+	assert.Equal(t, `#  expected   actual  resource
+0  passed     passed  file /etc/passwd
+1  failed     error   file /etc/shadow <-
+2  not error  none    - <-
+`, diff)
+
+	diff = r.eventsDiff([]*event.Event{
+		{Result: "passed", ResourceType: "file", ResourceID: "/etc/passwd"},
+		{Result: "failed", ResourceType: "file", ResourceID: "/etc/shadow"},
+		{Result: "failed", ResourceType: "file", ResourceID: "/etc/group"},
+		{Result: "passed", ResourceType: "file", ResourceID: "/etc/hosts"},
+	})
+	assert.Equal(t, `#  expected   actual  resource
+0  passed     passed  file /etc/passwd
+1  failed     failed  file /etc/shadow
+2  not error  failed  file /etc/group
+3  none       passed  file /etc/hosts <-
+`, diff)
+}
+
+func TestHostnameFunc(t *testing.T) {
+	const input = `
+- constants:
+		foo: bar
+`
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	"{{.Hostname}}" != ""
+	f := dd.passed_finding("host", "{{.Hostname}}", {})
+}
+
+findings[f] {
+	"{{.Hostname}}" == ""
+	f := dd.failing_finding("host", "unknown", {})
+}
+`
+
+	calls := 0
+	b := NewTestBench(t).WithHostnameFunc(func() (string, error) {
+		calls++
+		return fmt.Sprintf("host%d", calls), nil
+	})
+	b.AddRule("First").
+		WithInput(input).
+		WithRego(rego).
+		AssertPassedEventWithResource("host", "host1", nil)
+	b.AddRule("Second").
+		WithInput(input).
+		WithRego(rego).
+		AssertPassedEventWithResource("host", "host2", nil)
+	b.Run()
+	assert.Equal(t, 2, calls)
+
+	b = NewTestBench(t).WithHostnameFunc(func() (string, error) {
+		return "", nil
+	})
+	b.AddRule("Empty").
+		WithInput(input).
+		WithRego(rego).
+		AssertFailedEvent(nil)
+	b.Run()
+
+	b = NewTestBench(t).WithHostnameFunc(func() (string, error) {
+		return "", errors.New("no hostname")
+	})
+	b.AddRule("Failing").
+		WithInput(input).
+		WithRego(rego).
+		AssertError()
+	b.Run()
+}
+
+func TestAssertErrorMatches(t *testing.T) {
+	c := &assertedRule{}
+	c.AssertErrorMatches("rego_parse_error")
+
+	p := &probeT{}
+	c.checkError(p, errors.New("1 error occurred: rego_parse_error: unexpected eof token"))
+	assert.False(t, p.failed)
+
+	p = &probeT{}
+	c.checkError(p, errors.New("yaml: unmarshal errors"))
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`expected an error containing "rego_parse_error", got: yaml: unmarshal errors`}, p.messages)
+}
+
+func TestForHostnames(t *testing.T) {
+	b := NewTestBench(t).WithHostname("default")
+	defer b.Run()
+
+	b.AddRule("PerHost").
+		WithInput(`
+- constants:
+		allowed: ["web-1", "web-2"]
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.allowed[_] == "{{.Hostname}}"
+	f := dd.passed_finding("host", "{{.Hostname}}", {})
+}
+
+findings[f] {
+	count({h | h := input.constants.allowed[_]; h == "{{.Hostname}}"}) == 0
+	f := dd.failing_finding("host", "{{.Hostname}}", {})
+}
+`).
+		ForHostnames(func(hostname string, r *assertedRule) {
+			if hostname == "db-1" {
+				r.AssertFailedEvent(func(t eventT, evt *event.Event) {
+					assert.Equal(t, hostname, evt.ResourceID)
+				})
+			} else {
+				r.AssertPassedEventWithResource("host", hostname, nil)
+			}
+		}, "web-1", "web-2", "db-1")
+}
+
+func TestScopeExpr(t *testing.T) {
+	const input = `
+- constants:
+		foo: bar
+`
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+
+	dockerClient := &mocks.DockerClient{}
+	dockerClient.On("Close").Return(nil)
+
+	b := NewTestBench(t).WithDockerClient(dockerClient)
+	b.AddRule("DockerMatched").
+		WithScopeExpr("docker, none").
+		WithInput(input).
+		WithRego(rego).
+		AssertPassedEvent(nil)
+	b.AddRule("KubernetesNodeSkipped").
+		WithScopeExpr("kubernetesNode,none").
+		WithInput(input).
+		WithRego(rego).
+		AssertNoEvent()
+	b.Run()
+
+	b = NewTestBench(t)
+	b.AddRule("DockerSkipped").
+		WithScopeExpr("docker, none").
+		WithInput(input).
+		WithRego(rego).
+		AssertNoEvent()
+	b.AddRule("UnknownScope").
+		WithScopeExpr("docker.running").
+		WithInput(input).
+		WithRego(rego).
+		AssertNoEvent()
+	b.Run()
+
+	assert.Contains(t, buildSuite("Scopes", "", b.rules[0]), "scope:\n      - docker\n      - none\n")
+	p := probeRule(func(r *assertedRule) {
+		r.WithScopeExpr(" , ")
+	})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`empty scope expression for rule "Probe"`}, p.messages)
+}
+
+func TestDiagnoseSuite(t *testing.T) {
+	r := &assertedRule{name: "Valid", input: "- constants:\n    foo: bar\n"}
+	assert.Empty(t, diagnoseSuite(buildSuite("Valid", "", r)))
+
+	diagnosis := diagnoseSuite("schema:\n  version: 1.0.0\nrules:\n  - id: Bad\n   scope: none\n")
+	assert.Contains(t, diagnosis, "generated suite is malformed: yaml: line 4:")
+	assert.Contains(t, diagnosis, "\nline 4:   - id: Bad\n")
+	assert.Contains(t, diagnosis, "\n>   4 |   - id: Bad\n")
+	assert.Contains(t, diagnosis, "\n    5 |    scope: none\n")
+}
+
+func TestStableEventOrder(t *testing.T) {
+	b := NewTestBench(t).WithStableEventOrder()
+	defer b.Run()
+
+	b.AddRule("Sorted").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "a", {})
+}
+
+findings[f] {
+	f := dd.failing_finding("foo", "b", {})
+}
+
+findings[f] {
+	f := dd.passed_finding("foo", "c", {})
+}
+`).
+		AssertPassedEventWithResource("foo", "a", nil).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "b", evt.ResourceID)
+		}).
+		AssertPassedEventWithResource("foo", "c", nil)
+}
+
+func TestTagFilter(t *testing.T) {
+	b := NewTestBench(t).WithTagFilter("cis")
+	defer b.Run()
+
+	const input = `
+- constants:
+		foo: bar
+`
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+
+	b.AddRule("Selected").
+		WithTags("cis", "docker").
+		WithInput(input).
+		WithRego(rego).
+		AssertPassedEvent(nil)
+
+	// would fail if it was run
+	b.AddRule("Filtered").
+		WithTags("stig").
+		WithInput(input).
+		WithRego(rego).
+		AssertFailedEvent(nil)
+
+	b.AddRule("Untagged").
+		WithInput(input).
+		WithRego(rego).
+		AssertFailedEvent(nil)
+}
+
+func TestRawReports(t *testing.T) {
+	r := NewTestBench(t).AddRule("Raw").
+		AssertNoEvent().
+		AssertRawReport(func(t eventT, content []byte, service string, tags []string) {
+			assert.Equal(t, "payload", string(content))
+			assert.Equal(t, "compliance", service)
+			assert.Equal(t, []string{"host:foo"}, tags)
+		})
+
+	content := []byte("payload")
+	r.ReportRaw(content, "compliance", "host:foo")
+	content[0] = 'P'
+	r.assertEvents(t)
+
+	r = NewTestBench(t).AddRule("NoRaw").
+		AssertNoEvent().
+		DisallowRawReports()
+	r.assertEvents(t)
+}
+
+func TestParallel(t *testing.T) {
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+
+	b := NewTestBench(t).Parallel()
+	defer b.Run()
+
+	for i := 0; i < 3; i++ {
+		b.AddRule(fmt.Sprintf("Parallel%d", i)).
+			WithInput(`
+- constants:
+		foo: bar
+`).
+			WithRego(rego).
+			AssertPassedEvent(nil)
+	}
+
+	// run alone, as it changes the local timezone
+	b.AddRule("Timezones").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertStableAcrossTimezones("Asia/Tokyo").
+		AssertPassedEvent(nil)
+
+	assert.True(t, b.runsInParallel(b.rules[0]))
+	assert.False(t, b.runsInParallel(b.rules[3]))
+}
+
+func TestParallelClients(t *testing.T) {
+	b := NewTestBench(t).WithDockerClient(&mocks.DockerClient{})
+	r := b.AddRule("Rule")
+	assert.False(t, b.runsInParallel(r))
+
+	b.Parallel()
+	assert.False(t, b.runsInParallel(r))
+
+	b.WithConcurrentClients()
+	assert.True(t, b.runsInParallel(r))
+
+	r.WithEnv(map[string]string{"FOO": "bar"})
+	assert.False(t, b.runsInParallel(r))
+}
+
+func TestRegoFile(t *testing.T) {
+	b := NewTestBench(t).WithHostname("myhost")
+	defer b.Run()
+
+	b.AddRule("Hostname").
+		WithInput(`
+- constants:
+		hostname: myhost
+`).
+		WithRegoFile("testdata/hostname.rego").
+		AssertPassedEventWithResource("host", "Hostname", func(t eventT, evt *event.Event) {
+			assert.Equal(t, "myhost", evt.Data.(event.Data)["hostname"])
+		})
+
+	p := probeRule(func(r *assertedRule) {
+		r.WithRegoFile("testdata/missing.rego")
+	})
+	assert.True(t, p.failed)
+	assert.Len(t, p.messages, 1)
+	assert.Contains(t, p.messages[0], `could not read rego of rule "Probe"`)
+}
+
+func TestRegoGzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.foo == "bar"
+	f := dd.passed_finding("gzip", "rego", {})
+}
+`))
+	_ = w.Close()
+
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("Gzipped").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRegoGzip(buf.Bytes()).
+		AssertPassedEventWithResource("gzip", "rego", nil)
+
+	// the runs in each timezone write the gzipped rego as well
+	b.AddRule("GzippedTimezones").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRegoGzip(buf.Bytes()).
+		AssertStableAcrossTimezones("Asia/Tokyo")
+
+	p := probeRule(func(r *assertedRule) {
+		r.WithRegoGzip([]byte("package datadog"))
+	})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`could not decompress rego of rule "Probe": gzip: invalid header`}, p.messages)
+}
+
+func TestRegoVars(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.constants.max_age > {{.MaxAge}}
+	f := dd.failing_finding("password", "{{.RuleID}}", {"max_age": {{.MaxAge}}})
+}
+
+findings[f] {
+	input.constants.max_age <= {{.MaxAge}}
+	f := dd.passed_finding("password", "{{.RuleID}}", {"max_age": {{.MaxAge}}})
+}
+`
+	const input = `
+- constants:
+		max_age: 90
+`
+
+	b.AddRule("TooOld").
+		WithInput(input).
+		WithRegoVars(map[string]any{"MaxAge": 60}).
+		WithRego(rego).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "TooOld", evt.ResourceID)
+			assert.Equal(t, "60", fmt.Sprint(evt.Data.(event.Data)["max_age"]))
+		})
+
+	b.AddRule("Recent").
+		WithInput(input).
+		WithRegoVars(map[string]any{"MaxAge": 365}).
+		WithRego(rego).
+		AssertPassedEvent(nil)
+
+	p := probeRule(func(r *assertedRule) {
+		r.WithRegoVars(map[string]any{"RuleID": "foo"})
+	})
+	assert.True(t, p.failed)
+	assert.Equal(t, []string{`rego variable "RuleID" of rule "Probe" is reserved`}, p.messages)
+
+	p = probeRule(func(r *assertedRule) {
+		r.WithRego(rego)
+	})
+	assert.True(t, p.failed)
+	assert.Len(t, p.messages, 1)
+	assert.Contains(t, p.messages[0], `could not render rego of rule "Probe"`)
+	assert.Contains(t, p.messages[0], `map has no entry for key "MaxAge"`)
+}
+
+func TestDumpGenerated(t *testing.T) {
+	b := NewTestBench(t)
+	b.AddRule("Dumped").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+`).
+		WithRegoVersion("v2", `
+package datadog
+import future.keywords.if
+`, func(r *assertedRule) { r.AssertNoEvent() })
+
+	var buf bytes.Buffer
+	b.DumpGenerated(&buf)
+	dump := buf.String()
+	assert.Contains(t, dump, "--- rule Dumped/v2: Dumped.yaml ---\nschema:")
+	assert.Contains(t, dump, "  - id: Dumped\n")
+	assert.Contains(t, dump, "--- rule Dumped/v2: Dumped.rego ---\n\npackage datadog\nimport future.keywords.if\n")
+}
+
+func TestSuiteInterval(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "bar", {})
+}
+`
+
+	b.AddRule("DefaultInterval").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertInterval(20 * time.Minute)
+
+	b.AddRule("SuiteInterval").
+		WithSuiteInterval("4h").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertInterval(4 * time.Hour).
+		AssertPassedEvent(nil)
+
+	b.AddRule("InvalidSuiteInterval").
+		WithSuiteInterval("often").
+		WithInput(`
+- constants:
+		foo: bar
+`).
+		WithRego(rego).
+		AssertNoEvent()
+}
+
+func TestRegoCalls(t *testing.T) {
+	calls, err := regoCalls("test", `
+package datadog
+
+response := http.send({"method": "GET", "url": input.url})
+
+allowed {
+	count(input.items) > 0
+	lower(input.name) == "foo"
+}
+`)
+	if assert.NoError(t, err) {
+		assert.Contains(t, calls, "http.send")
+		assert.Contains(t, calls, "count")
+		assert.Contains(t, calls, "lower")
+		assert.Contains(t, calls, "gt")
+		assert.NotContains(t, calls, "opa.runtime")
+	}
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		input    string
+		expected string
+	}{
+		{
+			name:     "single line",
+			count:    1,
+			input:    "foo: bar",
+			expected: "foo: bar",
+		},
+		{
+			name:     "trailing newlines",
+			count:    1,
+			input:    "foo: bar\nbaz: qux\n\n",
+			expected: "foo: bar\n  baz: qux",
+		},
+		{
+			name:     "leading blank lines",
+			count:    1,
+			input:    "\n  \nfoo: bar\nbaz: qux",
+			expected: "foo: bar\n  baz: qux",
+		},
+		{
+			name:     "blank lines",
+			count:    2,
+			input:    "foo: bar\n\n    \nbaz: qux",
+			expected: "foo: bar\n\n\n    baz: qux",
+		},
+		{
+			name:     "nested maps",
+			count:    1,
+			input:    "- constants:\n    foo:\n      bar: baz\n  tag: foo",
+			expected: "- constants:\n      foo:\n        bar: baz\n    tag: foo",
+		},
+		{
+			name:     "common indentation",
+			count:    1,
+			input:    "\n    - constants:\n        foo: bar\n      tag: foo\n  ",
+			expected: "- constants:\n      foo: bar\n    tag: foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, indent(test.count, test.input))
+		})
+	}
+}
+
+func TestInputIndentation(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	b.AddRule("IndentedInput").
+		WithInput(`
+		- constants:
+				foo: bar
+
+				baz: qux
+			tag: consts
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.consts.foo == "bar"
+	input.consts.baz == "qux"
+	f := dd.passed_finding("consts", "consts", {})
+}
+`).
+		AssertPassedEvent(nil)
+}
+
+func TestDefaultAssert(t *testing.T) {
+	var seen []string
+	t.Run("bench", func(t *testing.T) {
+		b := NewTestBench(t).
+			WithDefaultAssert(func(t eventT, evt *event.Event) {
+				assert.Equal(t, "foo", evt.ResourceType)
+				seen = append(seen, evt.ResourceID)
+			})
+		defer b.Run()
+
+		const input = `
+- constants:
+		foo: bar
+`
+		b.AddRule("Passed").
+			WithInput(input).
+			WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("foo", "a", {})
+}
+
+findings[f] {
+	f := dd.passed_finding("foo", "b", {})
+}
+`).
+			AssertUnorderedEvents().
+			AssertPassedEvent(nil).
+			AssertPassedEvent(nil)
+
+		b.AddRule("NoEvent").
+			WithInput(input).
+			WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.nopenope
+	f := dd.passed_finding("foo", "c", {})
+}
+`).
+			AssertNoEvent()
+	})
+	sort.Strings(seen)
+	assert.Equal(t, []string{"a", "b"}, seen)
+}
@@ -0,0 +1,325 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/cloud"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudResources(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{
+				Type: "aws_s3_bucket",
+				ID:   "arn:aws:s3:::private",
+				Attributes: map[string]interface{}{
+					"name": "private",
+					"public_access_block": map[string]interface{}{
+						"block_public_acls":   true,
+						"block_public_policy": true,
+					},
+				},
+			},
+			&env.CloudResource{
+				Type: "aws_s3_bucket",
+				ID:   "arn:aws:s3:::public",
+				Attributes: map[string]interface{}{
+					"name":                "public",
+					"public_access_block": nil,
+				},
+			},
+			&env.CloudResource{
+				Type: "aws_iam_role",
+				ID:   "arn:aws:iam::123456789012:role/foo",
+			},
+		)
+	defer b.Run()
+
+	b.AddRule("S3PublicAccess").
+		WithInput(`
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+blocked(b) {
+	b.attributes.public_access_block.block_public_acls
+	b.attributes.public_access_block.block_public_policy
+}
+
+findings[f] {
+	b := input.buckets[_]
+	blocked(b)
+	f := dd.passed_finding(b.type, b.id, {"name": b.attributes.name})
+}
+
+findings[f] {
+	b := input.buckets[_]
+	not blocked(b)
+	f := dd.failing_finding(b.type, b.id, {"name": b.attributes.name})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::public", evt.ResourceID)
+			assert.Equal(t, "public", evt.Data.(event.Data)["name"])
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::private", evt.ResourceID)
+			assert.Equal(t, "aws_s3_bucket", evt.ResourceType)
+		})
+
+	b.AddRule("NoResource").
+		WithInput(`
+- cloud:
+		type: aws_ec2_instance
+	type: array
+	tag: instances
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.instances
+	f := dd.passed_finding("foo", "bar", {})
+}
+`).
+		AssertNoEvent()
+}
+
+func TestResourceFilter(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::foo"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::bar"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::baz"},
+		).
+		WithResourceFilter(func(resourceType, resourceID string) bool {
+			return resourceID != "arn:aws:s3:::bar"
+		})
+	defer b.Run()
+
+	b.AddRule("Filtered").
+		WithInput(`
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	b := input.buckets[_]
+	f := dd.passed_finding(b.type, b.id, {})
+}
+`).
+		AssertPassedEventWithResource("aws_s3_bucket", "arn:aws:s3:::baz", nil).
+		AssertPassedEventWithResource("aws_s3_bucket", "arn:aws:s3:::foo", nil)
+}
+
+func TestUnorderedEvents(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::foo"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::bar"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::baz"},
+		)
+	defer b.Run()
+
+	b.AddRule("Unordered").
+		WithInput(`
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	b := input.buckets[_]
+	f := dd.passed_finding(b.type, b.id, {})
+}
+`).
+		AssertUnorderedEvents().
+		AssertPassedEvent(nil).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::foo", evt.ResourceID)
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::bar", evt.ResourceID)
+		})
+}
+
+func TestEventCount(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::foo"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::bar"},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::baz"},
+		)
+	defer b.Run()
+
+	const input = `
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	b := input.buckets[_]
+	f := dd.failing_finding(b.type, b.id, {})
+}
+`
+
+	b.AddRule("AtLeastOne").
+		WithInput(input).
+		WithRego(rego).
+		AssertEventCount(1, -1).
+		AssertFailedEvent(nil)
+
+	b.AddRule("Bounded").
+		WithInput(input).
+		WithRego(rego).
+		AssertEventCount(2, 3).
+		AssertUnorderedEvents().
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::foo", evt.ResourceID)
+		})
+
+	b.AddRule("CountOnly").
+		WithInput(input).
+		WithRego(rego).
+		AssertEventCount(3, 3)
+}
+
+func TestOmittedFields(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{
+				Type: "aws_s3_bucket",
+				ID:   "arn:aws:s3:::partial",
+				Attributes: map[string]interface{}{
+					"name": "partial",
+					"public_access_block": map[string]interface{}{
+						"block_public_acls":   true,
+						"block_public_policy": true,
+					},
+				},
+			},
+		).
+		WithOmittedFields("public_access_block.block_public_policy")
+	defer b.Run()
+
+	b.AddRule("MissingOptionalField").
+		WithInput(`
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+blocked(b) {
+	b.attributes.public_access_block.block_public_acls
+	b.attributes.public_access_block.block_public_policy
+}
+
+findings[f] {
+	b := input.buckets[_]
+	blocked(b)
+	f := dd.passed_finding(b.type, b.id, {"name": b.attributes.name})
+}
+
+findings[f] {
+	b := input.buckets[_]
+	not blocked(b)
+	f := dd.failing_finding(b.type, b.id, {"name": b.attributes.name})
+}
+`).
+		AssertNoErrorEvent()
+}
+
+func TestOmitFields(t *testing.T) {
+	attributes := map[string]interface{}{
+		"name": "foo",
+		"nested": map[string]interface{}{
+			"a": 1,
+			"b": 2,
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"nested": map[string]interface{}{"b": 2},
+	}, omitFields(attributes, []string{"name", "nested.a", "unknown.c"}))
+	assert.Equal(t, "foo", attributes["name"], "source attributes must not be modified")
+	assert.Nil(t, omitFields(nil, []string{"name"}))
+}
+
+func TestExceptions(t *testing.T) {
+	b := NewTestBench(t).
+		WithCloudResources(
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::accepted", Attributes: map[string]interface{}{"public": true}},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::private", Attributes: map[string]interface{}{"public": false}},
+			&env.CloudResource{Type: "aws_s3_bucket", ID: "arn:aws:s3:::public", Attributes: map[string]interface{}{"public": true}},
+		).
+		WithExceptions(
+			compliance.Exception{RuleID: "S3Private", ResourceID: "arn:aws:s3:::accepted", Justification: "static website"},
+			compliance.Exception{RuleID: "S3Private", ResourceID: "arn:aws:s3:::private", Justification: "not needed"},
+			compliance.Exception{RuleID: "OtherRule", ResourceID: "arn:aws:s3:::public", Justification: "other rule"},
+		)
+	defer b.Run()
+
+	b.AddRule("S3Private").
+		WithInput(`
+- cloud:
+		type: aws_s3_bucket
+	type: array
+	tag: buckets
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	b := input.buckets[_]
+	b.attributes.public
+	f := dd.failing_finding(b.type, b.id, {})
+}
+
+findings[f] {
+	b := input.buckets[_]
+	not b.attributes.public
+	f := dd.passed_finding(b.type, b.id, {})
+}
+`).
+		AssertExceptionEvent("arn:aws:s3:::accepted", "static website").
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::public", evt.ResourceID)
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "arn:aws:s3:::private", evt.ResourceID)
+		})
+}
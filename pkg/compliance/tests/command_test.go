@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/command"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandEnv(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	const input = `
+- command:
+		shell:
+			run: echo "${DD_COMPLIANCE_TEST_SITE:-unset}"
+	type: object
+	tag: site
+`
+	const rego = `
+package datadog
+import data.datadog as dd
+
+site := trim_space(input.site.stdout)
+
+findings[f] {
+	site == "datadoghq.eu"
+	f := dd.passed_finding("site", "site", {"site": site})
+}
+
+findings[f] {
+	site != "datadoghq.eu"
+	f := dd.failing_finding("site", "site", {"site": site})
+}
+`
+
+	b.AddRule("SiteSet").
+		WithEnv(map[string]string{"DD_COMPLIANCE_TEST_SITE": "datadoghq.eu"}).
+		WithInput(input).
+		WithRego(rego).
+		AssertPassedEvent(nil)
+
+	b.AddRule("SiteRestored").
+		WithInput(input).
+		WithRego(rego).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "unset", evt.Data.(event.Data)["site"])
+		})
+}
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/directory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectoryEntries(t *testing.T) {
+	b := NewTestBench(t).
+		WithDirectoryEntries(
+			&env.DirectoryEntry{
+				DN: "cn=default,ou=policies,dc=example,dc=com",
+				Attributes: map[string][]string{
+					"objectClass":  {"pwdPolicy"},
+					"pwdMinLength": {"14"},
+				},
+			},
+			&env.DirectoryEntry{
+				DN: "cn=legacy,ou=policies,dc=example,dc=com",
+				Attributes: map[string][]string{
+					"objectClass":  {"pwdPolicy"},
+					"pwdMinLength": {"6"},
+				},
+			},
+			&env.DirectoryEntry{
+				DN: "cn=admins,ou=groups,dc=example,dc=com",
+				Attributes: map[string][]string{
+					"objectClass": {"groupOfNames"},
+					"cn":          {"admins"},
+					"member":      {"uid=alice,ou=people,dc=example,dc=com", "uid=guest,ou=people,dc=example,dc=com"},
+				},
+			},
+		)
+	defer b.Run()
+
+	b.AddRule("PasswordMinLength").
+		WithInput(`
+- directory:
+		baseDN: ou=policies,dc=example,dc=com
+		filter: (objectClass=pwdPolicy)
+	type: array
+	tag: policies
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+compliant(p) {
+	to_number(p.attributes.pwdMinLength[0]) >= 14
+}
+
+findings[f] {
+	p := input.policies[_]
+	compliant(p)
+	f := dd.passed_finding("ldap_password_policy", p.dn, {})
+}
+
+findings[f] {
+	p := input.policies[_]
+	not compliant(p)
+	f := dd.failing_finding("ldap_password_policy", p.dn, {"min_length": p.attributes.pwdMinLength[0]})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "cn=legacy,ou=policies,dc=example,dc=com", evt.ResourceID)
+			assert.Equal(t, "6", evt.Data.(event.Data)["min_length"])
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "cn=default,ou=policies,dc=example,dc=com", evt.ResourceID)
+		})
+
+	b.AddRule("AdminsMembership").
+		WithInput(`
+- directory:
+		baseDN: ou=groups,dc=example,dc=com
+		filter: (cn=admins)
+	type: object
+	tag: admins
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	m := input.admins.attributes.member[_]
+	startswith(m, "uid=guest,")
+	f := dd.failing_finding("ldap_group", input.admins.dn, {"member": m})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "uid=guest,ou=people,dc=example,dc=com", evt.Data.(event.Data)["member"])
+		})
+}
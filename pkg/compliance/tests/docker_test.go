@@ -63,7 +63,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, e *event.Event) {
+		AssertPassedEvent(func(t eventT, e *event.Event) {
 			assert.Equal(t, "TaggedInfos", e.AgentRuleID)
 			assert.Equal(t, "my_resource_type", e.ResourceType)
 			assert.Equal(t, "my_resource_id", e.ResourceID)
@@ -101,7 +101,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "valid_context", evt.ResourceType)
 			assert.Equal(t, "valid_context_id", evt.ResourceID)
 			assert.Equal(t, event.Data{"foo": "bar"}, evt.Data)
@@ -154,7 +154,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.NotEmpty(t, evt.Data.(event.Data)["ids"])
 		})
 
@@ -9,8 +9,12 @@
 package tests
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/file"
@@ -48,7 +52,7 @@ findings[f] {
 	)
 }
 `).
-		AssertFailedEvent(func(t *testing.T, evt *event.Event) {
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "my_resource_id", evt.ResourceID)
 			assert.Equal(t, "my_resource_type", evt.ResourceType)
 			assert.Equal(t, "bar", evt.Data.(event.Data)["foo"])
@@ -90,7 +94,7 @@ findings[f] {
 	)
 }
 `, tmpFile).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "the_resource_id", evt.ResourceID)
 			assert.Equal(t, "the_resource_type", evt.ResourceType)
 			assert.Equal(t, "", evt.Data.(event.Data)["content"])
@@ -119,7 +123,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "foobar", evt.Data.(event.Data)["content"])
 		})
 
@@ -150,7 +154,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "plop_type", evt.ResourceType)
 			assert.Equal(t, "plop_id", evt.ResourceID)
 		})
@@ -194,7 +198,7 @@ findings[f] {
 	)
 }
 `, tmpFileJSON).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "foo", evt.ResourceType)
 			assert.Equal(t, "bar", evt.ResourceID)
 		})
@@ -226,7 +230,7 @@ findings[f] {
 	)
 }
 `, tmpFileYAML).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "foo", evt.ResourceType)
 			assert.Equal(t, "bar", evt.ResourceID)
 		})
@@ -327,3 +331,236 @@ findings[f] {
 		AssertPassedEvent(nil).
 		AssertPassedEvent(nil)
 }
+
+func TestFileContentMatch(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	sshdConfig := filepath.Join("etc", "ssh", "sshd_config")
+	sshdConfigPath := filepath.Join(b.rootDir, sshdConfig)
+
+	b.AddRule("PermitRootLogin").
+		WithInput(`
+- file:
+		path: %s
+		parser: raw
+`, sshdConfigPath).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+compliant(f) {
+	regex.match("(?m)^PermitRootLogin\\s+no$", f.content)
+}
+
+findings[f] {
+	compliant(input.file)
+	f := dd.passed_finding("sshd_config", input.file.path, {})
+}
+
+findings[f] {
+	not compliant(input.file)
+	f := dd.failing_finding("sshd_config", input.file.path, {})
+}
+`).
+		AssertFileContentMatch(sshdConfig,
+			"Port 22\nPermitRootLogin no\n",
+			"Port 22\nPermitRootLogin yes\n")
+
+	b.AddRule("PermitRootLoginFixtures").
+		WithInput(`
+- file:
+		path: %s
+		parser: raw
+`, sshdConfigPath).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	regex.match("(?m)^PermitRootLogin\\s+no$", input.file.content)
+	f := dd.passed_finding("sshd_config", input.file.path, {})
+}
+`).
+		WithFileFixture("commented", sshdConfig, "#PermitRootLogin no\n", func(r *assertedRule) {
+			r.AssertNoEvent()
+		}).
+		WithFileFixture("set", sshdConfig, "PermitRootLogin  no\n", func(r *assertedRule) {
+			r.AssertPassedEvent(func(t eventT, evt *event.Event) {
+				assert.Equal(t, sshdConfigPath, evt.ResourceID)
+			})
+		})
+
+	for _, path := range []string{sshdConfigPath, filepath.Join("..", "sshd_config")} {
+		p := probeRule(func(r *assertedRule) {
+			r.WithFileFixture("outside", path, "", func(*assertedRule) {})
+		})
+		assert.True(t, p.failed)
+		assert.Equal(t, []string{fmt.Sprintf(`rule "Probe": file fixture %q is not relative to the bench root`, path)}, p.messages)
+	}
+}
+
+func TestFileFindingLine(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	sshdConfig := filepath.Join("etc", "ssh", "sshd_config")
+	sshdConfigPath := filepath.Join(b.rootDir, sshdConfig)
+
+	b.AddRule("PermitRootLoginLine").
+		WithInput(`
+- file:
+		path: %s
+		parser: raw
+`, sshdConfigPath).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	some i
+	regex.match("^PermitRootLogin\\s+yes$", input.file.lines[i])
+	f := dd.failing_finding("sshd_config", input.file.path, {"line": i + 1})
+}
+`).
+		WithFileFixture("lf", sshdConfig, "Port 22\n\nPermitRootLogin yes", func(r *assertedRule) {
+			r.AssertFindingLine(3)
+		}).
+		WithFileFixture("crlf", sshdConfig, "Port 22\r\nPermitRootLogin yes\r\n", func(r *assertedRule) {
+			r.AssertFindingLine(2)
+		}).
+		WithFileFixture("trailing", sshdConfig, "PermitRootLogin yes\n\n\n", func(r *assertedRule) {
+			r.AssertFindingLine(1)
+		}).
+		WithFileFixture("compliant", sshdConfig, "PermitRootLogin no\n", func(r *assertedRule) {
+			r.AssertNoEvent()
+		})
+}
+
+func TestFileExpiryTimezones(t *testing.T) {
+	b := NewTestBench(t).
+		WithClock(time.Date(2026, 12, 10, 0, 0, 0, 0, time.UTC))
+	defer b.Run()
+
+	expiry := b.WriteTempFile(t, "2026-12-31 00:00:00")
+
+	b.AddRule("CertExpiry").
+		WithInput(`
+- file:
+		path: %s
+		parser: raw
+`, expiry).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+days_left = d {
+	expires := time.parse_ns("2006-01-02 15:04:05", trim_space(input.file.content))
+	d := floor((expires - time.now_ns()) / (24 * 3600 * 1000000000))
+}
+
+findings[f] {
+	days_left < 30
+	f := dd.failing_finding("certificate", input.file.path, {"days_left": days_left})
+}
+
+findings[f] {
+	days_left >= 30
+	f := dd.passed_finding("certificate", input.file.path, {"days_left": days_left})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "21", fmt.Sprint(evt.Data.(event.Data)["days_left"]))
+		}).
+		AssertStableAcrossTimezones("Asia/Tokyo", "America/Los_Angeles", "Pacific/Kiritimati")
+}
+
+func TestFileMultipleInputs(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	config := b.WriteTempFile(t, "max_connections: 200\n")
+
+	const rego = `
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.config.content.max_connections > input.limits.max_connections
+	f := dd.failing_finding("config", input.config.path, {"limit": input.limits.max_connections})
+}
+
+findings[f] {
+	input.config.content.max_connections <= input.limits.max_connections
+	f := dd.passed_finding("config", input.config.path, {"limit": input.limits.max_connections})
+}
+`
+
+	b.AddRule("TooManyConnections").
+		AddInput("config", fmt.Sprintf(`
+file:
+	path: %s
+	parser: yaml
+`, config)).
+		AddInput("limits", `
+constants:
+	max_connections: 100
+`).
+		WithRego(rego).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, config, evt.ResourceID)
+			assert.Equal(t, "100", fmt.Sprint(evt.Data.(event.Data)["limit"]))
+		})
+
+	b.AddRule("WithInputFirst").
+		WithInput(`
+- file:
+		path: %s
+		parser: yaml
+	tag: config
+`, config).
+		AddInput("limits", `
+constants:
+	max_connections: 500
+`).
+		WithRego(rego).
+		AssertPassedEventWithResource("config", config, nil)
+}
+
+func TestFileSetupOrdered(t *testing.T) {
+	b := NewTestBench(t)
+	defer b.Run()
+
+	path := filepath.Join(b.rootDir, "created", "by-setup.conf")
+	b.AddRule("FileCreatedBySetup").
+		SetupOrdered(func(t *testing.T, ctx context.Context) {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("created"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, func(t *testing.T, events []*event.Event) {
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, "created", string(content))
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, "created", events[0].Data.(event.Data)["content"])
+			}
+		}).
+		WithInput(`
+- file:
+		path: %s
+		parser: raw
+	tag: thefile
+`, path).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	f := dd.passed_finding("file", input.thefile.path, {"content": input.thefile.content})
+}
+`).
+		AssertPassedEvent(nil)
+}
@@ -7,50 +7,212 @@ package tests
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"text/tabwriter"
 	"text/template"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/agent"
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	"github.com/DataDog/datadog-agent/pkg/compliance/rego"
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 	"k8s.io/client-go/dynamic"
 )
 
+// eventT is the part of testing.T used by the event assertions. It lets the
+// unordered assertions be tried against events without failing the test.
+type eventT interface {
+	Errorf(format string, args ...any)
+	FailNow()
+	Logf(format string, args ...any)
+}
+
+// yamlLineRe matches the line numbers in the errors of the YAML decoder
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// regoCompileErrorRe matches the codes of the errors raised while compiling rego
+var regoCompileErrorRe = regexp.MustCompile(`rego_(parse|compile|type|unsafe_var|recursion)_error`)
+
 type suite struct {
-	t        *testing.T
-	hostname string
-	rootDir  string
+	t            *testing.T
+	hostname     string
+	hostnameFunc func() (string, error)
+	rootDir      string
+
+	dockerClient  env.DockerClient
+	auditClient   env.AuditClient
+	kubeClient    dynamic.Interface
+	processClient env.ProcessClient
+
+	cloudResources []*env.CloudResource
+	omittedFields  []string
+
+	directoryEntries []*env.DirectoryEntry
+
+	fileSystem fs.FS
+
+	suiteMatcher   checks.SuiteMatcher
+	resourceFilter env.ResourceFilter
+	maxInputBytes  int
+
+	hostProfiles map[string][]checks.BuilderOption
+
+	now        time.Time
+	exceptions []compliance.Exception
+
+	tagFilter []string
+
+	stableEventOrder bool
+
+	// defaultAsserts are run against every event of every rule, see
+	// WithDefaultAssert
+	defaultAsserts []func(eventT, *event.Event)
 
-	dockerClient env.DockerClient
-	auditClient  env.AuditClient
-	kubeClient   dynamic.Interface
+	// regoPrints captures the output of the rego print calls, when set by
+	// WithRegoPrintCapture
+	regoPrints *regoPrintCapture
+
+	// parallel runs the rules in parallel, but the ones using the injected
+	// clients unless concurrentClients is set
+	parallel          bool
+	concurrentClients bool
 
 	rules []*assertedRule
 }
 
+// builderT is the part of testing.T the builders of a rule use to fail the
+// test on invalid arguments
+type builderT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
 type assertedRule struct {
+	// t is the test the rule was added to, failed by the builders
+	t        builderT
 	rootDir  string
 	hostname string
 	name     string
 	input    string
 	rego     string
-	scope    string
+	scopes   []string
+
+	// inputs are the entries added by AddInput, rendered after input
+	inputs []string
+
+	// regoTemplate is the rego before its rendering, kept to render it again
+	// with the hostname resolved when running the rule
+	regoTemplate string
+	regoVars     map[string]any
+
+	// regoGzip is the gzipped rego written along the suite, when set by
+	// WithRegoGzip
+	regoGzip []byte
+
+	tags []string
+
+	setups []func(*testing.T, context.Context)
+	events []*event.Event
+
+	// afterEvals are the checks run once the rule has been evaluated, see
+	// AfterEval
+	afterEvals []func(*testing.T, []*event.Event)
+
+	ruleAsserts
+
+	rawReports []rawReport
+
+	unordered bool
+	hermetic  bool
+
+	disallowedBuiltins []string
+
+	variants []*ruleVariant
+
+	countsPerProfile map[string]int
+
+	suiteInterval string
 
-	setups  []func(*testing.T, context.Context)
-	asserts []func(*testing.T, *event.Event)
-	events  []*event.Event
+	now time.Time
 
-	noEvent   bool
-	expectErr bool
+	timeout time.Duration
+
+	// abandoned is set once the checks of the rule exceeded its timeout, for
+	// their events to be dropped, see runChecks. It is guarded by reportMu.
+	abandoned bool
+	reportMu  sync.Mutex
+
+	// env holds the environment variables set while running the rule
+	env map[string]string
+
+	// defaultAsserts are the assertions of the suite run against every event
+	// of the rule, set when the suite runs
+	defaultAsserts []func(eventT, *event.Event)
+}
+
+// ruleAsserts holds what is expected from running a rule, as opposed to what
+// the rule runs, so that a variant keeps its own assertions when it inherits
+// the rest of its rule, see variantRule.
+type ruleAsserts struct {
+	asserts []func(eventT, *event.Event)
+
+	// stream checks the events as they are reported instead of collecting
+	// them in events, when set by AssertEventStream
+	stream *eventStream
+
+	// assertKinds holds the result expected by each assertion, reported when
+	// the events do not match them
+	assertKinds []string
+
+	rawAsserts  []func(t eventT, content []byte, service string, tags []string)
+	disallowRaw bool
+
+	noEvent        bool
+	expectErr      bool
+	expectErrMatch string
+
+	// bounds of the number of events, when set by AssertEventCount
+	countSet           bool
+	countMin, countMax int
+
+	expectedInterval time.Duration
+	timezones        []string
+}
+
+type rawReport struct {
+	content []byte
+	service string
+	tags    []string
+}
+
+type ruleVariant struct {
+	name string
+	rule *assertedRule
+
+	// hostname the variant runs with, when set by ForHostnames
+	hostname string
+
+	// fileFixture is set for the variants of WithFileFixture, which write a
+	// file of the bench root shared by the rules
+	fileFixture bool
 }
 
 func NewTestBench(t *testing.T) *suite {
@@ -66,6 +228,14 @@ func (s *suite) WithHostname(hostname string) *suite {
 	return s
 }
 
+// WithHostnameFunc resolves the hostname with f, called once per rule run,
+// instead of using a fixed hostname. A resolution error fails the rule unless
+// it expects an error with AssertError.
+func (s *suite) WithHostnameFunc(f func() (string, error)) *suite {
+	s.hostnameFunc = f
+	return s
+}
+
 func (s *suite) WithDockerClient(cl env.DockerClient) *suite {
 	s.dockerClient = cl
 	return s
@@ -81,6 +251,146 @@ func (s *suite) WithKubeClient(cl dynamic.Interface) *suite {
 	return s
 }
 
+func (s *suite) WithProcessClient(cl env.ProcessClient) *suite {
+	s.processClient = cl
+	return s
+}
+
+func (s *suite) WithCloudResources(resources ...*env.CloudResource) *suite {
+	s.cloudResources = append(s.cloudResources, resources...)
+	return s
+}
+
+// WithOmittedFields removes the given attributes from the cloud resources
+// returned to the rules, as a resolver lacking permissions would. Nested
+// attributes are separated by dots.
+func (s *suite) WithOmittedFields(fields ...string) *suite {
+	s.omittedFields = append(s.omittedFields, fields...)
+	return s
+}
+
+// WithDirectoryEntries seeds the directory searched by the rules with the
+// given entries.
+func (s *suite) WithDirectoryEntries(entries ...*env.DirectoryEntry) *suite {
+	s.directoryEntries = append(s.directoryEntries, entries...)
+	return s
+}
+
+func (s *suite) WithSuiteMatcher(matcher checks.SuiteMatcher) *suite {
+	s.suiteMatcher = matcher
+	return s
+}
+
+func (s *suite) WithResourceFilter(filter env.ResourceFilter) *suite {
+	s.resourceFilter = filter
+	return s
+}
+
+func (s *suite) WithMaxInputBytes(max int) *suite {
+	s.maxInputBytes = max
+	return s
+}
+
+// WithHostProfile declares a host profile: the builder options describing a
+// host, such as its docker client, added to the suite ones when a rule runs
+// under this profile. See AssertEventCountsPerProfile.
+func (s *suite) WithHostProfile(name string, options ...checks.BuilderOption) *suite {
+	if s.hostProfiles == nil {
+		s.hostProfiles = make(map[string][]checks.BuilderOption)
+	}
+	s.hostProfiles[name] = options
+	return s
+}
+
+// WithExceptions accepts the failures of rules on the given resources.
+func (s *suite) WithExceptions(exceptions ...compliance.Exception) *suite {
+	s.exceptions = append(s.exceptions, exceptions...)
+	return s
+}
+
+// WithClock sets the current time seen by the rego evaluations.
+func (s *suite) WithClock(now time.Time) *suite {
+	s.now = now
+	return s
+}
+
+// Parallel runs the rules of the suite in parallel. The rules are still run
+// one at a time when the suite has docker, kubernetes, audit or process
+// clients, unless WithConcurrentClients is used, and the rules checking
+// timezones or setting environment variables are always run alone.
+func (s *suite) Parallel() *suite {
+	s.parallel = true
+	return s
+}
+
+// WithConcurrentClients asserts the clients given to the suite can be used
+// by rules running in parallel.
+func (s *suite) WithConcurrentClients() *suite {
+	s.concurrentClients = true
+	return s
+}
+
+// WithStableEventOrder makes the checks report the events of a run sorted by
+// rule ID and then resource ID, for the assertions to match them in this
+// order. See checks.WithStableEventOrder.
+func (s *suite) WithStableEventOrder() *suite {
+	s.stableEventOrder = true
+	return s
+}
+
+// WithDefaultAssert adds an assertion run against every event of every rule,
+// in addition to the assertions of the rule. It is not run for the rules
+// expecting no event or an error.
+func (s *suite) WithDefaultAssert(f func(t eventT, evt *event.Event)) *suite {
+	s.defaultAsserts = append(s.defaultAsserts, f)
+	return s
+}
+
+// WithTagFilter only runs the rules having at least one of the given tags
+func (s *suite) WithTagFilter(tags ...string) *suite {
+	s.tagFilter = append(s.tagFilter, tags...)
+	return s
+}
+
+// WithRegoPrintCapture captures the output of the print calls of the rego of
+// the rules, and logs it when a rule fails.
+func (s *suite) WithRegoPrintCapture() *suite {
+	s.regoPrints = &regoPrintCapture{outputs: make(map[string][]string)}
+	return s
+}
+
+// regoPrintCapture holds the output of the rego print calls by rule ID
+type regoPrintCapture struct {
+	sync.Mutex
+	outputs map[string][]string
+}
+
+func (p *regoPrintCapture) record(ruleID, output string) {
+	p.Lock()
+	defer p.Unlock()
+	p.outputs[ruleID] = append(p.outputs[ruleID], output)
+}
+
+// logOnFailure logs the print output of the rule at the end of t, when it
+// failed
+func (p *regoPrintCapture) logOnFailure(t *testing.T, ruleID string) {
+	if p == nil {
+		return
+	}
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		p.Lock()
+		defer p.Unlock()
+		if len(p.outputs[ruleID]) == 0 {
+			t.Logf("rule %q printed nothing", ruleID)
+			return
+		}
+		t.Logf("rule %q printed:\n%s", ruleID, strings.Join(p.outputs[ruleID], "\n"))
+	})
+}
+
 func (s *suite) AddRule(name string) *assertedRule {
 	for _, rule := range s.rules {
 		if rule.name == name {
@@ -88,6 +398,7 @@ func (s *suite) AddRule(name string) *assertedRule {
 		}
 	}
 	rule := &assertedRule{
+		t:        s.t,
 		name:     name,
 		rootDir:  s.rootDir,
 		hostname: s.hostname,
@@ -100,24 +411,291 @@ func (s *suite) Run() {
 	if len(s.rules) == 0 {
 		s.t.Fatal("no rule to run")
 	}
+	if err := s.Validate(); err != nil {
+		s.t.Fatal(err)
+	}
 	for _, c := range s.rules {
+		c := c
 		s.t.Run(c.name, func(t *testing.T) {
-			var options []checks.BuilderOption
-			options = append(options, checks.WithHostname(s.hostname))
-			if s.auditClient != nil {
-				options = append(options, checks.WithAuditClient(s.auditClient))
+			if !s.selects(c) {
+				t.Skipf("rule tags %v do not match the filter %v", c.tags, s.tagFilter)
+			}
+			s.regoPrints.logOnFailure(t, c.name)
+			hostname, err := s.resolveHostname()
+			if err != nil {
+				if c.expectErr {
+					c.checkError(t, err)
+					return
+				}
+				t.Fatalf("could not resolve hostname: %v", err)
+			}
+			if s.hostnameFunc != nil {
+				if err := c.setHostname(hostname); err != nil {
+					t.Fatal(err)
+				}
 			}
-			if s.dockerClient != nil {
-				options = append(options, checks.WithDockerClient(s.dockerClient))
+			options := s.options(hostname)
+			c.now = s.now
+			c.defaultAsserts = s.defaultAsserts
+			if s.runsInParallel(c) {
+				t.Parallel()
+			}
+			if len(c.countsPerProfile) > 0 {
+				c.runProfiles(t, options, s.hostProfiles)
+			} else {
+				c.run(t, options)
+			}
+		})
+	}
+}
+
+// RunAsSingleSuite runs all the rules from a single suite, checked in one
+// run, as in production. The events are dispatched to the rules by rule ID
+// before being asserted. The environment variables of the rules are set for
+// the whole run, so two rules cannot set the same variable to different
+// values.
+func (s *suite) RunAsSingleSuite() {
+	if len(s.rules) == 0 {
+		s.t.Fatal("no rule to run")
+	}
+	if err := s.Validate(); err != nil {
+		s.t.Fatal(err)
+	}
+
+	hostname, err := s.resolveHostname()
+	if err != nil {
+		s.t.Fatalf("could not resolve hostname: %v", err)
+	}
+	options := s.options(hostname)
+	router := &ruleRouter{t: s.t, rules: make(map[string]*assertedRule, len(s.rules))}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var rules []*assertedRule
+	for _, c := range s.rules {
+		if s.selects(c) {
+			rules = append(rules, c)
+		}
+	}
+	if len(rules) == 0 {
+		s.t.Skipf("no rule matches the filter %v", s.tagFilter)
+	}
+	env, err := singleSuiteEnv(rules)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	for k, v := range env {
+		s.t.Setenv(k, v)
+	}
+
+	for _, c := range rules {
+		if len(c.variants) > 0 || len(c.countsPerProfile) > 0 || c.expectedInterval > 0 || len(c.timezones) > 0 || c.expectErr || c.timeout > 0 || c.suiteInterval != "" || c.hermetic {
+			s.t.Fatalf("rule %q: variants, host profiles, intervals, timezones, timeouts, expected errors and hermetic evaluation are not supported in a single suite", c.name)
+		}
+		for _, setup := range c.setups {
+			setup(s.t, ctx)
+		}
+		if s.hostnameFunc != nil {
+			if err := c.setHostname(hostname); err != nil {
+				s.t.Fatal(err)
 			}
-			if s.kubeClient != nil {
-				options = append(options, checks.WithKubernetesClient(s.kubeClient, ""))
+		}
+		c.writeRego(s.t, c.name)
+		c.defaultAsserts = s.defaultAsserts
+		router.rules[c.name] = c
+	}
+
+	file := filepath.Join(s.rootDir, "SingleSuite.yaml")
+	suiteData := buildSuite("SingleSuite", "", rules...)
+	if diagnosis := diagnoseSuite(suiteData); diagnosis != "" {
+		s.t.Log(diagnosis)
+	}
+	if err := os.WriteFile(file, []byte(suiteData), 0o644); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := agent.RunChecksFromFile(router, file, options...); err != nil {
+		s.t.Fatal(err)
+	}
+
+	for _, c := range rules {
+		s.t.Run(c.name, func(t *testing.T) {
+			s.regoPrints.logOnFailure(t, c.name)
+			if len(c.disallowedBuiltins) > 0 {
+				c.checkBuiltins(t)
 			}
-			c.run(t, options)
+			c.runAfterEvals(t)
+			c.assertEvents(t)
 		})
 	}
 }
 
+// singleSuiteEnv merges the environment variables of the rules, set for the
+// whole run of a single suite. It fails when two rules set the same variable
+// to different values.
+func singleSuiteEnv(rules []*assertedRule) (map[string]string, error) {
+	env := make(map[string]string)
+	setBy := make(map[string]string)
+	for _, c := range rules {
+		for k, v := range c.env {
+			if other, ok := setBy[k]; ok && env[k] != v {
+				return nil, fmt.Errorf("rule %q sets %s=%q but rule %q sets it to %q: the rules of a single suite share their environment variables", c.name, k, v, other, env[k])
+			}
+			env[k] = v
+			setBy[k] = c.name
+		}
+	}
+	return env, nil
+}
+
+// DumpGenerated writes the suite and the rego generated for each rule to w,
+// without running them. Setting COMPLIANCE_TEST_DUMP=1 logs them as well
+// when the rules are run.
+func (s *suite) DumpGenerated(w io.Writer) {
+	for _, c := range s.rules {
+		if len(c.variants) == 0 {
+			suiteName := strings.ReplaceAll(c.name, string(os.PathSeparator), "")
+			dumpGenerated(w, c.name, suiteName, buildSuite(suiteName, c.suiteInterval, c), c.rego)
+			continue
+		}
+		for _, variant := range c.variants {
+			v, err := c.variantRule(variant, c.rootDir)
+			if err != nil {
+				s.t.Fatal(err)
+			}
+			suiteName := strings.ReplaceAll(v.name, string(os.PathSeparator), "")
+			dumpGenerated(w, c.name+"/"+variant.name, suiteName, buildSuite(suiteName, v.suiteInterval, v), v.rego)
+		}
+	}
+}
+
+func dumpGenerated(w io.Writer, label, suiteName, suiteData, rego string) {
+	fmt.Fprintf(w, "--- rule %s: %s.yaml ---\n%s\n", label, suiteName, suiteData)
+	fmt.Fprintf(w, "--- rule %s: %s.rego ---\n%s\n", label, suiteName, rego)
+}
+
+// runsInParallel returns whether the rule can run in parallel with others
+func (s *suite) runsInParallel(c *assertedRule) bool {
+	if !s.parallel || len(c.timezones) > 0 || len(c.env) > 0 {
+		return false
+	}
+	for _, variant := range c.variants {
+		if len(variant.rule.timezones) > 0 || len(variant.rule.env) > 0 || variant.fileFixture {
+			return false
+		}
+	}
+	hasClients := s.dockerClient != nil || s.kubeClient != nil || s.auditClient != nil || s.processClient != nil
+	return !hasClients || s.concurrentClients
+}
+
+// selects returns whether the rule matches the tag filter of the suite
+func (s *suite) selects(c *assertedRule) bool {
+	if len(s.tagFilter) == 0 {
+		return true
+	}
+	for _, tag := range c.tags {
+		for _, filter := range s.tagFilter {
+			if tag == filter {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveHostname returns the hostname of the suite
+func (s *suite) resolveHostname() (string, error) {
+	if s.hostnameFunc != nil {
+		return s.hostnameFunc()
+	}
+	return s.hostname, nil
+}
+
+// options returns the builder options configured on the suite
+func (s *suite) options(hostname string) []checks.BuilderOption {
+	var options []checks.BuilderOption
+	options = append(options, checks.WithHostname(hostname))
+	if s.auditClient != nil {
+		options = append(options, checks.WithAuditClient(s.auditClient))
+	}
+	if s.dockerClient != nil {
+		options = append(options, checks.WithDockerClient(s.dockerClient))
+	}
+	if s.kubeClient != nil {
+		options = append(options, checks.WithKubernetesClient(s.kubeClient, ""))
+	}
+	if s.processClient != nil {
+		options = append(options, checks.WithProcessClient(s.processClient))
+	}
+	if len(s.cloudResources) > 0 {
+		options = append(options, checks.WithCloudClient(&fakeCloudClient{
+			resources:     s.cloudResources,
+			omittedFields: s.omittedFields,
+		}))
+	}
+	if len(s.directoryEntries) > 0 {
+		options = append(options, checks.WithDirectoryClient(&fakeDirectoryClient{
+			entries: s.directoryEntries,
+		}))
+	}
+	if s.fileSystem != nil {
+		options = append(options, checks.WithFileSystem(s.fileSystem))
+	}
+	if s.resourceFilter != nil {
+		options = append(options, checks.WithResourceFilter(s.resourceFilter))
+	}
+	if s.maxInputBytes > 0 {
+		options = append(options, checks.WithMaxInputBytes(s.maxInputBytes))
+	}
+	if s.suiteMatcher != nil {
+		options = append(options, checks.WithRuntimeMatchSuite(s.suiteMatcher))
+	}
+	if len(s.exceptions) > 0 {
+		options = append(options, checks.WithExceptions(s.exceptions...))
+	}
+	if !s.now.IsZero() {
+		now := s.now
+		options = append(options, checks.WithClock(func() time.Time { return now }))
+	}
+	if s.regoPrints != nil {
+		options = append(options, checks.WithRegoPrintHook(s.regoPrints.record))
+	}
+	if s.stableEventOrder {
+		options = append(options, checks.WithStableEventOrder())
+	}
+	return options
+}
+
+// ruleRouter reports the events of a suite to the rule they were emitted for
+type ruleRouter struct {
+	t     *testing.T
+	rules map[string]*assertedRule
+}
+
+func (r *ruleRouter) Report(event *event.Event) {
+	c, ok := r.rules[event.AgentRuleID]
+	if !ok {
+		r.t.Errorf("received an event for unknown rule %q: %+v", event.AgentRuleID, event)
+		return
+	}
+	c.Report(event)
+}
+
+func (r *ruleRouter) ReportRaw(content []byte, service string, tags ...string) {
+	r.t.Errorf("raw reports cannot be dispatched to the rules of a single suite: %s", content)
+}
+
+func (s *suite) Validate() error {
+	var underSpecified []string
+	for _, rule := range s.rules {
+		if !rule.isSpecified() {
+			underSpecified = append(underSpecified, rule.name)
+		}
+	}
+	if len(underSpecified) > 0 {
+		return fmt.Errorf("rules without any assertion: %s", strings.Join(underSpecified, ", "))
+	}
+	return nil
+}
+
 func (s *suite) WriteTempFile(t *testing.T, data string) string {
 	f, err := os.CreateTemp(s.rootDir, "")
 	if err != nil {
@@ -135,6 +713,45 @@ func (c *assertedRule) Setup(setup func(t *testing.T, ctx context.Context)) *ass
 	return c
 }
 
+// SetupWithCleanup is like Setup, but setup can return a function releasing
+// the resources it created. The cleanups run once the rule events have been
+// asserted, in the reverse order of their setups. The context given to setup
+// is canceled by then.
+func (c *assertedRule) SetupWithCleanup(setup func(t *testing.T, ctx context.Context) (cleanup func())) *assertedRule {
+	return c.Setup(func(t *testing.T, ctx context.Context) {
+		if cleanup := setup(t, ctx); cleanup != nil {
+			t.Cleanup(cleanup)
+		}
+	})
+}
+
+// SetupOrdered is like Setup, with check run once the rule has been evaluated
+// to confirm the state left by setup, such as a created file, was the one the
+// rule observed. check fails the test when setup did not run before the
+// evaluation.
+func (c *assertedRule) SetupOrdered(setup func(t *testing.T, ctx context.Context), check func(t *testing.T, events []*event.Event)) *assertedRule {
+	var ran bool
+	c.Setup(func(t *testing.T, ctx context.Context) {
+		setup(t, ctx)
+		ran = true
+	})
+	return c.AfterEval(func(t *testing.T, events []*event.Event) {
+		if !ran {
+			t.Fatalf("setup did not run before the evaluation of the rule")
+		}
+		ran = false
+		check(t, events)
+	})
+}
+
+// AfterEval adds a check run with the events of the rule once it has been
+// evaluated, before they are asserted. The checks run in the order they were
+// added, after the ones of the parent rule for a variant.
+func (c *assertedRule) AfterEval(check func(t *testing.T, events []*event.Event)) *assertedRule {
+	c.afterEvals = append(c.afterEvals, check)
+	return c
+}
+
 func (c *assertedRule) WriteFile(t *testing.T, name, data string) string {
 	n := filepath.Join(c.rootDir, name)
 	f, err := os.OpenFile(n, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fs.FileMode(0o644))
@@ -148,114 +765,697 @@ func (c *assertedRule) WriteFile(t *testing.T, name, data string) string {
 	return f.Name()
 }
 
-func (c *assertedRule) WithScope(scope string) *assertedRule {
-	c.scope = scope
-	return c
+// writeRego writes the rego of the rule next to the suite of the given name
+func (c *assertedRule) writeRego(t *testing.T, suiteName string) {
+	if c.regoGzip != nil {
+		c.WriteFile(t, suiteName+".rego.gz", string(c.regoGzip))
+	} else {
+		c.WriteFile(t, suiteName+".rego", c.rego)
+	}
 }
 
-func (c *assertedRule) WithInput(input string, args ...any) *assertedRule {
-	r := regexp.MustCompile("(?m)^\\t+")
-	input = r.ReplaceAllStringFunc(input, func(p string) string { return strings.Repeat("  ", len(p)) })
-	c.input = strings.TrimSpace(fmt.Sprintf(input, args...))
+// WithTags labels the rule, to be selected by the tag filter of the suite
+func (c *assertedRule) WithTags(tags ...string) *assertedRule {
+	c.tags = append(c.tags, tags...)
 	return c
 }
 
-func (c *assertedRule) WithRego(rego string, args ...any) *assertedRule {
-	var buf bytes.Buffer
-	rego = fmt.Sprintf(rego, args...)
-	tmpl := template.Must(template.New("name").Parse(rego))
-	err := tmpl.Execute(&buf, struct {
-		Hostname string
-		RuleID   string
-	}{
-		Hostname: c.hostname,
-		RuleID:   c.name,
-	})
-	if err != nil {
-		panic(err)
+// WithEnv sets environment variables of the test process while the rule is
+// run, before its setups, restoring them once it is done as t.Setenv does. The
+// inputs run by the checks, like commands, inherit them. The rules setting
+// environment variables are never run in parallel.
+func (c *assertedRule) WithEnv(vars map[string]string) *assertedRule {
+	if c.env == nil {
+		c.env = make(map[string]string, len(vars))
+	}
+	for k, v := range vars {
+		c.env[k] = v
 	}
-	c.rego = buf.String()
 	return c
 }
 
-func (c *assertedRule) AssertPassedEvent(f func(t *testing.T, evt *event.Event)) *assertedRule {
-	c.asserts = append(c.asserts, func(t *testing.T, evt *event.Event) {
-		if assert.Equal(t, "passed", evt.Result) {
-			if f != nil {
-				f(t, evt)
-			}
-		} else {
-			t.Logf("received unexpected %q event : %v", evt.Result, evt)
-		}
-	})
+// WithTimeout fails the rule when its setups and checks take longer than d to
+// run. The context given to the setups is canceled once d expires. The checks
+// cannot be canceled: they are left running in the background, their events
+// being dropped, so a rule hitting its timeout may still access its files
+// after the test ended.
+func (c *assertedRule) WithTimeout(d time.Duration) *assertedRule {
+	c.timeout = d
 	return c
 }
 
-func (c *assertedRule) AssertFailedEvent(f func(t *testing.T, evt *event.Event)) *assertedRule {
-	c.asserts = append(c.asserts, func(t *testing.T, evt *event.Event) {
-		if assert.Equal(t, "failed", evt.Result) {
-			if f != nil {
-				f(t, evt)
-			}
-		} else {
-			t.Logf("received unexpected %q event : %v", evt.Result, evt)
-		}
-	})
+func (c *assertedRule) WithScope(scope string) *assertedRule {
+	c.scopes = []string{scope}
 	return c
 }
 
-func (c *assertedRule) AssertErrorEvent() *assertedRule {
-	c.asserts = append(c.asserts, func(t *testing.T, evt *event.Event) {
-		if assert.Equal(t, "error", evt.Result) {
-			assert.NotNil(t, evt.Data.(event.Data)["error"])
+// WithScopeExpr sets the scope list of the rule from a comma-separated list
+// of scopes, such as "docker, none". As in production, the check applies the
+// first of the docker, kubernetesNode, kubernetesCluster and none scopes
+// found in the list, and skips the rule when its environment is missing. The
+// test fails when the expression holds no scope.
+func (c *assertedRule) WithScopeExpr(expr string) *assertedRule {
+	var scopes []string
+	for _, scope := range strings.Split(expr, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
 		}
-	})
+	}
+	if len(scopes) == 0 {
+		c.t.Helper()
+		c.t.Fatalf("empty scope expression for rule %q", c.name)
+	}
+	c.scopes = scopes
 	return c
 }
 
-func (c *assertedRule) AssertNoEvent() *assertedRule {
-	c.noEvent = true
+// WithSuiteInterval sets the interval of the suite generated for the rule.
+func (c *assertedRule) WithSuiteInterval(interval string) *assertedRule {
+	c.suiteInterval = interval
 	return c
 }
 
-func (c *assertedRule) AssertError() *assertedRule {
+func (c *assertedRule) WithInput(input string, args ...any) *assertedRule {
+	c.input = fmt.Sprintf(expandTabs(input), args...)
+	return c
+}
+
+// AddInput adds an input to the rule, tagged with name so that the rego reads
+// it as input.<name>. spec is the YAML of the input entry without its tag,
+// such as "file:\n\tpath: /etc/passwd". The inputs are rendered in the order
+// they are added, after the ones set by WithInput.
+func (c *assertedRule) AddInput(name, spec string) *assertedRule {
+	entry := "- " + indent(1, expandTabs(spec))
+	if name != "" {
+		entry += "\n  tag: " + name
+	}
+	c.inputs = append(c.inputs, entry)
+	return c
+}
+
+// inputBlock returns the input block of the rule, made of the inputs set by
+// WithInput followed by the ones added by AddInput
+func (c *assertedRule) inputBlock() string {
+	var entries []string
+	if strings.TrimSpace(c.input) != "" {
+		entries = append(entries, indent(0, c.input))
+	}
+	entries = append(entries, c.inputs...)
+	return strings.Join(entries, "\n")
+}
+
+// expandTabs replaces the tabs indenting the lines of s by two spaces each,
+// as YAML does not allow tabs in indentation
+func expandTabs(s string) string {
+	r := regexp.MustCompile("(?m)^\\t+")
+	return r.ReplaceAllStringFunc(s, func(p string) string { return strings.Repeat("  ", len(p)) })
+}
+
+func (c *assertedRule) WithRego(rego string, args ...any) *assertedRule {
+	if err := c.setRegoTemplate(fmt.Sprintf(rego, args...)); err != nil {
+		c.t.Helper()
+		c.t.Fatalf("%v", err)
+	}
+	return c
+}
+
+// WithRegoFile uses the rego policy read from the file at path, relative to
+// the working directory of the test. The test fails when the file cannot be
+// read.
+func (c *assertedRule) WithRegoFile(path string) *assertedRule {
+	rego, err := os.ReadFile(path)
+	if err != nil {
+		c.t.Helper()
+		c.t.Fatalf("could not read rego of rule %q: %v", c.name, err)
+	}
+	if err := c.setRegoTemplate(string(rego)); err != nil {
+		c.t.Helper()
+		c.t.Fatalf("%v", err)
+	}
+	return c
+}
+
+// WithRegoGzip uses the gzipped rego policy data, written as a .rego.gz file
+// along the suite for the check to decompress it. The rego is not a template.
+// The test fails when data cannot be decompressed.
+func (c *assertedRule) WithRegoGzip(data []byte) *assertedRule {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		c.t.Helper()
+		c.t.Fatalf("could not decompress rego of rule %q: %v", c.name, err)
+	}
+	rego, err := io.ReadAll(r)
+	if err != nil {
+		c.t.Helper()
+		c.t.Fatalf("could not decompress rego of rule %q: %v", c.name, err)
+	}
+	c.regoTemplate = ""
+	c.rego = string(rego)
+	c.regoGzip = data
+	return c
+}
+
+// WithRegoVars adds variables to the data of the rego template, next to
+// Hostname and RuleID. It must be called before WithRego or WithRegoFile. The
+// test fails when a variable is named after a reserved one.
+func (c *assertedRule) WithRegoVars(vars map[string]any) *assertedRule {
+	if c.regoVars == nil {
+		c.regoVars = make(map[string]any, len(vars))
+	}
+	for k, v := range vars {
+		if k == "Hostname" || k == "RuleID" {
+			c.t.Helper()
+			c.t.Fatalf("rego variable %q of rule %q is reserved", k, c.name)
+		}
+		c.regoVars[k] = v
+	}
+	return c
+}
+
+// setRegoTemplate sets the rego template of the rule and renders it. It fails
+// when the template is invalid or uses a variable that is not set.
+func (c *assertedRule) setRegoTemplate(rego string) error {
+	rendered, err := c.renderRego(rego)
+	if err != nil {
+		return fmt.Errorf("could not render rego of rule %q: %w", c.name, err)
+	}
+	c.regoTemplate = rego
+	c.rego = rendered
+	return nil
+}
+
+// setHostname changes the hostname of the rule and its variants, rendering
+// their rego again
+func (c *assertedRule) setHostname(hostname string) error {
+	c.hostname = hostname
+	if c.regoTemplate != "" {
+		rego, err := c.renderRego(c.regoTemplate)
+		if err != nil {
+			return fmt.Errorf("could not render rego of rule %q: %w", c.name, err)
+		}
+		c.rego = rego
+	}
+	for _, variant := range c.variants {
+		if variant.hostname == "" {
+			if err := variant.rule.setHostname(hostname); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderRego executes rego as a template of the hostname, the rule ID and the
+// rego variables
+func (c *assertedRule) renderRego(rego string) (string, error) {
+	data := make(map[string]any, len(c.regoVars)+2)
+	for k, v := range c.regoVars {
+		data[k] = v
+	}
+	data["Hostname"] = c.hostname
+	data["RuleID"] = c.name
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(rego)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// addVariant adds a variant of the rule, run as a subtest named name, with
+// the hostname of the rule unless hostname is set. configure is called to set
+// up the variant and add its assertions.
+func (c *assertedRule) addVariant(name, hostname string, configure func(*assertedRule)) *ruleVariant {
+	v := &assertedRule{
+		t:        c.t,
+		hostname: c.hostname,
+		name:     c.name,
+	}
+	if hostname != "" {
+		v.hostname = hostname
+	}
+	configure(v)
+	variant := &ruleVariant{name: name, rule: v, hostname: hostname}
+	c.variants = append(c.variants, variant)
+	return variant
+}
+
+// WithRegoVersion runs the rule as a subtest named after the version, with
+// rego as its policy. asserts is called to add the assertions of the run.
+func (c *assertedRule) WithRegoVersion(version string, rego string, asserts func(*assertedRule)) *assertedRule {
+	c.addVariant(version, "", func(v *assertedRule) {
+		v.WithRego(rego)
+		asserts(v)
+	})
+	return c
+}
+
+// ForHostnames runs the rule once per hostname, as a subtest named after the
+// hostname, with its rego rendered again for each of them. asserts is called
+// with each hostname to add the assertions of its run.
+func (c *assertedRule) ForHostnames(asserts func(hostname string, r *assertedRule), hostnames ...string) *assertedRule {
+	for _, hostname := range hostnames {
+		c.addVariant(hostname, hostname, func(v *assertedRule) {
+			asserts(hostname, v)
+		})
+	}
+	return c
+}
+
+// WithFileFixture runs the rule as a subtest named after the fixture, with the
+// file at path, relative to the bench root, holding content. asserts is called
+// to add the assertions of the run. The rules with file fixtures do not run in
+// parallel.
+func (c *assertedRule) WithFileFixture(fixture, path, content string, asserts func(*assertedRule)) *assertedRule {
+	if clean := filepath.Clean(path); filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		c.t.Helper()
+		c.t.Fatalf("rule %q: file fixture %q is not relative to the bench root", c.name, path)
+	}
+	path = filepath.Join(c.rootDir, path)
+
+	variant := c.addVariant(fixture, "", func(v *assertedRule) {
+		v.Setup(func(t *testing.T, ctx context.Context) {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		})
+		asserts(v)
+	})
+	variant.fileFixture = true
+	return c
+}
+
+// AssertFileContentMatch runs the rule with the file at path, relative to the
+// bench root, holding matching then notMatching, expecting a passed then a
+// failed event. See WithFileFixture.
+func (c *assertedRule) AssertFileContentMatch(path, matching, notMatching string) *assertedRule {
+	c.WithFileFixture("match", path, matching, func(r *assertedRule) {
+		r.AssertPassedEvent(nil)
+	})
+	c.WithFileFixture("no_match", path, notMatching, func(r *assertedRule) {
+		r.AssertFailedEvent(nil)
+	})
+	return c
+}
+
+// notErrorKind is the kind of the assertions accepting any event but an error
+const notErrorKind = "not error"
+
+// addAssert adds an assertion on the next event of the rule, expecting an
+// event of the given kind.
+func (c *assertedRule) addAssert(kind string, f func(t eventT, evt *event.Event)) *assertedRule {
+	c.asserts = append(c.asserts, f)
+	c.assertKinds = append(c.assertKinds, kind)
+	return c
+}
+
+func (c *assertedRule) AssertPassedEvent(f func(t eventT, evt *event.Event)) *assertedRule {
+	return c.addAssert(event.Passed, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, "passed", evt.Result) {
+			if f != nil {
+				f(t, evt)
+			}
+		} else {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+		}
+	})
+}
+
+func (c *assertedRule) AssertFailedEvent(f func(t eventT, evt *event.Event)) *assertedRule {
+	return c.addAssert(event.Failed, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, "failed", evt.Result) {
+			if f != nil {
+				f(t, evt)
+			}
+		} else {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+		}
+	})
+}
+
+// failureReasonFields are the event data fields looked into by
+// AssertFailedEventContains, in order.
+var failureReasonFields = []string{"reason", "message", "error"}
+
+// AssertFailedEventContains asserts a failed event whose reason, message or
+// error data field contains substr.
+func (c *assertedRule) AssertFailedEventContains(substr string) *assertedRule {
+	return c.addAssert(event.Failed, func(t eventT, evt *event.Event) {
+		if !assert.Equal(t, "failed", evt.Result) {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+			return
+		}
+		data, _ := evt.Data.(event.Data)
+		var reasons []string
+		for _, field := range failureReasonFields {
+			v, ok := data[field]
+			if !ok {
+				continue
+			}
+			reason := fmt.Sprint(v)
+			if strings.Contains(reason, substr) {
+				return
+			}
+			reasons = append(reasons, fmt.Sprintf("%s: %q", field, reason))
+		}
+		if len(reasons) == 0 {
+			t.Errorf("expected a failed event with a reason containing %q but it has no %s field: %v", substr, strings.Join(failureReasonFields, "/"), data)
+			return
+		}
+		t.Errorf("expected a failed event with a reason containing %q but got:\n\t%s", substr, strings.Join(reasons, "\n\t"))
+	})
+}
+
+// AssertPassedEventWithResource asserts a passed event reported on the
+// resource of the given type and ID, before calling f if any.
+func (c *assertedRule) AssertPassedEventWithResource(kind, id string, f func(t eventT, evt *event.Event)) *assertedRule {
+	return c.addAssert(event.Passed, func(t eventT, evt *event.Event) {
+		if !assert.Equal(t, "passed", evt.Result) {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+			return
+		}
+		if evt.ResourceType != kind || evt.ResourceID != id {
+			t.Errorf("expected an event on resource %s %q but it was reported on %s %q", kind, id, evt.ResourceType, evt.ResourceID)
+			return
+		}
+		if f != nil {
+			f(t, evt)
+		}
+	})
+}
+
+// AssertPassedEventWithTag asserts a passed event carrying the tag, before
+// calling f if any. A tag without value, like "image_id", matches any value of
+// the tag.
+func (c *assertedRule) AssertPassedEventWithTag(tag string, f func(t eventT, evt *event.Event)) *assertedRule {
+	return c.addAssert(event.Passed, func(t eventT, evt *event.Event) {
+		if !assert.Equal(t, "passed", evt.Result) {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+			return
+		}
+		if !hasTag(evt.Tags, tag) {
+			t.Errorf("expected an event with tag %q but it has tags %v", tag, evt.Tags)
+			return
+		}
+		if f != nil {
+			f(t, evt)
+		}
+	})
+}
+
+// AssertPassedEventWithoutTag asserts a passed event not carrying the tag,
+// before calling f if any. A tag without value, like "image_id", matches any
+// value of the tag.
+func (c *assertedRule) AssertPassedEventWithoutTag(tag string, f func(t eventT, evt *event.Event)) *assertedRule {
+	return c.addAssert(event.Passed, func(t eventT, evt *event.Event) {
+		if !assert.Equal(t, "passed", evt.Result) {
+			t.Logf("received unexpected %q event : %v", evt.Result, evt)
+			return
+		}
+		if hasTag(evt.Tags, tag) {
+			t.Errorf("expected an event without tag %q but it has tags %v", tag, evt.Tags)
+			return
+		}
+		if f != nil {
+			f(t, evt)
+		}
+	})
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag || (!strings.Contains(tag, ":") && strings.HasPrefix(t, tag+":")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertExceptionEvent asserts an exception event on the resource, carrying
+// the justification of the exception.
+func (c *assertedRule) AssertExceptionEvent(resourceID, justification string) *assertedRule {
+	return c.addAssert(event.Exception, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, event.Exception, evt.Result) {
+			assert.Equal(t, resourceID, evt.ResourceID)
+			assert.Equal(t, justification, evt.Data.(event.Data)["exception_justification"])
+		}
+	})
+}
+
+func (c *assertedRule) AssertErrorEvent() *assertedRule {
+	return c.addAssert(event.Error, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, "error", evt.Result) {
+			assert.NotNil(t, evt.Data.(event.Data)["error"])
+		}
+	})
+}
+
+// AssertSingleErrorEvent asserts the rule emits exactly one event, an error
+// event whose cause contains causeSubstr.
+func (c *assertedRule) AssertSingleErrorEvent(causeSubstr string) *assertedRule {
+	c.AssertEventCount(1, 1)
+	return c.addAssert(event.Error, func(t eventT, evt *event.Event) {
+		cause := fmt.Sprint(evt.Data.(event.Data)["error"])
+		if evt.Result != event.Error {
+			t.Errorf("expected an error event caused by %q but got a %s event: %v", causeSubstr, evt.Result, evt.Data)
+			return
+		}
+		if !strings.Contains(cause, causeSubstr) {
+			t.Errorf("expected an error event caused by %q but got cause: %s", causeSubstr, cause)
+		}
+	})
+}
+
+func (c *assertedRule) AssertNoErrorEvent() *assertedRule {
+	return c.addAssert(notErrorKind, func(t eventT, evt *event.Event) {
+		if !assert.NotEqual(t, "error", evt.Result) {
+			t.Logf("received unexpected error event: %v", evt.Data)
+		}
+	})
+}
+
+// AssertRegoRuntimeError asserts an error event reporting a rego evaluation
+// error containing substr. Compile-time errors, from rego parsing or type
+// checking, do not match.
+func (c *assertedRule) AssertRegoRuntimeError(substr string) *assertedRule {
+	return c.addAssert(event.Error, func(t eventT, evt *event.Event) {
+		if !assert.Equal(t, "error", evt.Result) {
+			return
+		}
+		msg, _ := evt.Data.(event.Data)["error"].(string)
+		if regoCompileErrorRe.MatchString(msg) {
+			t.Errorf("expected a rego runtime error but got a compile-time error: %s", msg)
+			return
+		}
+		assert.Contains(t, msg, substr)
+	})
+}
+
+// AssertFindingLine asserts a failed event whose finding reports the 1-based
+// line n in its "line" field.
+func (c *assertedRule) AssertFindingLine(n int) *assertedRule {
+	return c.addAssert(event.Failed, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, "failed", evt.Result) {
+			assert.Equal(t, fmt.Sprint(n), fmt.Sprint(evt.Data.(event.Data)["line"]))
+		}
+	})
+}
+
+func (c *assertedRule) AssertInputTooLarge() *assertedRule {
+	return c.addAssert(event.Error, func(t eventT, evt *event.Event) {
+		if assert.Equal(t, "error", evt.Result) {
+			assert.Contains(t, evt.Data.(event.Data)["error"], rego.ErrInputTooLarge.Error())
+		}
+	})
+}
+
+func (c *assertedRule) AssertNoDisallowedBuiltins(names ...string) *assertedRule {
+	c.disallowedBuiltins = append(c.disallowedBuiltins, names...)
+	return c
+}
+
+// AssertHermetic runs the rule with the rego built-ins reaching the network
+// rejected: the rule must still produce its expected events.
+func (c *assertedRule) AssertHermetic() *assertedRule {
+	c.hermetic = true
+	return c
+}
+
+// AssertEventCountsPerProfile runs the rule once per given host profile and
+// checks the number of events it produces under each of them.
+func (c *assertedRule) AssertEventCountsPerProfile(counts map[string]int) *assertedRule {
+	c.countsPerProfile = counts
+	return c
+}
+
+// AssertInterval asserts the check built from the rule runs at the given interval.
+func (c *assertedRule) AssertInterval(interval time.Duration) *assertedRule {
+	c.expectedInterval = interval
+	return c
+}
+
+// AssertStableAcrossTimezones asserts the rule reports the same events when
+// the local timezone is set to each of the given timezones as in UTC. The
+// rego evaluations of all runs see the same current time.
+func (c *assertedRule) AssertStableAcrossTimezones(timezones ...string) *assertedRule {
+	c.timezones = append(c.timezones, timezones...)
+	return c
+}
+
+// AssertUnorderedEvents matches each event of the rule to exactly one of its
+// assertions, whatever the order the events are reported in.
+func (c *assertedRule) AssertUnorderedEvents() *assertedRule {
+	c.unordered = true
+	return c
+}
+
+// AssertEventCount asserts the rule emits between min and max events, max
+// being -1 for no upper bound. The other assertions of the rule then only
+// apply to its first events instead of requiring one event each.
+func (c *assertedRule) AssertEventCount(min, max int) *assertedRule {
+	c.countSet = true
+	c.countMin, c.countMax = min, max
+	return c
+}
+
+// AssertRawReport asserts the rule reports raw content, checked by f. Each
+// call asserts one raw report, in the order they are reported.
+func (c *assertedRule) AssertRawReport(f func(t eventT, content []byte, service string, tags []string)) *assertedRule {
+	c.rawAsserts = append(c.rawAsserts, f)
+	return c
+}
+
+// DisallowRawReports fails the rule if it reports any raw content. Otherwise
+// the raw reports that are not asserted are ignored.
+func (c *assertedRule) DisallowRawReports() *assertedRule {
+	c.disallowRaw = true
+	return c
+}
+
+func (c *assertedRule) AssertNoEvent() *assertedRule {
+	c.noEvent = true
+	return c
+}
+
+func (c *assertedRule) AssertError() *assertedRule {
 	c.expectErr = true
 	return c
 }
 
-func (c *assertedRule) run(t *testing.T, options []checks.BuilderOption) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// AssertErrorMatches expects running the rule to fail with an error whose
+// message contains substr.
+func (c *assertedRule) AssertErrorMatches(substr string) *assertedRule {
+	c.expectErr = true
+	c.expectErrMatch = substr
+	return c
+}
 
-	for _, setup := range c.setups {
-		setup(t, ctx)
+// checkError checks the error returned when running the rule against the
+// substring expected by AssertErrorMatches, if any.
+func (c *assertedRule) checkError(t eventT, err error) {
+	if c.expectErrMatch != "" && !strings.Contains(err.Error(), c.expectErrMatch) {
+		t.Errorf("expected an error containing %q, got: %v", c.expectErrMatch, err)
 	}
+}
 
-	suiteName := strings.ReplaceAll(c.name, string(os.PathSeparator), "")
-	suiteData := buildSuite(suiteName, c)
+func (c *assertedRule) isSpecified() bool {
+	if len(c.variants) > 0 {
+		for _, variant := range c.variants {
+			if !variant.rule.isSpecified() {
+				return false
+			}
+		}
+		return true
+	}
+	return len(c.asserts) > 0 || len(c.rawAsserts) > 0 || c.stream != nil || c.noEvent || c.countSet || c.expectErr || len(c.countsPerProfile) > 0 || c.expectedInterval > 0 || len(c.timezones) > 0
+}
 
-	_ = c.WriteFile(t, suiteName+".rego", c.rego)
-	file := c.WriteFile(t, suiteName+".yaml", suiteData)
+func (c *assertedRule) run(t *testing.T, options []checks.BuilderOption) {
+	if len(c.variants) > 0 {
+		c.runVariants(t, options)
+		return
+	}
 
-	err := agent.RunChecksFromFile(c, file, options...)
+	file, err := c.runChecks(t, options)
 	if c.expectErr {
 		if err == nil {
 			t.Fatalf("expected to fail running checks but resulting in no error")
 		}
+		c.checkError(t, err)
 		return
 	}
 	if err != nil {
 		t.Fatal(err)
 	}
+	c.runAfterEvals(t)
+
+	if c.expectedInterval > 0 {
+		c.checkInterval(t, file, options)
+		if !c.noEvent && c.stream == nil && len(c.asserts) == 0 && len(c.rawAsserts) == 0 && len(c.timezones) == 0 {
+			return
+		}
+	}
 
+	if len(c.timezones) > 0 {
+		c.checkTimezones(t, options)
+		if !c.noEvent && c.stream == nil && len(c.asserts) == 0 && len(c.rawAsserts) == 0 {
+			return
+		}
+	}
+
+	c.assertEvents(t)
+}
+
+// runAfterEvals runs the checks added by AfterEval
+func (c *assertedRule) runAfterEvals(t *testing.T) {
+	for _, check := range c.afterEvals {
+		check(t, c.events)
+	}
+}
+
+// assertEvents checks the events reported for the rule against its assertions
+func (c *assertedRule) assertEvents(t *testing.T) {
 	if c.noEvent && len(c.asserts) > 0 {
 		t.Fatalf("no event expected: asserts should be empty")
 	}
-	if !c.noEvent && len(c.asserts) == 0 {
+	if c.noEvent && c.countSet {
+		t.Fatalf("no event expected: event count should not be asserted")
+	}
+	if c.countSet && (c.countMin < 0 || c.countMax < -1 || (c.countMax >= 0 && c.countMax < c.countMin)) {
+		t.Fatalf("invalid event count bounds [%d, %d]", c.countMin, c.countMax)
+	}
+	if c.disallowRaw && len(c.rawAsserts) > 0 {
+		t.Fatalf("no raw report allowed: raw asserts should be empty")
+	}
+	if c.stream != nil && (c.noEvent || len(c.asserts) > 0) {
+		t.Fatalf("streamed events: asserts should be empty")
+	}
+	if !c.noEvent && !c.countSet && c.stream == nil && len(c.asserts) == 0 && len(c.rawAsserts) == 0 {
 		t.Fatalf("missing assertions")
 	}
+	c.assertRawReports(t)
+	if c.stream != nil {
+		c.assertStream(t)
+		return
+	}
 
 	events := c.events
+	if c.hermetic {
+		for _, evt := range events {
+			if data, ok := evt.Data.(event.Data); ok && evt.Result == "error" {
+				if msg, ok := data["error"].(string); ok && strings.Contains(msg, "unsafe built-in") {
+					t.Errorf("rule attempted a network access: %s", msg)
+				}
+			}
+		}
+	}
 	if c.noEvent {
 		if len(events) > 0 {
 			for _, event := range events {
@@ -263,30 +1463,595 @@ func (c *assertedRule) run(t *testing.T, options []checks.BuilderOption) {
 			}
 			t.Fatalf("expected no event on this rule: received %d", len(events))
 		}
+	} else if c.countSet {
+		if len(events) < c.countMin || (c.countMax >= 0 && len(events) > c.countMax) {
+			for _, event := range events {
+				t.Logf("received event: %+v", event)
+			}
+			if c.countMax < 0 {
+				t.Errorf("expected at least %d events but received %d", c.countMin, len(events))
+			} else {
+				t.Errorf("expected between %d and %d events but received %d", c.countMin, c.countMax, len(events))
+			}
+		}
 	} else if len(events) != len(c.asserts) {
-		t.Logf("expected %d events but received %d", len(c.asserts), len(events))
-		t.Fail()
+		t.Errorf("expected %d events but received %d:\n%s", len(c.asserts), len(events), c.eventsDiff(events))
+	}
+
+	if !c.noEvent {
+		for _, event := range events {
+			for _, assertion := range c.defaultAsserts {
+				assertion(t, event)
+			}
+		}
 	}
 
+	if c.unordered {
+		c.matchUnordered(t, events)
+		return
+	}
 	for i, event := range events {
 		if i < len(c.asserts) {
 			c.asserts[i](t, event)
-		} else {
+		} else if !c.countSet {
 			t.Logf("unexpected event %d", i)
 			t.Fail()
 		}
 	}
+	if c.countSet && len(events) < len(c.asserts) {
+		t.Errorf("expected at least %d events to assert but received %d", len(c.asserts), len(events))
+	}
+}
+
+// eventsDiff lists side by side the kind of event expected by each assertion
+// and the event received at the same index.
+func (c *assertedRule) eventsDiff(events []*event.Event) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\texpected\tactual\tresource")
+	for i := 0; i < len(c.asserts) || i < len(events); i++ {
+		expected, actual, resource := "none", "none", "-"
+		if i < len(c.assertKinds) {
+			expected = c.assertKinds[i]
+		}
+		if i < len(events) {
+			actual = events[i].Result
+			resource = events[i].ResourceType + " " + events[i].ResourceID
+		}
+		mark := ""
+		if expected != actual && (expected != notErrorKind || actual == event.Error || actual == "none") {
+			mark = " <-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s%s\n", i, expected, actual, resource, mark)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// assertRawReports checks the raw content reported by the rule against its
+// raw assertions
+func (c *assertedRule) assertRawReports(t *testing.T) {
+	if c.disallowRaw {
+		for _, report := range c.rawReports {
+			t.Errorf("unexpected raw report for service %q: %s", report.service, report.content)
+		}
+		return
+	}
+	if len(c.rawAsserts) == 0 {
+		return
+	}
+	if len(c.rawReports) != len(c.rawAsserts) {
+		t.Errorf("expected %d raw reports but received %d", len(c.rawAsserts), len(c.rawReports))
+	}
+	for i, report := range c.rawReports {
+		if i < len(c.rawAsserts) {
+			c.rawAsserts[i](t, report.content, report.service, report.tags)
+		}
+	}
+}
+
+// matchUnordered pairs each event with a distinct assertion it satisfies, and
+// reports the failures of the events and assertions left unpaired. Unpaired
+// events are accepted when the event count is asserted.
+func (c *assertedRule) matchUnordered(t *testing.T, events []*event.Event) {
+	probes := make([][]*probeT, len(events))
+	for i, evt := range events {
+		probes[i] = make([]*probeT, len(c.asserts))
+		for j, assertion := range c.asserts {
+			probes[i][j] = probe(assertion, evt)
+		}
+	}
+
+	// assertion index -> event index, found with augmenting paths so that a
+	// greedy choice never prevents a complete matching
+	matchedEvent := make([]int, len(c.asserts))
+	for j := range matchedEvent {
+		matchedEvent[j] = -1
+	}
+	var augment func(i int, seen []bool) bool
+	augment = func(i int, seen []bool) bool {
+		for j, p := range probes[i] {
+			if p.failed || seen[j] {
+				continue
+			}
+			seen[j] = true
+			if matchedEvent[j] < 0 || augment(matchedEvent[j], seen) {
+				matchedEvent[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	matchedAssert := make([]bool, len(events))
+	for i := range events {
+		matchedAssert[i] = augment(i, make([]bool, len(c.asserts)))
+	}
+
+	for i, evt := range events {
+		if matchedAssert[i] || c.countSet {
+			continue
+		}
+		t.Errorf("event %d matched no assertion: %+v", i, evt)
+		for j, p := range probes[i] {
+			if matchedEvent[j] < 0 {
+				t.Logf("  assertion %d: %s", j, strings.Join(p.messages, "; "))
+			}
+		}
+	}
+	for j, i := range matchedEvent {
+		if i < 0 {
+			t.Errorf("assertion %d matched no event", j)
+		}
+	}
+}
+
+// probeT records the failures of an assertion instead of failing the test
+type probeT struct {
+	failed   bool
+	messages []string
+}
+
+func (p *probeT) Errorf(format string, args ...any) {
+	p.failed = true
+	p.messages = append(p.messages, strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (p *probeT) FailNow() {
+	p.failed = true
+	runtime.Goexit()
+}
+
+func (p *probeT) Logf(format string, args ...any) {
+	p.messages = append(p.messages, strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (p *probeT) Fatalf(format string, args ...any) {
+	p.Errorf(format, args...)
+	p.FailNow()
+}
+
+func (p *probeT) Helper() {}
+
+// probe runs the assertion against the event, in its own goroutine as FailNow
+// exits it
+func probe(assertion func(eventT, *event.Event), evt *event.Event) *probeT {
+	p := &probeT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assertion(p, evt)
+	}()
+	<-done
+	return p
+}
+
+// probeRule calls build with a rule failing the probe instead of the test, in
+// its own goroutine as Fatalf exits it
+func probeRule(build func(*assertedRule)) *probeT {
+	p := &probeT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		build(&assertedRule{t: p, name: "Probe"})
+	}()
+	<-done
+	return p
+}
+
+// runChecks runs the setups of the rule and then the rule itself, collecting
+// its events in c.events. It returns the path of the generated suite.
+func (c *assertedRule) runChecks(t *testing.T, options []checks.BuilderOption) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	for k, v := range c.env {
+		t.Setenv(k, v)
+	}
+	for _, setup := range c.setups {
+		setup(t, ctx)
+	}
+
+	suiteName := strings.ReplaceAll(c.name, string(os.PathSeparator), "")
+	suiteData := buildSuite(suiteName, c.suiteInterval, c)
+	if diagnosis := diagnoseSuite(suiteData); diagnosis != "" {
+		t.Log(diagnosis)
+	}
+
+	if len(c.disallowedBuiltins) > 0 {
+		c.checkBuiltins(t)
+	}
+
+	c.writeRego(t, suiteName)
+	file := c.WriteFile(t, suiteName+".yaml", suiteData)
+
+	if os.Getenv("COMPLIANCE_TEST_DUMP") != "" {
+		var buf bytes.Buffer
+		dumpGenerated(&buf, c.name, suiteName, suiteData, c.rego)
+		t.Log(buf.String())
+	}
+
+	if c.hermetic {
+		options = append(options[:len(options):len(options)], checks.WithHermeticRegoEval())
+	}
+
+	if c.timeout == 0 {
+		return file, agent.RunChecksFromFile(c, file, options...)
+	}
+
+	// on timeout, the remaining checks are not run and Report drops the events
+	// of the check still running, which is waited for before rootDir is
+	// removed
+	done := make(chan error, 1)
+	go func() {
+		done <- agent.RunChecksFromFileContext(ctx, c, file, options...)
+	}()
+	select {
+	case err := <-done:
+		return file, err
+	case <-ctx.Done():
+		c.reportMu.Lock()
+		c.abandoned = true
+		c.reportMu.Unlock()
+		t.Cleanup(func() { <-done })
+		return file, fmt.Errorf("rule %q exceeded timeout %s", c.name, c.timeout)
+	}
+}
+
+func (c *assertedRule) checkInterval(t *testing.T, file string, options []checks.BuilderOption) {
+	builder, err := checks.NewBuilder(c, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer builder.Close()
+
+	found := false
+	err = builder.ChecksFromFile(file, func(rule *compliance.RuleCommon, check compliance.Check, err error) bool {
+		if err != nil {
+			t.Errorf("could not build check %s: %v", rule.ID, err)
+			return true
+		}
+		found = true
+		assert.Equal(t, c.expectedInterval, check.Interval(), "unexpected interval for %s", rule.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Errorf("no check built from the rule")
+	}
+}
+
+// checkTimezones runs the rule in UTC and then in each of the configured
+// timezones, and compares their events.
+func (c *assertedRule) checkTimezones(t *testing.T, options []checks.BuilderOption) {
+	now := c.now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	options = append(options[:len(options):len(options)], checks.WithClock(func() time.Time { return now }))
+
+	runIn := func(t *testing.T, tz string) []string {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			t.Fatalf("unknown timezone %q: %v", tz, err)
+		}
+		local := time.Local
+		time.Local = loc
+		defer func() { time.Local = local }()
+		t.Setenv("TZ", tz)
+
+		rootDir, err := os.MkdirTemp(c.rootDir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := c.clone(rootDir)
+		if _, err := r.runChecks(t, options); err != nil {
+			t.Fatal(err)
+		}
+		return eventsSummary(r.events)
+	}
+
+	expected := runIn(t, "UTC")
+	for _, tz := range c.timezones {
+		t.Run(tz, func(t *testing.T) {
+			assert.Equal(t, expected, runIn(t, tz), "events differ from UTC in timezone %s", tz)
+		})
+	}
+}
+
+// eventsSummary returns a sorted description of the results of events,
+// ignoring their timestamps
+func eventsSummary(events []*event.Event) []string {
+	summary := make([]string, 0, len(events))
+	for _, evt := range events {
+		summary = append(summary, fmt.Sprintf("%s %s %s %v", evt.Result, evt.ResourceType, evt.ResourceID, evt.Data))
+	}
+	sort.Strings(summary)
+	return summary
+}
+
+func (c *assertedRule) runVariants(t *testing.T, options []checks.BuilderOption) {
+	for _, variant := range c.variants {
+		rootDir, err := os.MkdirTemp(c.rootDir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := c.variantRule(variant, rootDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		variantOptions := options
+		if variant.hostname != "" {
+			variantOptions = append(options[:len(options):len(options)], checks.WithHostname(variant.hostname))
+		}
+		t.Run(variant.name, func(t *testing.T) {
+			v.run(t, variantOptions)
+		})
+	}
+}
+
+// clone returns a copy of the rule run from rootDir, with its inputs, policy,
+// setups and settings, but without its assertions, variants, host profiles or
+// events.
+func (c *assertedRule) clone(rootDir string) *assertedRule {
+	r := &assertedRule{
+		t:                  c.t,
+		rootDir:            rootDir,
+		hostname:           c.hostname,
+		name:               c.name,
+		input:              c.input,
+		inputs:             append([]string(nil), c.inputs...),
+		rego:               c.rego,
+		scopes:             append([]string(nil), c.scopes...),
+		regoTemplate:       c.regoTemplate,
+		regoVars:           c.regoVars,
+		regoGzip:           c.regoGzip,
+		tags:               append([]string(nil), c.tags...),
+		setups:             append([]func(*testing.T, context.Context){}, c.setups...),
+		afterEvals:         append([]func(*testing.T, []*event.Event){}, c.afterEvals...),
+		unordered:          c.unordered,
+		hermetic:           c.hermetic,
+		disallowedBuiltins: append([]string(nil), c.disallowedBuiltins...),
+		suiteInterval:      c.suiteInterval,
+		now:                c.now,
+		timeout:            c.timeout,
+		defaultAsserts:     c.defaultAsserts,
+	}
+	if len(c.env) > 0 {
+		r.env = make(map[string]string, len(c.env))
+		for k, v := range c.env {
+			r.env[k] = v
+		}
+	}
+	return r
+}
+
+// variantRule returns the rule run for the variant from rootDir: a clone of
+// the rule, with the policy and settings of the variant taking precedence,
+// its setups and checks added after the ones of the rule, and its assertions.
+func (c *assertedRule) variantRule(variant *ruleVariant, rootDir string) (*assertedRule, error) {
+	v := variant.rule
+	r := c.clone(rootDir)
+	r.ruleAsserts = v.ruleAsserts
+	if variant.hostname != "" {
+		r.hostname = variant.hostname
+	}
+	if v.rego != "" {
+		r.rego, r.regoTemplate, r.regoVars, r.regoGzip = v.rego, v.regoTemplate, v.regoVars, v.regoGzip
+	} else if variant.hostname != "" && c.regoTemplate != "" {
+		rego, err := r.renderRego(c.regoTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("could not render rego of rule %q for hostname %q: %w", c.name, variant.hostname, err)
+		}
+		r.rego = rego
+	}
+	r.setups = append(r.setups, v.setups...)
+	r.afterEvals = append(r.afterEvals, v.afterEvals...)
+	r.disallowedBuiltins = append(r.disallowedBuiltins, v.disallowedBuiltins...)
+	r.hermetic = r.hermetic || v.hermetic
+	r.unordered = r.unordered || v.unordered
+	if v.suiteInterval != "" {
+		r.suiteInterval = v.suiteInterval
+	}
+	if v.timeout != 0 {
+		r.timeout = v.timeout
+	}
+	for k, val := range v.env {
+		if r.env == nil {
+			r.env = make(map[string]string, len(v.env))
+		}
+		r.env[k] = val
+	}
+	return r, nil
+}
+
+func (c *assertedRule) runProfiles(t *testing.T, options []checks.BuilderOption, profiles map[string][]checks.BuilderOption) {
+	names := make([]string, 0, len(c.countsPerProfile))
+	for name := range c.countsPerProfile {
+		if _, ok := profiles[name]; !ok {
+			t.Fatalf("unknown host profile %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rootDir, err := os.MkdirTemp(c.rootDir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := c.clone(rootDir)
+		profileOptions := append(options[:len(options):len(options)], profiles[name]...)
+		expected := c.countsPerProfile[name]
+		t.Run(name, func(t *testing.T) {
+			if _, err := p.runChecks(t, profileOptions); err != nil {
+				t.Fatal(err)
+			}
+			if len(p.events) != expected {
+				for _, event := range p.events {
+					t.Logf("received event: %+v", event)
+				}
+				t.Errorf("expected %d events with host profile %q but received %d", expected, name, len(p.events))
+			}
+		})
+	}
+}
+
+func (c *assertedRule) checkBuiltins(t *testing.T) {
+	used, err := regoCalls(c.name, c.rego)
+	if err != nil {
+		t.Fatalf("could not parse rego: %v", err)
+	}
+	for _, name := range c.disallowedBuiltins {
+		if loc, ok := used[name]; ok {
+			t.Errorf("rego uses disallowed built-in %s at %s", name, loc)
+		}
+	}
 }
 
 func (c *assertedRule) Report(event *event.Event) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if c.abandoned {
+		return
+	}
+	if c.stream != nil {
+		c.stream.report(event, c.defaultAsserts)
+		return
+	}
 	c.events = append(c.events, event)
 }
 
 func (c *assertedRule) ReportRaw(content []byte, service string, tags ...string) {
-	panic("should not have been called")
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if c.abandoned {
+		return
+	}
+	c.rawReports = append(c.rawReports, rawReport{
+		content: append([]byte(nil), content...),
+		service: service,
+		tags:    append([]string(nil), tags...),
+	})
+}
+
+type fakeCloudClient struct {
+	resources     []*env.CloudResource
+	omittedFields []string
+}
+
+func (c *fakeCloudClient) ListResources(ctx context.Context, resourceType string) ([]*env.CloudResource, error) {
+	var resources []*env.CloudResource
+	for _, r := range c.resources {
+		if r.Type == resourceType {
+			if len(c.omittedFields) > 0 {
+				r = &env.CloudResource{
+					Type:       r.Type,
+					ID:         r.ID,
+					Attributes: omitFields(r.Attributes, c.omittedFields),
+				}
+			}
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+// fakeDirectoryClient returns the entries under the searched base DN. Only
+// presence "(attr=*)" and equality "(attr=value)" filters are supported.
+type fakeDirectoryClient struct {
+	entries []*env.DirectoryEntry
+}
+
+func (c *fakeDirectoryClient) Search(ctx context.Context, baseDN, filter string) ([]*env.DirectoryEntry, error) {
+	if !strings.HasPrefix(filter, "(") || !strings.HasSuffix(filter, ")") || strings.Count(filter, "(") != 1 {
+		return nil, fmt.Errorf("unsupported directory filter %q", filter)
+	}
+	attr, value, ok := strings.Cut(filter[1:len(filter)-1], "=")
+	if !ok {
+		return nil, fmt.Errorf("unsupported directory filter %q", filter)
+	}
+
+	var entries []*env.DirectoryEntry
+	for _, e := range c.entries {
+		dn := strings.ToLower(e.DN)
+		base := strings.ToLower(baseDN)
+		if dn != base && !strings.HasSuffix(dn, ","+base) {
+			continue
+		}
+		if strings.EqualFold(attr, "objectClass") && value == "*" {
+			entries = append(entries, e)
+			continue
+		}
+		for name, values := range e.Attributes {
+			if !strings.EqualFold(name, attr) {
+				continue
+			}
+			for _, v := range values {
+				if value == "*" || strings.EqualFold(v, value) {
+					entries = append(entries, e)
+					break
+				}
+			}
+			break
+		}
+	}
+	return entries, nil
+}
+
+// omitFields returns a copy of attributes without the given dotted paths
+func omitFields(attributes map[string]interface{}, fields []string) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(attributes))
+	for k, v := range attributes {
+		var nested []string
+		omitted := false
+		for _, field := range fields {
+			if field == k {
+				omitted = true
+			} else if strings.HasPrefix(field, k+".") {
+				nested = append(nested, strings.TrimPrefix(field, k+"."))
+			}
+		}
+		if omitted {
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			v = omitFields(m, nested)
+		}
+		result[k] = v
+	}
+	return result
 }
 
-func buildSuite(name string, rules ...*assertedRule) string {
+func buildSuite(name, interval string, rules ...*assertedRule) string {
 	const suiteTpl = `schema:
   version: 1.0.0
 name: %s
@@ -296,26 +2061,121 @@ rules:`
 	const ruleTpl = `id: %s
 version: 123
 scope:
-  - %s
+%s
 input:
   %s`
 
 	suite := fmt.Sprintf(suiteTpl, name, "framework_"+name, "42.12")
+	if interval != "" {
+		suite = strings.Replace(suite, "\nrules:", fmt.Sprintf("\ninterval: %s\nrules:", interval), 1)
+	}
 	for _, rule := range rules {
-		scope := rule.scope
-		if scope == "" {
-			scope = "none"
+		scopes := rule.scopes
+		if len(scopes) == 0 {
+			scopes = []string{"none"}
+		}
+		var scopeList []string
+		for _, scope := range scopes {
+			scopeList = append(scopeList, "  - "+scope)
 		}
-		ruleData := fmt.Sprintf(ruleTpl, rule.name, scope, indent(1, rule.input))
+		ruleData := fmt.Sprintf(ruleTpl, rule.name, strings.Join(scopeList, "\n"), indent(1, rule.inputBlock()))
 		suite += "\n  - " + indent(2, ruleData)
 	}
 	return suite
 }
 
+// diagnoseSuite decodes the generated suite as the check does. When it is
+// malformed, it returns the error followed by the offending lines and the
+// numbered document, for the errors of the bench itself to be located.
+func diagnoseSuite(suiteData string) string {
+	err := yaml.Unmarshal([]byte(suiteData), &compliance.Suite{})
+	if err == nil {
+		return ""
+	}
+
+	lines := strings.Split(suiteData, "\n")
+	offending := make(map[int]bool)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "generated suite is malformed: %v\n", err)
+	for _, match := range yamlLineRe.FindAllStringSubmatch(err.Error(), -1) {
+		n, _ := strconv.Atoi(match[1])
+		if n >= 1 && n <= len(lines) && !offending[n] {
+			offending[n] = true
+			fmt.Fprintf(&buf, "line %d: %s\n", n, lines[n-1])
+		}
+	}
+	for i, line := range lines {
+		marker := " "
+		if offending[i+1] {
+			marker = ">"
+		}
+		fmt.Fprintf(&buf, "%s%4d | %s\n", marker, i+1, line)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func regoCalls(name, rego string) (map[string]*ast.Location, error) {
+	module, err := ast.ParseModule(name+".rego", rego)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make(map[string]*ast.Location)
+	addCall := func(operator ast.Ref, loc *ast.Location) {
+		if _, ok := calls[operator.String()]; !ok {
+			calls[operator.String()] = loc
+		}
+	}
+	ast.NewGenericVisitor(func(x interface{}) bool {
+		switch x := x.(type) {
+		case *ast.Expr:
+			if x.IsCall() {
+				addCall(x.Operator(), x.Location)
+			}
+		case *ast.Term:
+			if call, ok := x.Value.(ast.Call); ok {
+				if operator, ok := call[0].Value.(ast.Ref); ok {
+					addCall(operator, x.Location)
+				}
+			}
+		}
+		return false
+	}).Walk(module)
+	return calls, nil
+}
+
+// indent indents all the lines of s but the first one by count levels, once
+// the indentation common to its non-blank lines is removed. The leading and
+// trailing blank lines are dropped and the other blank lines are left empty.
 func indent(count int, s string) string {
-	lines := strings.SplitAfter(s, "\n")
-	if len(lines[len(lines)-1]) == 0 {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
 		lines = lines[:len(lines)-1]
 	}
-	return strings.TrimSpace(strings.Join(lines, strings.Repeat("  ", count)))
+
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " ")); common < 0 || n < common {
+			common = n
+		}
+	}
+
+	prefix := strings.Repeat("  ", count)
+	for i, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			lines[i] = ""
+		case i == 0:
+			lines[i] = line[common:]
+		default:
+			lines[i] = prefix + line[common:]
+		}
+	}
+	return strings.Join(lines, "\n")
 }
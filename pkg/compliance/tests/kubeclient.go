@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// NewFakeKubeClient returns a fake Kubernetes dynamic client serving the given
+// objects. The kinds of the built-in Kubernetes types are resolved from the
+// client-go scheme; any other object must set its TypeMeta.
+func NewFakeKubeClient(objs ...runtime.Object) dynamic.Interface {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	return dynamicfake.NewSimpleDynamicClient(scheme, objs...)
+}
+
+// WithKubeObjects makes the suite use a fake Kubernetes client serving the
+// given objects. See NewFakeKubeClient.
+func (s *suite) WithKubeObjects(objs ...runtime.Object) *suite {
+	return s.WithKubeClient(NewFakeKubeClient(objs...))
+}
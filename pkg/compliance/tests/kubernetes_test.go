@@ -17,28 +17,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/dynamic/fake"
-	"k8s.io/client-go/kubernetes/scheme"
 )
 
-func getMockedKubeClient(t *testing.T, objects ...runtime.Object) dynamic.Interface {
-	addKnownTypes := func(scheme *runtime.Scheme) error {
-		scheme.AddKnownTypes(schema.GroupVersion{Group: "mygroup.com", Version: "v1"},
-			&MyObj{},
-			&MyObjList{},
-		)
-		return nil
-	}
-	schemeBuilder := runtime.NewSchemeBuilder(addKnownTypes)
-	schemeBuilder.AddToScheme(scheme.Scheme)
-	return fake.NewSimpleDynamicClient(scheme.Scheme, objects...)
-}
-
 func TestKubernetesCluster(t *testing.T) {
-	kubeClient := getMockedKubeClient(t,
+	b := NewTestBench(t).WithKubeObjects(
 		&corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
 				UID:  "my-cluster",
@@ -51,9 +34,16 @@ func TestKubernetesCluster(t *testing.T) {
 		newMyObj("testns3", "dummy1", "103"),
 		newMyObj("testns3", "dummy2", "104"),
 		newMyObj("testns3", "dummy3", "105"),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "etcd",
+				Namespace: "kube-system",
+			},
+			Spec: corev1.PodSpec{
+				HostNetwork: true,
+			},
+		},
 	)
-
-	b := NewTestBench(t).WithKubeClient(kubeClient)
 	defer b.Run()
 
 	b.
@@ -192,6 +182,38 @@ findings[f] {
 		{}
 	)
 }
+`).
+		AssertPassedEvent(nil)
+	b.
+		AddRule("BuiltinObject").
+		WithScope("kubernetesCluster").
+		WithInput(`
+- kubeApiserver:
+		kind: pods
+		version: v1
+		namespace: kube-system
+		apiRequest:
+			verb: list
+	type: array
+	tag: pods
+`).
+		WithRego(`
+package datadog
+
+import data.datadog as dd
+
+findings[f] {
+	count(input.pods) = 1
+	pod := input.pods[0]
+	pod.name = "etcd"
+	pod.resource.Object.kind = "Pod"
+	pod.resource.Object.spec.hostNetwork = true
+	f := dd.passed_finding(
+		"my_resource_type",
+		"my_resource_id",
+		{}
+	)
+}
 `).
 		AssertPassedEvent(nil)
 }
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"io/fs"
+	"strings"
+	"testing/fstest"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+)
+
+// MemFile is a file of the file system returned by NewMemFS. Mode defaults
+// to 0644, and User and Group to root.
+type MemFile struct {
+	Content string
+	Mode    fs.FileMode
+	User    string
+	Group   string
+}
+
+// NewMemFS returns an in-memory file system holding the files, by absolute
+// path. The file resources read it like the files of the host, with the given
+// modes and owners on any OS.
+func NewMemFS(files map[string]MemFile) fs.FS {
+	fsys := make(fstest.MapFS, len(files))
+	for path, f := range files {
+		mode := f.Mode
+		if mode == 0 {
+			mode = 0o644
+		}
+		owner := &env.FileOwner{User: f.User, Group: f.Group}
+		if owner.User == "" {
+			owner.User = "root"
+		}
+		if owner.Group == "" {
+			owner.Group = "root"
+		}
+		fsys[strings.TrimPrefix(path, "/")] = &fstest.MapFile{
+			Data: []byte(f.Content),
+			Mode: mode,
+			Sys:  owner,
+		}
+	}
+	return fsys
+}
+
+// WithFileSystem makes the file resources of the rules read fsys instead of
+// the files of the host, see checks.WithFileSystem.
+func (s *suite) WithFileSystem(fsys fs.FS) *suite {
+	s.fileSystem = fsys
+	return s
+}
+
+// WithMemFiles makes the file resources of the rules read the given files
+// only. See NewMemFS.
+func (s *suite) WithMemFiles(files map[string]MemFile) *suite {
+	return s.WithFileSystem(NewMemFS(files))
+}
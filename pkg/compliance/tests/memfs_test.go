@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/file"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const worldWritableRego = `
+package datadog
+import data.datadog as dd
+
+world_writable(f) {
+	bits.and(f.permissions, 2) != 0
+}
+
+findings[f] {
+	file := input.file[_]
+	world_writable(file)
+	f := dd.failing_finding("file", file.path, {"user": file.user, "group": file.group})
+}
+
+findings[f] {
+	file := input.file[_]
+	not world_writable(file)
+	f := dd.passed_finding("file", file.path, {"user": file.user, "group": file.group})
+}
+`
+
+func TestMemFiles(t *testing.T) {
+	b := NewTestBench(t).WithMemFiles(map[string]MemFile{
+		"/etc/app/config.yaml":  {Content: "key: value\n", Mode: 0o666, User: "app", Group: "staff"},
+		"/etc/app/secrets.yaml": {Content: "token: x\n", Mode: 0o600},
+		"/etc/other":            {},
+	})
+	defer b.Run()
+
+	b.AddRule("WorldWritable").
+		WithInput(`
+- file:
+		glob: /etc/app/*.yaml
+	type: array
+`).
+		WithRego(worldWritableRego).
+		AssertUnorderedEvents().
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "/etc/app/config.yaml", evt.ResourceID)
+			assert.Equal(t, "app", evt.Data.(event.Data)["user"])
+			assert.Equal(t, "staff", evt.Data.(event.Data)["group"])
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "/etc/app/secrets.yaml", evt.ResourceID)
+			assert.Equal(t, "root", evt.Data.(event.Data)["user"])
+		})
+
+	b.AddRule("Content").
+		WithInput(`
+- file:
+		path: /etc/app/config.yaml
+		parser: yaml
+	tag: config
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	input.config.content.key == "value"
+	f := dd.passed_finding("file", input.config.path, {})
+}
+`).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "/etc/app/config.yaml", evt.ResourceID)
+		})
+
+	b.AddRule("Missing").
+		WithInput(`
+- file:
+		path: /etc/missing
+	tag: missing
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	not input.missing.path
+	f := dd.failing_finding("file", "/etc/missing", {})
+}
+`).
+		AssertFailedEvent(nil)
+}
@@ -16,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	processutils "github.com/DataDog/datadog-agent/pkg/compliance/utils/process"
 
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/group"
 	_ "github.com/DataDog/datadog-agent/pkg/compliance/resources/process"
@@ -66,7 +67,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "Self", evt.AgentRuleID)
 			assert.Equal(t, 0, evt.AgentRuleVersion)
 			assert.Equal(t, "my_resource_id", evt.ResourceID)
@@ -122,7 +123,7 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "SelfDuplicated", evt.AgentRuleID)
 			assert.Equal(t, 0, evt.AgentRuleVersion)
 			assert.Equal(t, "self1_id", evt.ResourceID)
@@ -130,7 +131,7 @@ findings[f] {
 			assert.Equal(t, "rego", evt.Evaluator)
 			assert.Equal(t, self, evt.Data.(event.Data)["name"])
 		}).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "SelfDuplicated", evt.AgentRuleID)
 			assert.Equal(t, 0, evt.AgentRuleVersion)
 			assert.Equal(t, "self2_id", evt.ResourceID)
@@ -209,12 +210,65 @@ findings[f] {
 	)
 }
 `).
-		AssertPassedEvent(func(t *testing.T, evt *event.Event) {
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
 			assert.Equal(t, "sleep", evt.ResourceID)
 			assert.Equal(t, "sleep", evt.ResourceType)
 		})
 }
 
+type fakeProcessClient processutils.Processes
+
+func (c fakeProcessClient) FindProcessesByName(name string) (processutils.Processes, error) {
+	var processes processutils.Processes
+	for _, p := range c {
+		if p.Name == name {
+			processes = append(processes, p)
+		}
+	}
+	return processes, nil
+}
+
+func TestProcessClient(t *testing.T) {
+	b := NewTestBench(t).
+		WithProcessClient(fakeProcessClient{
+			processutils.NewProcessMetadata(42, 0, "kubelet", []string{"kubelet", "--anonymous-auth=false"}, []string{"FOO=foo"}),
+			processutils.NewProcessMetadata(43, 0, "kubelet", []string{"kubelet", "--anonymous-auth=true"}, nil),
+			processutils.NewProcessMetadata(44, 0, "etcd", []string{"etcd"}, nil),
+		})
+	defer b.Run()
+
+	b.AddRule("Kubelet").
+		WithInput(`
+- process:
+		name: kubelet
+		envs:
+			- FOO
+`).
+		WithRego(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	p := input.process[_]
+	p.flags["--anonymous-auth"] == "false"
+	f := dd.passed_finding("process", format_int(p.pid, 10), {"foo": p.envs.FOO})
+}
+
+findings[f] {
+	p := input.process[_]
+	p.flags["--anonymous-auth"] != "false"
+	f := dd.failing_finding("process", format_int(p.pid, 10), {})
+}
+`).
+		AssertFailedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "43", evt.ResourceID)
+		}).
+		AssertPassedEvent(func(t eventT, evt *event.Event) {
+			assert.Equal(t, "42", evt.ResourceID)
+			assert.Equal(t, "foo", evt.Data.(event.Data)["foo"])
+		})
+}
+
 func TestEtcGroup(t *testing.T) {
 	b := NewTestBench(t)
 	defer b.Run()
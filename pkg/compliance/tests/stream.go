@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+)
+
+// streamTailSize is the number of the last streamed events kept to be logged
+// when the stream assertion fails
+const streamTailSize = 16
+
+// eventStream checks the events of a rule as they are reported, keeping only
+// their count, the first failing one and the last ones in a ring buffer.
+type eventStream struct {
+	check func(i int, evt *event.Event) bool
+
+	count int
+	tail  []*event.Event
+
+	failures     int
+	firstFailure int
+	firstFailed  *event.Event
+	messages     []string
+}
+
+// AssertEventStream checks each event of the rule with check as it is
+// reported, with its index, instead of collecting the events: rules emitting
+// thousands of events can be asserted without retaining them. The events
+// failing check, or one of the default assertions of the suite, are counted
+// and only the first of them is reported, along with the last events
+// received. AssertEventCount bounds the number of streamed events; the other
+// event assertions cannot be combined with the stream, and the checks added
+// by AfterEval get no event.
+func (c *assertedRule) AssertEventStream(check func(i int, evt *event.Event) bool) *assertedRule {
+	c.stream = &eventStream{check: check, firstFailure: -1}
+	return c
+}
+
+func (s *eventStream) report(evt *event.Event, defaultAsserts []func(eventT, *event.Event)) {
+	i := s.count
+	s.count++
+	if len(s.tail) < streamTailSize {
+		s.tail = append(s.tail, evt)
+	} else {
+		s.tail[i%streamTailSize] = evt
+	}
+
+	ok := s.check(i, evt)
+	var messages []string
+	for _, assertion := range defaultAsserts {
+		if p := probe(assertion, evt); p.failed {
+			ok = false
+			messages = append(messages, p.messages...)
+		}
+	}
+	if ok {
+		return
+	}
+	s.failures++
+	if s.firstFailed == nil {
+		s.firstFailure, s.firstFailed, s.messages = i, evt, messages
+	}
+}
+
+// last returns the events kept in the ring buffer, oldest first
+func (s *eventStream) last() []*event.Event {
+	if s.count <= streamTailSize {
+		return s.tail
+	}
+	start := s.count % streamTailSize
+	return append(append([]*event.Event{}, s.tail[start:]...), s.tail[:start]...)
+}
+
+func (c *assertedRule) assertStream(t *testing.T) {
+	s := c.stream
+	if c.countSet {
+		if s.count < c.countMin || (c.countMax >= 0 && s.count > c.countMax) {
+			if c.countMax < 0 {
+				t.Errorf("expected at least %d streamed events but received %d", c.countMin, s.count)
+			} else {
+				t.Errorf("expected between %d and %d streamed events but received %d", c.countMin, c.countMax, s.count)
+			}
+		}
+	} else if s.count == 0 {
+		t.Errorf("expected streamed events but received none")
+	}
+	if s.failures > 0 {
+		t.Errorf("%d of %d streamed events failed, the first one at index %d: %+v", s.failures, s.count, s.firstFailure, s.firstFailed)
+		if len(s.messages) > 0 {
+			t.Logf("failed assertions on event %d: %s", s.firstFailure, strings.Join(s.messages, "; "))
+		}
+	}
+	if t.Failed() {
+		last := s.last()
+		for i, evt := range last {
+			t.Logf("streamed event %d: %+v", s.count-len(last)+i, evt)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tests
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertEventStream(t *testing.T) {
+	const count = 5000
+
+	seen := make(map[string]bool, count)
+	t.Run("bench", func(t *testing.T) {
+		b := NewTestBench(t)
+		defer b.Run()
+
+		b.AddRule("LargeFleet").
+			WithInput(`
+- constants:
+		foo: bar
+`).
+			WithRego(fmt.Sprintf(`
+package datadog
+import data.datadog as dd
+
+findings[f] {
+	i := numbers.range(0, %d)[_]
+	f := dd.passed_finding("container", sprintf("container-%%d", [i]), {})
+}
+`, count-1)).
+			AssertEventCount(count, count).
+			AssertEventStream(func(i int, evt *event.Event) bool {
+				if evt.Result != event.Passed || seen[evt.ResourceID] {
+					return false
+				}
+				seen[evt.ResourceID] = true
+				return true
+			})
+	})
+	assert.Len(t, seen, count)
+}
+
+func TestEventStreamReport(t *testing.T) {
+	s := &eventStream{
+		check: func(i int, evt *event.Event) bool {
+			return evt.Result == event.Passed
+		},
+		firstFailure: -1,
+	}
+	notFoo := func(t eventT, evt *event.Event) {
+		assert.NotEqual(t, "foo", evt.ResourceType)
+	}
+
+	for i := 0; i < 100; i++ {
+		evt := &event.Event{Result: event.Passed, ResourceType: "bar", ResourceID: strconv.Itoa(i)}
+		switch i {
+		case 10, 20:
+			evt.Result = event.Failed
+		case 30:
+			evt.ResourceType = "foo"
+		}
+		s.report(evt, []func(eventT, *event.Event){notFoo})
+	}
+
+	assert.Equal(t, 100, s.count)
+	assert.Equal(t, 3, s.failures)
+	assert.Equal(t, 10, s.firstFailure)
+	assert.Equal(t, "10", s.firstFailed.ResourceID)
+	assert.Empty(t, s.messages)
+
+	last := s.last()
+	if assert.Len(t, last, streamTailSize) {
+		for i, evt := range last {
+			assert.Equal(t, strconv.Itoa(100-streamTailSize+i), evt.ResourceID)
+		}
+	}
+}
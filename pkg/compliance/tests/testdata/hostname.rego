@@ -0,0 +1,8 @@
+package datadog
+
+import data.datadog as dd
+
+findings[f] {
+	input.constants.hostname == "{{.Hostname}}"
+	f := dd.passed_finding("host", "{{.RuleID}}", {"hostname": input.constants.hostname})
+}
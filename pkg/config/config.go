@@ -48,6 +48,12 @@ const (
 	// also used if the user-provided value is invalid.
 	DefaultForwarderRecoveryInterval = 2
 
+	// DefaultForwarderBlockedEndpointsMaxSize is the default number of
+	// endpoints above which the forwarder forgets the recovered ones, also used
+	// if the user-provided value is invalid. It is a soft cap: the endpoints
+	// still failing are kept above it.
+	DefaultForwarderBlockedEndpointsMaxSize = 1000
+
 	megaByte = 1024 * 1024
 
 	// DefaultBatchWait is the default HTTP batch wait in second for logs
@@ -478,8 +484,22 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_backoff_factor", 2)
 	config.BindEnvAndSetDefault("forwarder_backoff_base", 2)
 	config.BindEnvAndSetDefault("forwarder_backoff_max", 64)
+	config.BindEnvAndSetDefault("forwarder_backoff_jitter", 1)     // fraction of the retry interval range randomized
+	config.BindEnvAndSetDefault("forwarder_backoff_max_errors", 0) // 0 means the number of errors to reach forwarder_backoff_max
+	config.BindEnvAndSetDefault("forwarder_backoff_min_interval", 0)
 	config.BindEnvAndSetDefault("forwarder_recovery_interval", DefaultForwarderRecoveryInterval)
 	config.BindEnvAndSetDefault("forwarder_recovery_reset", false)
+	config.BindEnvAndSetDefault("forwarder_recovery_mode", "")        // linear, reset or halve, empty meaning forwarder_recovery_reset applies
+	config.BindEnvAndSetDefault("forwarder_recover_stable_period", 0) // in seconds, 0 means disabled
+	config.BindEnvAndSetDefault("forwarder_half_open_probe", false)   // send a single probe once a block expires
+	// soft cap: the endpoints still failing are kept above it
+	config.BindEnvAndSetDefault("forwarder_blocked_endpoints_max_size", DefaultForwarderBlockedEndpointsMaxSize)
+	config.BindEnvAndSetDefault("forwarder_retry_non_idempotent", true)
+	config.BindEnvAndSetDefault("forwarder_endpoint_rate", 0)                // sends per second per endpoint, 0 means no limit
+	config.BindEnvAndSetDefault("forwarder_health_degraded_threshold", 0.5)  // fraction of failing endpoints
+	config.BindEnvAndSetDefault("forwarder_health_recovered_threshold", 0.2) // fraction of failing endpoints
+	config.BindEnvAndSetDefault("forwarder_health_recovery_duration", 60)    // in seconds
+	config.BindEnvAndSetDefault("forwarder_log_retry_timeline", false)
 
 	// Forwarder storage on disk
 	config.BindEnvAndSetDefault("forwarder_storage_path", "")
@@ -1149,13 +1169,23 @@ func InitConfig(config Config) {
 	// Datadog security agent (compliance)
 	config.BindEnvAndSetDefault("compliance_config.enabled", false)
 	config.BindEnvAndSetDefault("compliance_config.xccdf.enabled", false)
+	config.BindEnvAndSetDefault("compliance_config.cloud.enabled", false)
+	config.BindEnvAndSetDefault("compliance_config.exceptions_file", "")
+	config.BindEnvAndSetDefault("compliance_config.directory.enabled", false)
+	config.BindEnvAndSetDefault("compliance_config.directory.url", "")
+	config.BindEnvAndSetDefault("compliance_config.directory.bind_dn", "")
+	config.BindEnvAndSetDefault("compliance_config.directory.bind_password", "")
 	config.BindEnvAndSetDefault("compliance_config.check_interval", 20*time.Minute)
 	config.BindEnvAndSetDefault("compliance_config.check_max_events_per_run", 100)
+	config.BindEnvAndSetDefault("compliance_config.max_input_bytes", 0)              // 0 means no limit
+	config.BindEnvAndSetDefault("compliance_config.disabled_frameworks", []string{}) // updatable at runtime with `config set`
 	config.BindEnvAndSetDefault("compliance_config.dir", "/etc/datadog-agent/compliance.d")
 	config.BindEnvAndSetDefault("compliance_config.run_path", defaultRunPath)
 	config.BindEnv("compliance_config.run_commands_as")
 	bindEnvAndSetLogsConfigKeys(config, "compliance_config.endpoints.")
 	config.BindEnvAndSetDefault("compliance_config.metrics.enabled", false)
+	config.BindEnvAndSetDefault("compliance_config.socket_reporter.path", "")
+	config.BindEnvAndSetDefault("compliance_config.socket_reporter.disable_backend", false)
 	config.BindEnvAndSetDefault("compliance_config.opa.metrics.enabled", false)
 
 	// Datadog security agent (runtime)
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 var runtimeSettings = make(map[string]RuntimeSetting)
@@ -115,3 +116,34 @@ func GetInt(v interface{}) (int, error) {
 		return 0, fmt.Errorf("GetInt: bad parameter value provided: %v", v)
 	}
 }
+
+// GetStringSlice returns the list of strings contained in value.
+// If value is a string, it splits it on commas, ignoring the empty items.
+// If value is a list of strings, returns it.
+// Else, returns an error.
+func GetStringSlice(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		items := []string{}
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("GetStringSlice: bad parameter value provided: %v", v)
+			}
+			items = append(items, s)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("GetStringSlice: bad parameter value provided: %v", v)
+	}
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// ComplianceDisabledFrameworksRuntimeSetting wraps operations to disable compliance frameworks at runtime.
+type ComplianceDisabledFrameworksRuntimeSetting struct {
+}
+
+// Description returns the runtime setting's description
+func (l ComplianceDisabledFrameworksRuntimeSetting) Description() string {
+	return "Comma-separated list of the compliance frameworks whose checks are skipped, from their next run."
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (l ComplianceDisabledFrameworksRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (l ComplianceDisabledFrameworksRuntimeSetting) Name() string {
+	return "compliance_config.disabled_frameworks"
+}
+
+// Get returns the current value of the runtime setting
+func (l ComplianceDisabledFrameworksRuntimeSetting) Get() (interface{}, error) {
+	return config.Datadog.GetStringSlice("compliance_config.disabled_frameworks"), nil
+}
+
+// Set changes the value of the runtime setting
+func (l ComplianceDisabledFrameworksRuntimeSetting) Set(v interface{}) error {
+	frameworks, err := GetStringSlice(v)
+	if err != nil {
+		return fmt.Errorf("ComplianceDisabledFrameworksRuntimeSetting: %v", err)
+	}
+
+	config.Datadog.Set("compliance_config.disabled_frameworks", frameworks)
+	return nil
+}
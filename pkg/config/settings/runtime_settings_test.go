@@ -158,3 +158,56 @@ func TestGetInt(t *testing.T) {
 		}
 	}
 }
+
+func TestComplianceDisabledFrameworks(t *testing.T) {
+	cleanRuntimeSetting()
+	config.Mock(t)
+
+	s := ComplianceDisabledFrameworksRuntimeSetting{}
+	assert.Equal(t, "compliance_config.disabled_frameworks", s.Name())
+
+	v, err := s.Get()
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+
+	err = s.Set("cis-docker, cis-kubernetes,")
+	assert.Nil(t, err)
+	v, err = s.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cis-docker", "cis-kubernetes"}, v)
+
+	err = s.Set("")
+	assert.Nil(t, err)
+	v, err = s.Get()
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+
+	err = s.Set(42)
+	assert.NotNil(t, err)
+}
+
+func TestGetStringSlice(t *testing.T) {
+	cases := []struct {
+		v   interface{}
+		exp []string
+		err bool
+	}{
+		{"", []string{}, false},
+		{"a", []string{"a"}, false},
+		{" a , b,,", []string{"a", "b"}, false},
+		{[]string{"a", "b"}, []string{"a", "b"}, false},
+		{[]interface{}{"a", "b"}, []string{"a", "b"}, false},
+		{[]interface{}{"a", 1}, nil, true},
+		{1, nil, true},
+	}
+
+	for _, c := range cases {
+		v, err := GetStringSlice(c.v)
+		if c.err {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, c.exp, v)
+		}
+	}
+}
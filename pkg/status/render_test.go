@@ -6,6 +6,7 @@
 package status
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -30,3 +31,58 @@ func TestFormatStatus(t *testing.T) {
 		assert.NotContains(t, actual, statusRenderErrors)
 	})
 }
+
+func TestRenderBlockedEndpoints(t *testing.T) {
+	stats := map[string]interface{}{
+		"Backoff": map[string]interface{}{
+			"Endpoints": []interface{}{
+				map[string]interface{}{"Endpoint": "https://example.com/api/v1/series", "NbError": 3, "Until": "2023-05-01T10:00:00Z", "Blocked": true},
+				map[string]interface{}{"Endpoint": "https://example.com/api/v1/check_run", "NbError": 0, "Until": "2023-05-01T09:00:00Z", "Blocked": false},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, RenderStatusTemplate(&b, "/forwarder.tmpl", stats))
+	assert.Contains(t, b.String(), "Blocked Endpoints")
+	assert.Contains(t, b.String(), "https://example.com/api/v1/series: blocked until 2023-05-01T10:00:00Z after 3 error(s)")
+	assert.NotContains(t, b.String(), "check_run")
+
+	stats["Backoff"].(map[string]interface{})["Endpoints"] = []interface{}{
+		map[string]interface{}{"Endpoint": "https://example.com/api/v1/check_run", "NbError": 0, "Until": "2023-05-01T09:00:00Z", "Blocked": false},
+	}
+	b.Reset()
+	require.NoError(t, RenderStatusTemplate(&b, "/forwarder.tmpl", stats))
+	assert.NotContains(t, b.String(), "Blocked Endpoints")
+
+	b.Reset()
+	require.NoError(t, RenderStatusTemplate(&b, "/forwarder.tmpl", map[string]interface{}{}))
+	assert.NotContains(t, b.String(), "Blocked Endpoints")
+}
+
+func TestRenderBackoffPolicy(t *testing.T) {
+	stats := map[string]interface{}{
+		"Backoff": map[string]interface{}{
+			"Policy": map[string]interface{}{
+				"MinBackoffFactor": float64(2),
+				"BaseBackoffTime":  float64(2),
+				"MaxBackoffTime":   float64(64),
+				"RecoveryInterval": float64(2),
+				"MaxErrors":        float64(6),
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, RenderStatusTemplate(&b, "/forwarder.tmpl", stats))
+	assert.Contains(t, b.String(), "Backoff Policy")
+	assert.Contains(t, b.String(), "Base backoff time: 2s")
+	assert.Contains(t, b.String(), "Max backoff time: 64s")
+	assert.Contains(t, b.String(), "Min backoff factor: 2")
+	assert.Contains(t, b.String(), "Recovery interval: 2")
+	assert.Contains(t, b.String(), "Max errors: 6")
+
+	b.Reset()
+	require.NoError(t, RenderStatusTemplate(&b, "/forwarder.tmpl", map[string]interface{}{}))
+	assert.NotContains(t, b.String(), "Backoff Policy")
+}
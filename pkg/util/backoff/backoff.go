@@ -34,12 +34,30 @@ type Policy struct {
 
 	// MaxErrors derived value is the number of errors it will take to reach the maxBackoffTime.
 	MaxErrors int
+
+	// Jitter is the fraction of the retry interval range, ending at its upper bound, the
+	// backoff time is randomly drawn from. At 0, the upper bound is always used; at 1, the
+	// default, the backoff time is drawn from the whole range.
+	Jitter float64
+
+	// HalveErrors makes DecError halve the number of errors instead of stepping down by
+	// RecoveryInterval.
+	HalveErrors bool
+
+	// MinBackoffTime is the minimum number of seconds to wait for a retry after an error,
+	// whatever the randomization. It should not exceed MaxBackoffTime.
+	MinBackoffTime float64
 }
 
 const secondsFloat = float64(time.Second)
 
-func randomBetween(min, max float64) float64 {
-	return rand.Float64()*(max-min) + min
+// randomBetween draws a number between min and max from r, or from the global
+// source when r is nil
+func randomBetween(r *rand.Rand, min, max float64) float64 {
+	if r == nil {
+		return rand.Float64()*(max-min) + min
+	}
+	return r.Float64()*(max-min) + min
 }
 
 // NewPolicy constructs new Backoff object with given parameters
@@ -56,27 +74,46 @@ func NewPolicy(minBackoffFactor, baseBackoffTime, maxBackoffTime float64, recove
 		MaxBackoffTime:   maxBackoffTime,
 		RecoveryInterval: recoveryInterval,
 		MaxErrors:        maxErrors,
+		Jitter:           1,
 	}
 }
 
 // GetBackoffDuration returns amount of time to sleep after numErrors error
 func (b *Policy) GetBackoffDuration(numErrors int) time.Duration {
-	var backoffTime float64
-
-	if numErrors > 0 {
-		backoffTime = b.BaseBackoffTime * math.Pow(2, float64(numErrors))
-
-		if backoffTime > b.MaxBackoffTime {
-			backoffTime = b.MaxBackoffTime
-		} else {
-			min := backoffTime / b.MinBackoffFactor
-			max := math.Min(b.MaxBackoffTime, backoffTime)
-			backoffTime = randomBetween(min, max)
-		}
+	return b.GetBackoffDurationFrom(nil, numErrors)
+}
+
+// GetBackoffDurationFrom is GetBackoffDuration, drawing the random part of the
+// backoff time from r instead of the global source. r is not safe for concurrent
+// use, the caller must synchronize its calls.
+func (b *Policy) GetBackoffDurationFrom(r *rand.Rand, numErrors int) time.Duration {
+	min, max := b.getBackoffRange(numErrors)
+	backoffTime := max
+	if min < max {
+		backoffTime = randomBetween(r, min, max)
 	}
 
 	return time.Duration(backoffTime * secondsFloat)
+}
 
+// GetBackoffRange returns the bounds of the amount of time GetBackoffDuration
+// can return after numErrors error
+func (b *Policy) GetBackoffRange(numErrors int) (time.Duration, time.Duration) {
+	min, max := b.getBackoffRange(numErrors)
+	return time.Duration(min * secondsFloat), time.Duration(max * secondsFloat)
+}
+
+func (b *Policy) getBackoffRange(numErrors int) (float64, float64) {
+	if numErrors <= 0 {
+		return 0, 0
+	}
+
+	backoffTime := b.BaseBackoffTime * math.Pow(2, float64(numErrors))
+	if backoffTime > b.MaxBackoffTime {
+		return b.MaxBackoffTime, b.MaxBackoffTime
+	}
+	min := backoffTime - b.Jitter*(backoffTime-backoffTime/b.MinBackoffFactor)
+	return math.Max(min, b.MinBackoffTime), math.Max(backoffTime, b.MinBackoffTime)
 }
 
 // IncError increments the error counter up to MaxErrors
@@ -88,8 +125,12 @@ func (b *Policy) IncError(numErrors int) int {
 	return numErrors
 }
 
-// DecError decrements the error counter down to zero at RecoveryInterval rate
+// DecError decrements the error counter down to zero at RecoveryInterval rate, or halves
+// it when HalveErrors is set
 func (b *Policy) DecError(numErrors int) int {
+	if b.HalveErrors {
+		return numErrors / 2
+	}
 	numErrors -= b.RecoveryInterval
 	if numErrors < 0 {
 		return 0
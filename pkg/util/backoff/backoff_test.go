@@ -29,7 +29,7 @@ func TestRandomBetween(t *testing.T) {
 
 	for i := 1; i < 100; i++ {
 		min, max := getRandomMinMax()
-		between := randomBetween(min, max)
+		between := randomBetween(nil, min, max)
 
 		assert.True(t, min <= between)
 		assert.True(t, max >= between)
@@ -64,3 +64,102 @@ func TestBackoff(t *testing.T) {
 	assert.Equal(t, 8*time.Second, b.GetBackoffDuration(3))
 	assert.Equal(t, 9*time.Second, b.GetBackoffDuration(4))
 }
+
+func TestBackoffRange(t *testing.T) {
+	b := NewPolicy(2, 1, 9, 2, false)
+
+	for numErrors, expected := range [][2]time.Duration{
+		{0, 0},
+		{1 * time.Second, 2 * time.Second},
+		{2 * time.Second, 4 * time.Second},
+		{4 * time.Second, 8 * time.Second},
+		{9 * time.Second, 9 * time.Second},
+	} {
+		min, max := b.GetBackoffRange(numErrors)
+		assert.Equal(t, expected[0], min, "min for %d errors", numErrors)
+		assert.Equal(t, expected[1], max, "max for %d errors", numErrors)
+
+		d := b.GetBackoffDuration(numErrors)
+		assert.True(t, d >= min && d <= max, "%v not in [%v, %v]", d, min, max)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewPolicy(2, 1, 9, 2, false)
+	assert.Equal(t, float64(1), b.Jitter)
+
+	b.Jitter = 0
+	for numErrors := 1; numErrors <= b.MaxErrors; numErrors++ {
+		min, max := b.GetBackoffRange(numErrors)
+		assert.Equal(t, max, min, "range for %d errors", numErrors)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, max, b.GetBackoffDuration(numErrors), "duration for %d errors", numErrors)
+		}
+	}
+
+	b.Jitter = 0.5
+	min, max := b.GetBackoffRange(3)
+	assert.Equal(t, 6*time.Second, min)
+	assert.Equal(t, 8*time.Second, max)
+
+	b.Jitter = 1
+	for numErrors := 1; numErrors <= b.MaxErrors; numErrors++ {
+		base := time.Duration(b.BaseBackoffTime * math.Pow(2, float64(numErrors)) / b.MinBackoffFactor * float64(time.Second))
+		if base > time.Duration(b.MaxBackoffTime)*time.Second {
+			base = time.Duration(b.MaxBackoffTime) * time.Second
+		}
+		_, max := b.GetBackoffRange(numErrors)
+		for i := 0; i < 10; i++ {
+			d := b.GetBackoffDuration(numErrors)
+			assert.True(t, d >= base && d <= max, "%v not in [%v, %v]", d, base, max)
+		}
+	}
+}
+
+func TestBackoffHalveErrors(t *testing.T) {
+	b := NewPolicy(1, 1, 9, 2, false)
+	b.HalveErrors = true
+
+	assert.Equal(t, 0, b.DecError(0))
+	assert.Equal(t, 0, b.DecError(1))
+	assert.Equal(t, 1, b.DecError(2))
+	assert.Equal(t, 1, b.DecError(3))
+	assert.Equal(t, 2, b.DecError(4))
+}
+
+func TestBackoffMinBackoffTime(t *testing.T) {
+	b := NewPolicy(2, 1, 9, 2, false)
+	b.MinBackoffTime = 3
+
+	assert.Equal(t, time.Duration(0), b.GetBackoffDuration(0))
+	for numErrors := 1; numErrors <= b.MaxErrors; numErrors++ {
+		min, max := b.GetBackoffRange(numErrors)
+		assert.True(t, min >= 3*time.Second, "min for %d errors: %v", numErrors, min)
+		assert.True(t, max >= min, "max for %d errors: %v", numErrors, max)
+		for i := 0; i < 10; i++ {
+			d := b.GetBackoffDuration(numErrors)
+			assert.True(t, d >= 3*time.Second, "duration for %d errors: %v", numErrors, d)
+		}
+	}
+
+	min, max := b.GetBackoffRange(1)
+	assert.Equal(t, 3*time.Second, min)
+	assert.Equal(t, 3*time.Second, max)
+	min, max = b.GetBackoffRange(3)
+	assert.Equal(t, 4*time.Second, min)
+	assert.Equal(t, 8*time.Second, max)
+}
+
+func TestBackoffDurationFrom(t *testing.T) {
+	b := NewPolicy(2, 1, 9, 2, false)
+
+	r1 := rand.New(rand.NewSource(42))
+	r2 := rand.New(rand.NewSource(42))
+	for numErrors := 0; numErrors <= b.MaxErrors; numErrors++ {
+		d := b.GetBackoffDurationFrom(r1, numErrors)
+		assert.Equal(t, d, b.GetBackoffDurationFrom(r2, numErrors), "duration for %d errors", numErrors)
+
+		min, max := b.GetBackoffRange(numErrors)
+		assert.True(t, d >= min && d <= max, "%v not in [%v, %v]", d, min, max)
+	}
+}
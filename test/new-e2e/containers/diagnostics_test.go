@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/runner"
+	"github.com/DataDog/datadog-agent/test/new-e2e/runner/parameters"
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/clients"
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/infra"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// collectDiagnosticsOnFailure writes the Pulumi export of the stack and the
+// ECS description of the agent tasks to the artifacts directory of the test
+// when it fails and the stack is kept, see infra.StackOptions.KeepOnFailure.
+// The artifacts directory is the artifacts_dir parameter, or a directory of
+// the temporary directory, followed by the name of the test.
+func collectDiagnosticsOnFailure(t *testing.T, stack *auto.Stack, s ecsStack, opts infra.StackOptions) {
+	if !opts.KeepOnFailure {
+		return
+	}
+
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+
+		dir, err := artifactsDir(t)
+		if err != nil {
+			t.Logf("Failed to create the artifacts directory: %v", err)
+			return
+		}
+
+		ctx := context.Background()
+		if stack != nil {
+			deployment, err := stack.Export(ctx)
+			if err != nil {
+				t.Logf("Failed to export stack %s: %v", stack.Name(), err)
+			} else {
+				writeArtifact(t, dir, "stack-export.json", deployment)
+			}
+		}
+
+		if s.clusterName != "" && s.taskFamily != "" {
+			tasks, err := clients.DescribeECSTasks(ctx, s.clusterName, s.taskFamily)
+			if err != nil {
+				t.Logf("Failed to describe the tasks of family %s: %v", s.taskFamily, err)
+			} else {
+				writeArtifact(t, dir, "agent-tasks.json", tasks)
+			}
+		}
+	})
+}
+
+func artifactsDir(t *testing.T) (string, error) {
+	root, err := runner.GetProfile().ParamStore().GetWithDefault(parameters.ArtifactsDir, filepath.Join(os.TempDir(), "dd-e2e-artifacts"))
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, strings.ReplaceAll(t.Name(), "/", "_"))
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+func writeArtifact(t *testing.T, dir, name string, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, name), content, 0o644)
+	}
+	if err != nil {
+		t.Logf("Failed to write %s: %v", name, err)
+		return
+	}
+	t.Logf("Wrote %s", filepath.Join(dir, name))
+}
@@ -7,23 +7,101 @@ package containers
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/runner"
 	"github.com/DataDog/datadog-agent/test/new-e2e/runner/parameters"
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/clients"
 	"github.com/DataDog/datadog-agent/test/new-e2e/utils/infra"
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/metrics"
 	"github.com/DataDog/test-infra-definitions/aws/scenarios/ecs"
 
-	"github.com/cenkalti/backoff"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/stretchr/testify/require"
 	datadog "gopkg.in/zorkian/go-datadog-api.v2"
 )
 
+const (
+	// fargateContainers is the number of containers of the agent task
+	fargateContainers = 3
+
+	// recoveryDeadline is how long the agent has to report metrics again
+	// after its task was stopped
+	recoveryDeadline = 10 * time.Minute
+	queryInterval    = 20 * time.Second
+
+	// windowsTaskDeadline is how long the Windows agent daemon task has to
+	// start once the stack is up, the Windows instances being slow to join
+	// the cluster
+	windowsTaskDeadline = 20 * time.Minute
+	// windowsMetric is only reported by the agent running on Windows
+	windowsMetric = "system.mem.pagefile.pct_free"
+)
+
+type ecsStack struct {
+	clusterName string
+	taskFamily  string
+	taskVersion float64
+}
+
 func TestAgentOnECS(t *testing.T) {
+	stack := getECSStack(t)
+
+	// Check content in Datadog
+	datadogClient := newDatadogClient(t)
+	containers, err := metrics.WaitForContainerCount(datadogClient, stack.clusterName, stack.taskFamily, stack.taskVersion, fargateContainers, metrics.MetricQueryOptions{})
+	require.NoError(t, err)
+	t.Logf("containers: %v", containers)
+}
+
+func TestAgentOnECSRecovers(t *testing.T) {
+	stack := getECSStack(t)
+
+	datadogClient := newDatadogClient(t)
+	query := metrics.ContainerCountQuery(stack.clusterName, stack.taskFamily, stack.taskVersion)
+	t.Log(query)
+
+	_, err := metrics.WaitForContainerCount(datadogClient, stack.clusterName, stack.taskFamily, stack.taskVersion, fargateContainers, metrics.MetricQueryOptions{})
+	require.NoError(t, err)
+
+	// Kill the agent task, the ECS service is expected to restart it
+	killedAt := time.Now()
+	stopped, err := clients.StopECSTasks(context.Background(), stack.clusterName, stack.taskFamily, "e2e fault injection")
+	require.NoError(t, err)
+	t.Logf("stopped tasks: %v", stopped)
+
+	// Metrics must resume from a new task
+	_, err = metrics.WaitForMetric(datadogClient, query, metrics.Options{
+		MinSeries: fargateContainers,
+		Tags:      metrics.ContainerCountTags(stack.clusterName, stack.taskFamily, stack.taskVersion),
+		Check:     metrics.ContainerCountCheck(fargateContainers),
+		Since:     killedAt,
+		MetricQueryOptions: metrics.MetricQueryOptions{
+			Interval: queryInterval,
+			Retries:  int(recoveryDeadline / queryInterval),
+		},
+	})
+	require.NoError(t, err, "metrics did not resume within %v after the agent task was stopped", recoveryDeadline)
+}
+
+func TestAgentOnECSWindows(t *testing.T) {
+	stack := getECSWindowsStack(t)
+
+	tasks, err := clients.WaitForECSTasks(context.Background(), stack.clusterName, stack.taskFamily, windowsTaskDeadline)
+	require.NoError(t, err)
+	t.Logf("running tasks: %v", tasks)
+
+	datadogClient := newDatadogClient(t)
+	query := stack.windowsQuery()
+	t.Log(query)
+
+	_, err = metrics.WaitForMetric(datadogClient, query, metrics.Options{})
+	require.NoError(t, err)
+}
+
+func getECSStack(t *testing.T) ecsStack {
 	// Creating the stack
 	stackConfig := runner.ConfigMap{
 		"ddinfra:aws/ecs/linuxECSOptimizedNodeGroup": auto.ConfigValue{Value: "false"},
@@ -32,42 +110,70 @@ func TestAgentOnECS(t *testing.T) {
 		"ddagent:deploy":                             auto.ConfigValue{Value: "true"},
 	}
 
-	_, stackOutput, err := infra.GetStackManager().GetStack(context.Background(), "ecs-cluster", stackConfig, ecs.Run, false)
+	// Stacks are kept after a failure to investigate it. The stack is shared
+	// by the tests of the package, so it is not destroyed after each of them
+	// but once by TestMain, when they all passed.
+	opts := infra.StackOptions{
+		KeepOnFailure: true,
+	}
+	pulumiStack, stackOutput, err := infra.GetStackManager().GetTestStack(context.Background(), t, "ecs-cluster", stackConfig, ecs.Run, false, opts)
 	require.NoError(t, err)
 
-	ecsClusterName := stackOutput.Outputs["ecs-cluster-name"].Value.(string)
-	ecsTaskFamily := stackOutput.Outputs["agent-fargate-task-family"].Value.(string)
-	ecsTaskVersion := stackOutput.Outputs["agent-fargate-task-version"].Value.(float64)
-
-	// Check content in Datadog
-	apiKey, err := runner.GetProfile().SecretStore().Get(parameters.APIKey)
+	var stack ecsStack
+	defer func() { collectDiagnosticsOnFailure(t, pulumiStack, stack, opts) }()
+	stack.clusterName, err = infra.GetStringOutput(stackOutput.Outputs, "ecs-cluster-name")
 	require.NoError(t, err)
-	appKey, err := runner.GetProfile().SecretStore().Get(parameters.APPKey)
+	stack.taskFamily, err = infra.GetStringOutput(stackOutput.Outputs, "agent-fargate-task-family")
 	require.NoError(t, err)
-	datadogClient := datadog.NewClient(apiKey, appKey)
-	query := fmt.Sprintf("avg:ecs.fargate.cpu.user{ecs_cluster_name:%s,ecs_task_family:%s,ecs_task_version:%.0f} by {ecs_container_name}", ecsClusterName, ecsTaskFamily, ecsTaskVersion)
-	t.Log(query)
+	stack.taskVersion, err = infra.GetFloatOutput(stackOutput.Outputs, "agent-fargate-task-version")
+	require.NoError(t, err)
+	return stack
+}
 
-	err = backoff.Retry(func() error {
-		currentTime := time.Now().Unix()
-		series, err := datadogClient.QueryMetrics(currentTime-120, currentTime, query)
-		if err != nil {
-			return err
-		}
+// getECSWindowsStack returns a stack with a Windows node group, whose task is
+// the Windows agent daemon. The ECS scenario does not export the Windows agent
+// task yet, so the test is skipped unless the ecs_windows parameter is set,
+// rather than deploying the Windows stack to find out.
+func getECSWindowsStack(t *testing.T) ecsStack {
+	enabled, err := runner.GetProfile().ParamStore().GetBoolWithDefault(parameters.ECSWindows, false)
+	require.NoError(t, err)
+	if !enabled {
+		t.Skipf("the Windows ECS stack is only deployed when the %s parameter is set", parameters.ECSWindows)
+	}
 
-		if len(series) == 0 {
-			return errors.New("No data yet")
-		}
+	stackConfig := runner.ConfigMap{
+		"ddinfra:aws/ecs/linuxECSOptimizedNodeGroup": auto.ConfigValue{Value: "false"},
+		"ddinfra:aws/ecs/linuxBottlerocketNodeGroup": auto.ConfigValue{Value: "false"},
+		"ddinfra:aws/ecs/windowsLTSCNodeGroup":       auto.ConfigValue{Value: "true"},
+		"ddagent:deploy":                             auto.ConfigValue{Value: "true"},
+	}
 
-		if len(series) != 3 {
-			return errors.New("Not all containers")
-		}
+	opts := infra.StackOptions{
+		DestroyOnSuccess: true,
+		KeepOnFailure:    true,
+	}
+	pulumiStack, stackOutput, err := infra.GetStackManager().GetTestStack(context.Background(), t, "ecs-cluster-windows", stackConfig, ecs.Run, false, opts)
+	require.NoError(t, err)
 
-		if series[0].Points[0][1] == nil || *series[0].Points[0][1] == 0 {
-			return errors.New("0-value")
-		}
+	var stack ecsStack
+	defer func() { collectDiagnosticsOnFailure(t, pulumiStack, stack, opts) }()
+	stack.clusterName, err = infra.GetStringOutput(stackOutput.Outputs, "ecs-cluster-name")
+	require.NoError(t, err)
+	stack.taskFamily, err = infra.GetStringOutput(stackOutput.Outputs, "agent-ec2-windows-task-family")
+	require.NoError(t, err)
+	stack.taskVersion, err = infra.GetFloatOutput(stackOutput.Outputs, "agent-ec2-windows-task-version")
+	require.NoError(t, err)
+	return stack
+}
+
+func (s ecsStack) windowsQuery() string {
+	return fmt.Sprintf("avg:%s{ecs_cluster_name:%s} by {host}", windowsMetric, s.clusterName)
+}
 
-		return nil
-	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(20*time.Second), 20))
+func newDatadogClient(t *testing.T) *datadog.Client {
+	apiKey, err := runner.GetProfile().SecretStore().Get(parameters.APIKey)
+	require.NoError(t, err)
+	appKey, err := runner.GetProfile().SecretStore().Get(parameters.APPKey)
 	require.NoError(t, err)
+	return datadog.NewClient(apiKey, appKey)
 }
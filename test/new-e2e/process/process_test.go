@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package process
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/e2e"
+	"github.com/DataDog/datadog-agent/test/new-e2e/utils/e2e/client"
+	ec2vm "github.com/DataDog/test-infra-definitions/aws/scenarios/vm/ec2VM"
+	"github.com/DataDog/test-infra-definitions/aws/scenarios/vm/os"
+	commonos "github.com/DataDog/test-infra-definitions/common/os"
+	"github.com/DataDog/test-infra-definitions/datadog/agent"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const processAgentConfig = `process_config:
+  process_collection:
+    enabled: true
+`
+
+type processEnv struct {
+	VM    *client.VM
+	Agent *client.Agent
+}
+
+type processSuite struct {
+	*e2e.Suite[processEnv]
+}
+
+func TestProcessSuite(t *testing.T) {
+	suite.Run(t, &processSuite{Suite: e2e.NewSuite("process-agent", &e2e.StackDefinition[processEnv]{
+		EnvFactory: func(ctx *pulumi.Context) (*processEnv, error) {
+			vm, err := ec2vm.NewUnixEc2VM(ctx, ec2vm.WithArch(os.UbuntuOS, commonos.AMD64Arch))
+			if err != nil {
+				return nil, err
+			}
+
+			installer, err := agent.NewInstaller(vm, agent.WithAgentConfig(processAgentConfig))
+			if err != nil {
+				return nil, err
+			}
+			return &processEnv{
+				VM:    client.NewVM(vm),
+				Agent: client.NewAgent(installer),
+			}, nil
+		},
+	})})
+}
+
+func (v *processSuite) TestLiveProcessReported() {
+	// a long running process of the workload, only reported when process collection is enabled
+	_, err := v.Env.VM.Execute("nohup sleep 86400 > /dev/null 2>&1 &")
+	require.NoError(v.T(), err)
+
+	hostname, err := v.Env.Agent.Hostname()
+	require.NoError(v.T(), err)
+
+	processes, err := client.NewProcessesClient()
+	require.NoError(v.T(), err)
+	processes.AssertProcessReported(v.T(), hostname, "sleep 86400")
+}
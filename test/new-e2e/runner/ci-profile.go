@@ -42,7 +42,7 @@ func NewCIProfile() (Profile, error) {
 	}
 
 	return ciProfile{
-		baseProfile: newProfile("e2eci", []string{"aws/agent-qa"}, &secretStore),
+		baseProfile: newProfile("e2eci", []string{"aws/agent-qa"}, &secretStore, false),
 		ciUniqueID:  pipelineID + "-" + projectID,
 	}, nil
 }
@@ -17,7 +17,7 @@ func NewLocalProfile() (Profile, error) {
 		return nil, fmt.Errorf("unable to create temporary folder at: %s, err: %w", workspaceFolder, err)
 	}
 
-	return localProfile{baseProfile: newProfile("e2elocal", []string{"aws/sandbox"}, nil)}, nil
+	return localProfile{baseProfile: newProfile("e2elocal", []string{"aws/sandbox"}, nil, true)}, nil
 }
 
 type localProfile struct {
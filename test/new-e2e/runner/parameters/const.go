@@ -13,4 +13,7 @@ const (
 	PulumiPassword      = "pulumi_password"
 	StackParameters     = "stack_params"
 	SkipDeleteOnFailure = "skip_delete_on_failure"
+	AllowEnvFallback    = "allow_env_fallback"
+	ArtifactsDir        = "artifacts_dir"
+	ECSWindows          = "ecs_windows"
 )
@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package parameters
+
+type fallbackStore struct {
+	primary  valueStore
+	fallback valueStore
+}
+
+// NewFallbackStore returns a store reading parameters from primary first, and
+// from fallback when primary fails. When both fail, the error of primary is
+// returned.
+func NewFallbackStore(primary, fallback Store) Store {
+	return newStore(fallbackStore{
+		primary:  primary.vs,
+		fallback: fallback.vs,
+	})
+}
+
+func (s fallbackStore) get(key string) (string, error) {
+	val, err := s.primary.get(key)
+	if err == nil {
+		return val, nil
+	}
+
+	if fallbackVal, fallbackErr := s.fallback.get(key); fallbackErr == nil {
+		return fallbackVal, nil
+	}
+	return "", err
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package parameters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackStore(t *testing.T) {
+	t.Setenv("E2E_TEST_PRIMARY_APP_KEY", "primary-app-key")
+	t.Setenv("E2E_TEST_FALLBACK_API_KEY", "fallback-api-key")
+	t.Setenv("E2E_TEST_FALLBACK_APP_KEY", "fallback-app-key")
+	store := NewFallbackStore(NewEnvStore("E2E_TEST_PRIMARY_"), NewEnvStore("E2E_TEST_FALLBACK_"))
+
+	// the primary store comes first
+	val, err := store.Get(APPKey)
+	require.NoError(t, err)
+	assert.Equal(t, "primary-app-key", val)
+
+	val, err = store.Get(APIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback-api-key", val)
+
+	// the error of the primary store is returned when both fail
+	_, err = store.Get(SSHKey)
+	assert.EqualError(t, err, "parameter E2E_TEST_PRIMARY_SSH_KEY not found")
+	assert.True(t, errors.As(err, &ParameterNotFoundError{}))
+}
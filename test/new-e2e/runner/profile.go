@@ -22,6 +22,10 @@ const (
 	GCP       CloudProvider = "gcp"
 	EnvPrefix               = "E2E_"
 
+	// SecretEnvFallbackPrefix prefixes the environment variables read when
+	// the secret store fails and AllowEnvFallback is true, like DD_API_KEY
+	SecretEnvFallbackPrefix = "DD_"
+
 	envSep = ","
 )
 
@@ -46,6 +50,9 @@ type Profile interface {
 	NamePrefix() string
 	// AllowDevMode returns if DevMode is allowed
 	AllowDevMode() bool
+	// AllowEnvFallback returns if the secrets missing from the secret store
+	// are read from the SecretEnvFallbackPrefix environment variables
+	AllowEnvFallback() bool
 }
 
 // Shared implementations for common profiles methods
@@ -54,9 +61,13 @@ type baseProfile struct {
 	environments []string
 	store        parameters.Store
 	secretStore  parameters.Store
+
+	allowEnvFallback bool
 }
 
-func newProfile(projectName string, environments []string, secretStore *parameters.Store) baseProfile {
+// newProfile builds the common part of profiles. The allow_env_fallback
+// parameter overrides allowEnvFallback, see Profile.AllowEnvFallback.
+func newProfile(projectName string, environments []string, secretStore *parameters.Store, allowEnvFallback bool) baseProfile {
 	p := baseProfile{
 		projectName:  projectName,
 		environments: environments,
@@ -69,6 +80,15 @@ func newProfile(projectName string, environments []string, secretStore *paramete
 		p.secretStore = *secretStore
 	}
 
+	if allow, err := p.store.GetBoolWithDefault(parameters.AllowEnvFallback, allowEnvFallback); err == nil {
+		allowEnvFallback = allow
+	}
+	p.allowEnvFallback = allowEnvFallback
+	if allowEnvFallback {
+		// the secret store comes first, the environment second
+		p.secretStore = parameters.NewFallbackStore(p.secretStore, parameters.NewEnvStore(SecretEnvFallbackPrefix))
+	}
+
 	return p
 }
 
@@ -88,6 +108,10 @@ func (p baseProfile) SecretStore() parameters.Store {
 	return p.secretStore
 }
 
+func (p baseProfile) AllowEnvFallback() bool {
+	return p.allowEnvFallback
+}
+
 func GetProfile() Profile {
 	initProfile.Do(func() {
 		var profileFunc func() (Profile, error) = NewLocalProfile
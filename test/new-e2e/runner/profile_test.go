@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/runner/parameters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretStoreEnvFallback(t *testing.T) {
+	t.Setenv("DD_API_KEY", "env-api-key")
+	t.Setenv("DD_APP_KEY", "env-app-key")
+
+	emptyStore := parameters.NewEnvStore("E2E_TEST_UNSET_")
+	p := newProfile("test", nil, &emptyStore, true)
+	assert.True(t, p.AllowEnvFallback())
+
+	apiKey, err := p.SecretStore().Get(parameters.APIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "env-api-key", apiKey)
+	appKey, err := p.SecretStore().Get(parameters.APPKey)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app-key", appKey)
+
+	// the store comes first
+	t.Setenv("E2E_TEST_UNSET_API_KEY", "store-api-key")
+	apiKey, err = p.SecretStore().Get(parameters.APIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "store-api-key", apiKey)
+
+	// the fallback is disabled by the profile or the allow_env_fallback parameter
+	p = newProfile("test", nil, &emptyStore, false)
+	assert.False(t, p.AllowEnvFallback())
+	_, err = p.SecretStore().Get(parameters.APPKey)
+	assert.Error(t, err)
+
+	t.Setenv("E2E_ALLOW_ENV_FALLBACK", "false")
+	p = newProfile("test", nil, &emptyStore, true)
+	assert.False(t, p.AllowEnvFallback())
+	_, err = p.SecretStore().Get(parameters.APPKey)
+	assert.Error(t, err)
+}
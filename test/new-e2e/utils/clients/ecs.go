@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var awsECSClient *ecs.Client
+
+// GetAWSECSClient returns an aws ECS client
+func GetAWSECSClient() (*ecs.Client, error) {
+	initLock.Lock()
+	defer initLock.Unlock()
+
+	if awsECSClient != nil {
+		return awsECSClient, nil
+	}
+
+	cfg, err := getAWSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	awsECSClient = ecs.NewFromConfig(*cfg)
+	return awsECSClient, nil
+}
+
+// StopECSTasks stops the running tasks of the given family in the cluster,
+// as a fault injection, and returns the ARNs of the stopped tasks. Tasks
+// managed by a service are restarted by ECS.
+func StopECSTasks(ctx context.Context, cluster, family, reason string) ([]string, error) {
+	client, err := GetAWSECSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		Family:        aws.String(family),
+		DesiredStatus: types.DesiredStatusRunning,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks.TaskArns) == 0 {
+		return nil, fmt.Errorf("no running task of family %s in cluster %s", family, cluster)
+	}
+
+	for _, task := range tasks.TaskArns {
+		if _, err := client.StopTask(ctx, &ecs.StopTaskInput{
+			Cluster: aws.String(cluster),
+			Task:    aws.String(task),
+			Reason:  aws.String(reason),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to stop task %s: %w", task, err)
+		}
+	}
+	return tasks.TaskArns, nil
+}
+
+// WaitForECSTasks waits until tasks of the given family are running in the
+// cluster, for at most maxWait, and returns their ARNs. Daemon tasks are only
+// listed once their container instance joined the cluster, so the tasks are
+// listed again until there is one.
+func WaitForECSTasks(ctx context.Context, cluster, family string, maxWait time.Duration) ([]string, error) {
+	client, err := GetAWSECSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	for {
+		tasks, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:       aws.String(cluster),
+			Family:        aws.String(family),
+			DesiredStatus: types.DesiredStatusRunning,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks.TaskArns) > 0 {
+			deadline, _ := ctx.Deadline()
+			err = ecs.NewTasksRunningWaiter(client).Wait(ctx, &ecs.DescribeTasksInput{
+				Cluster: aws.String(cluster),
+				Tasks:   tasks.TaskArns,
+			}, time.Until(deadline))
+			if err != nil {
+				return nil, fmt.Errorf("tasks of family %s in cluster %s are not running: %w", family, cluster, err)
+			}
+			return tasks.TaskArns, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no task of family %s in cluster %s after %v", family, cluster, maxWait)
+		case <-time.After(20 * time.Second):
+		}
+	}
+}
+
+// DescribeECSTasks returns the running and the recently stopped tasks of the
+// given family in the cluster.
+func DescribeECSTasks(ctx context.Context, cluster, family string) ([]types.Task, error) {
+	client, err := GetAWSECSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var taskArns []string
+	for _, status := range []types.DesiredStatus{types.DesiredStatusRunning, types.DesiredStatusStopped} {
+		tasks, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:       aws.String(cluster),
+			Family:        aws.String(family),
+			DesiredStatus: status,
+		})
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, tasks.TaskArns...)
+	}
+	if len(taskArns) == 0 {
+		return nil, nil
+	}
+
+	// DescribeTasks accepts up to 100 tasks, more than ListTasks returns
+	output, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   taskArns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Tasks, nil
+}
@@ -6,7 +6,13 @@
 package client
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
 	"github.com/DataDog/test-infra-definitions/datadog/agent"
+	"github.com/stretchr/testify/require"
 )
 
 var _ stackInitializer = (*Agent)(nil)
@@ -33,3 +39,76 @@ func (agent *Agent) init(auth *Authentification, data *agent.ClientData) error {
 func (agent *Agent) Status() (string, error) {
 	return agent.sshClient.Execute("sudo datadog-agent status")
 }
+
+// Hostname returns the hostname the agent reports its data with.
+func (agent *Agent) Hostname() (string, error) {
+	output, err := agent.sshClient.Execute("sudo datadog-agent hostname")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// IntegrationInstanceStatus is the status of one instance of an integration,
+// as reported by the runner stats of `datadog-agent status --json`.
+type IntegrationInstanceStatus struct {
+	CheckName    string
+	CheckID      string
+	TotalRuns    uint64
+	TotalErrors  uint64
+	LastError    string
+	LastWarnings []string
+}
+
+// IsOK returns true when the instance ran at least once and its last run
+// reported neither error nor warning.
+func (s IntegrationInstanceStatus) IsOK() bool {
+	return s.TotalRuns > 0 && s.LastError == "" && len(s.LastWarnings) == 0
+}
+
+// IntegrationStatus returns the status of every instance of the named integration.
+func (agent *Agent) IntegrationStatus(name string) ([]IntegrationInstanceStatus, error) {
+	output, err := agent.sshClient.Execute("sudo datadog-agent status --json")
+	if err != nil {
+		return nil, err
+	}
+	return parseIntegrationStatus([]byte(output), name)
+}
+
+// AssertIntegrationOK fails the test unless the named integration is scheduled
+// and all its instances are in the OK state.
+func (agent *Agent) AssertIntegrationOK(t *testing.T, name string) {
+	statuses, err := agent.IntegrationStatus(name)
+	require.NoError(t, err)
+	require.NoError(t, checkIntegrationOK(name, statuses))
+}
+
+func parseIntegrationStatus(output []byte, name string) ([]IntegrationInstanceStatus, error) {
+	var status struct {
+		RunnerStats struct {
+			Checks map[string]map[string]IntegrationInstanceStatus
+		} `json:"runnerStats"`
+	}
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil, fmt.Errorf("unable to parse agent status: %w", err)
+	}
+
+	var statuses []IntegrationInstanceStatus
+	for _, instance := range status.RunnerStats.Checks[name] {
+		statuses = append(statuses, instance)
+	}
+	return statuses, nil
+}
+
+func checkIntegrationOK(name string, statuses []IntegrationInstanceStatus) error {
+	if len(statuses) == 0 {
+		return fmt.Errorf("integration %s is not running", name)
+	}
+	for _, s := range statuses {
+		if !s.IsOK() {
+			return fmt.Errorf("integration %s instance %s is not OK: runs=%d errors=%d last error=%q warnings=%v",
+				name, s.CheckID, s.TotalRuns, s.TotalErrors, s.LastError, s.LastWarnings)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const agentStatusJSON = `{
+  "runnerStats": {
+    "Checks": {
+      "cpu": {
+        "cpu": {"CheckName": "cpu", "CheckID": "cpu", "TotalRuns": 3, "TotalErrors": 0, "LastError": "", "LastWarnings": []}
+      },
+      "redisdb": {
+        "redisdb:abc": {"CheckName": "redisdb", "CheckID": "redisdb:abc", "TotalRuns": 3, "TotalErrors": 3, "LastError": "connection refused", "LastWarnings": []}
+      }
+    }
+  }
+}`
+
+func TestIntegrationStatus(t *testing.T) {
+	statuses, err := parseIntegrationStatus([]byte(agentStatusJSON), "cpu")
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.NoError(t, checkIntegrationOK("cpu", statuses))
+
+	statuses, err = parseIntegrationStatus([]byte(agentStatusJSON), "redisdb")
+	require.NoError(t, err)
+	require.ErrorContains(t, checkIntegrationOK("redisdb", statuses), "connection refused")
+
+	statuses, err = parseIntegrationStatus([]byte(agentStatusJSON), "disk")
+	require.NoError(t, err)
+	require.ErrorContains(t, checkIntegrationOK("disk", statuses), "not running")
+
+	_, err = parseIntegrationStatus([]byte("not json"), "cpu")
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/test/new-e2e/runner"
+	"github.com/DataDog/datadog-agent/test/new-e2e/runner/parameters"
+	"github.com/cenkalti/backoff"
+	"github.com/stretchr/testify/require"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+const (
+	processesRetryInterval = 20 * time.Second
+	processesMaxRetries    = 20
+)
+
+// Process is a process reported by the process agent, as returned by the
+// live processes API.
+type Process struct {
+	Host    string
+	PID     int
+	Cmdline []string
+	Tags    []string
+}
+
+// ProcessesClient queries the live processes API of Datadog.
+type ProcessesClient struct {
+	apiKey     string
+	appKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewProcessesClient creates a ProcessesClient using the API and APP keys of
+// the runner profile.
+func NewProcessesClient() (*ProcessesClient, error) {
+	apiKey, err := runner.GetProfile().SecretStore().Get(parameters.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	appKey, err := runner.GetProfile().SecretStore().Get(parameters.APPKey)
+	if err != nil {
+		return nil, err
+	}
+	datadogClient := datadog.NewClient(apiKey, appKey)
+	return &ProcessesClient{
+		apiKey:     apiKey,
+		appKey:     appKey,
+		baseURL:    datadogClient.GetBaseUrl(),
+		httpClient: datadogClient.HttpClient,
+	}, nil
+}
+
+// GetProcesses returns the live processes of host whose command line matches search.
+func (c *ProcessesClient) GetProcesses(host, search string) ([]Process, error) {
+	query := url.Values{}
+	query.Set("search", search)
+	query.Set("tags", "host:"+host)
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/v2/processes?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("DD-API-KEY", c.apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", c.appKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s querying processes: %s", resp.Status, body)
+	}
+	return parseProcesses(body)
+}
+
+// AssertProcessReported waits until a process of host whose command line
+// contains name is reported to Datadog, and fails the test if it never is.
+func (c *ProcessesClient) AssertProcessReported(t *testing.T, host, name string) {
+	err := backoff.Retry(func() error {
+		processes, err := c.GetProcesses(host, name)
+		if err != nil {
+			return err
+		}
+		return findProcess(processes, host, name)
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(processesRetryInterval), processesMaxRetries))
+	require.NoError(t, err)
+}
+
+func parseProcesses(body []byte) ([]Process, error) {
+	var response struct {
+		Data []struct {
+			Attributes struct {
+				Host    string   `json:"host"`
+				PID     int      `json:"pid"`
+				Cmdline []string `json:"cmdline"`
+				Tags    []string `json:"tags"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	processes := make([]Process, 0, len(response.Data))
+	for _, data := range response.Data {
+		processes = append(processes, Process{
+			Host:    data.Attributes.Host,
+			PID:     data.Attributes.PID,
+			Cmdline: data.Attributes.Cmdline,
+			Tags:    data.Attributes.Tags,
+		})
+	}
+	return processes, nil
+}
+
+func findProcess(processes []Process, host, name string) error {
+	if len(processes) == 0 {
+		return errors.New("no process data yet")
+	}
+	for _, p := range processes {
+		if p.Host == host && strings.Contains(strings.Join(p.Cmdline, " "), name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("process %q not found among the %d processes reported by %s", name, len(processes), host)
+}
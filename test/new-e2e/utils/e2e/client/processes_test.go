@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProcess(t *testing.T) {
+	processes, err := parseProcesses([]byte(`{
+  "data": [
+    {
+      "type": "process",
+      "id": "abc",
+      "attributes": {
+        "host": "my-host",
+        "pid": 42,
+        "cmdline": ["/opt/datadog-agent/embedded/bin/process-agent", "--cfgpath=/etc/datadog-agent/datadog.yaml"],
+        "tags": ["host:my-host"]
+      }
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	require.Len(t, processes, 1)
+	require.Equal(t, 42, processes[0].PID)
+
+	require.NoError(t, findProcess(processes, "my-host", "process-agent"))
+	require.ErrorContains(t, findProcess(processes, "my-host", "nginx"), `process "nginx" not found`)
+	require.ErrorContains(t, findProcess(processes, "other-host", "process-agent"), "not found")
+	require.ErrorContains(t, findProcess(nil, "my-host", "process-agent"), "no process data yet")
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	agentAuthTokenPath = "/etc/datadog-agent/auth_token"
+	agentCmdPort       = 5001
+	apiKeyValidStatus  = "API Key valid"
+)
+
+// StatusPage is the subset of the agent status served by its local API
+// that is checked by AssertStatusPageHealthy.
+type StatusPage struct {
+	ForwarderStats *struct {
+		APIKeyStatus map[string]string
+		Transactions struct {
+			DroppedOnInput int
+			Errors         int
+		}
+	} `json:"forwarderStats"`
+	RunnerStats *struct {
+		Runs int
+	} `json:"runnerStats"`
+}
+
+// StatusPage fetches the status page from the agent local API.
+func (agent *Agent) StatusPage() (*StatusPage, error) {
+	output, err := agent.sshClient.Execute(fmt.Sprintf(
+		`sudo curl -sSfk -H "Authorization: Bearer $(sudo cat %s)" https://localhost:%d/agent/status`,
+		agentAuthTokenPath, agentCmdPort))
+	if err != nil {
+		return nil, err
+	}
+	return parseStatusPage([]byte(output))
+}
+
+// AssertStatusPageHealthy fails the test unless the agent status page is
+// reachable and reports healthy Forwarder and Collector sections.
+func (agent *Agent) AssertStatusPageHealthy(t *testing.T) {
+	page, err := agent.StatusPage()
+	require.NoError(t, err)
+	require.NoError(t, page.checkHealthy())
+}
+
+func parseStatusPage(output []byte) (*StatusPage, error) {
+	var page StatusPage
+	if err := json.Unmarshal(output, &page); err != nil {
+		return nil, fmt.Errorf("unable to parse agent status page: %w", err)
+	}
+	return &page, nil
+}
+
+func (page *StatusPage) checkHealthy() error {
+	var problems []string
+
+	if page.ForwarderStats == nil {
+		problems = append(problems, "missing Forwarder section")
+	} else {
+		for apiKey, status := range page.ForwarderStats.APIKeyStatus {
+			if status != apiKeyValidStatus {
+				problems = append(problems, fmt.Sprintf("forwarder API key %s: %s", apiKey, status))
+			}
+		}
+		if dropped := page.ForwarderStats.Transactions.DroppedOnInput; dropped > 0 {
+			problems = append(problems, fmt.Sprintf("forwarder dropped %d transactions on input", dropped))
+		}
+	}
+
+	if page.RunnerStats == nil {
+		problems = append(problems, "missing Collector section")
+	} else if page.RunnerStats.Runs == 0 {
+		problems = append(problems, "collector did not run any check")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusPageHealthy(t *testing.T) {
+	page, err := parseStatusPage([]byte(`{
+  "forwarderStats": {
+    "APIKeyStatus": {"API key ending with abcde": "API Key valid"},
+    "Transactions": {"DroppedOnInput": 0, "Errors": 2}
+  },
+  "runnerStats": {"Runs": 12}
+}`))
+	require.NoError(t, err)
+	require.NoError(t, page.checkHealthy())
+}
+
+func TestStatusPageUnhealthy(t *testing.T) {
+	page, err := parseStatusPage([]byte(`{
+  "forwarderStats": {
+    "APIKeyStatus": {"API key ending with abcde": "API Key invalid"},
+    "Transactions": {"DroppedOnInput": 3}
+  }
+}`))
+	require.NoError(t, err)
+	err = page.checkHealthy()
+	require.ErrorContains(t, err, "API Key invalid")
+	require.ErrorContains(t, err, "dropped 3 transactions")
+	require.ErrorContains(t, err, "missing Collector section")
+}
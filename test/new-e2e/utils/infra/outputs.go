@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// GetStringOutput returns the string value of the key output of a stack.
+func GetStringOutput(outputs auto.OutputMap, key string) (string, error) {
+	value, err := getOutput(outputs, key)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("stack output %q is a %T, not a string", key, value)
+	}
+	return s, nil
+}
+
+// GetFloatOutput returns the numeric value of the key output of a stack.
+func GetFloatOutput(outputs auto.OutputMap, key string) (float64, error) {
+	value, err := getOutput(outputs, key)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("stack output %q is a %T, not a number", key, value)
+	}
+	return f, nil
+}
+
+func getOutput(outputs auto.OutputMap, key string) (interface{}, error) {
+	output, found := outputs[key]
+	if !found {
+		return nil, fmt.Errorf("stack output %q is missing", key)
+	}
+	return output.Value, nil
+}
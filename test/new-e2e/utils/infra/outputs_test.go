@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackOutputs(t *testing.T) {
+	outputs := auto.OutputMap{
+		"cluster-name": auto.OutputValue{Value: "cluster"},
+		"task-version": auto.OutputValue{Value: float64(3)},
+	}
+
+	s, err := GetStringOutput(outputs, "cluster-name")
+	require.NoError(t, err)
+	assert.Equal(t, "cluster", s)
+
+	f, err := GetFloatOutput(outputs, "task-version")
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), f)
+
+	_, err = GetStringOutput(outputs, "task-family")
+	assert.EqualError(t, err, `stack output "task-family" is missing`)
+
+	_, err = GetStringOutput(outputs, "task-version")
+	assert.EqualError(t, err, `stack output "task-version" is a float64, not a string`)
+
+	_, err = GetFloatOutput(outputs, "cluster-name")
+	assert.EqualError(t, err, `stack output "cluster-name" is a string, not a number`)
+}
@@ -8,11 +8,13 @@ package infra
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"path"
 	"runtime"
 	"strings"
 	"sync"
+	"testing"
 	"time"
 
 	"github.com/DataDog/datadog-agent/test/new-e2e/runner"
@@ -32,12 +34,33 @@ const (
 	stackUpTimeout      = 60 * time.Minute
 	stackDestroyTimeout = 60 * time.Minute
 	stackDeleteTimeout  = 20 * time.Minute
+
+	defaultStackUpMaxRetries = 3
 )
 
 var (
 	workspaceFolder  = path.Join(os.TempDir(), e2eWorkspaceDirectory)
 	stackManager     *StackManager
 	initStackManager sync.Once
+
+	// stackUpRetryInterval is the delay before the first retry of a failed
+	// stack up, doubled for each following retry
+	stackUpRetryInterval = 30 * time.Second
+
+	// retryableStackUpErrors are substrings of the stack up errors caused by
+	// transient AWS or Pulumi failures
+	retryableStackUpErrors = []string{
+		"Throttling",
+		"RequestLimitExceeded",
+		"TooManyRequestsException",
+		"ServiceUnavailable",
+		"InternalFailure",
+		"connection reset by peer",
+		"i/o timeout",
+		"TLS handshake timeout",
+		"error reading from server: EOF",
+		"the stack is currently locked",
+	}
 )
 
 // StackManager handles
@@ -46,6 +69,27 @@ type StackManager struct {
 	lock   sync.RWMutex
 }
 
+// StackOptions controls what happens to a stack at the end of the test using
+// it, see GetTestStack.
+type StackOptions struct {
+	// DestroyOnSuccess destroys the stack when the test passed. A stack shared
+	// by several tests should rather be destroyed once they all ran, with
+	// StackManager.Cleanup, not to be destroyed and created again for each.
+	DestroyOnSuccess bool
+	// KeepOnFailure keeps the stack when the test failed, to debug it, instead
+	// of destroying it
+	KeepOnFailure bool
+	// MaxRetries is the number of times a stack up failing with a transient
+	// error is retried. Zero uses the default of 3, a negative value disables
+	// the retries.
+	MaxRetries int
+	// SkipUpdateIfExists returns the outputs of the stack without running up
+	// when its last update succeeded, to iterate quickly on the assertions of
+	// a test against an existing stack. The configuration and the program of
+	// the stack are not checked.
+	SkipUpdateIfExists bool
+}
+
 func GetStackManager() *StackManager {
 	initStackManager.Do(func() {
 		var err error
@@ -65,8 +109,13 @@ func newStackManager(ctx context.Context) (*StackManager, error) {
 	}, nil
 }
 
-// GetStack creates or return a stack based on stack name and config
+// GetStack creates or return a stack based on stack name and config. Stack ups
+// failing with a transient error are retried, see StackOptions.MaxRetries.
 func (sm *StackManager) GetStack(ctx context.Context, name string, config runner.ConfigMap, deployFunc pulumi.RunFunc, failOnMissing bool) (*auto.Stack, auto.UpResult, error) {
+	return sm.getStack(ctx, name, config, deployFunc, failOnMissing, StackOptions{})
+}
+
+func (sm *StackManager) getStack(ctx context.Context, name string, config runner.ConfigMap, deployFunc pulumi.RunFunc, failOnMissing bool, opts StackOptions) (*auto.Stack, auto.UpResult, error) {
 	sm.lock.RLock()
 	defer sm.lock.RUnlock()
 
@@ -100,27 +149,126 @@ func (sm *StackManager) GetStack(ctx context.Context, name string, config runner
 		sm.stacks[name] = stack
 	}
 
+	if opts.SkipUpdateIfExists {
+		upResult, deployed, err := deployedStackResult(ctx, stack)
+		if err != nil {
+			return nil, auto.UpResult{}, err
+		}
+		if deployed {
+			return stack, upResult, nil
+		}
+	}
+
 	err = stack.SetAllConfig(ctx, cm.ToPulumi())
 	if err != nil {
 		return nil, auto.UpResult{}, err
 	}
 
-	upCtx, cancel := context.WithTimeout(ctx, stackUpTimeout)
-	var loglevel uint = 1
-	defer cancel()
-	upResult, err := stack.Up(upCtx, optup.ProgressStreams(os.Stderr), optup.DebugLogging(debug.LoggingOptions{
-		LogToStdErr:   true,
-		FlowToPlugins: true,
-		LogLevel:      &loglevel,
-	}))
+	upResult, err := upWithRetry(ctx, opts.MaxRetries, func(ctx context.Context) (auto.UpResult, error) {
+		upCtx, cancel := context.WithTimeout(ctx, stackUpTimeout)
+		var loglevel uint = 1
+		defer cancel()
+		return stack.Up(upCtx, optup.ProgressStreams(os.Stderr), optup.DebugLogging(debug.LoggingOptions{
+			LogToStdErr:   true,
+			FlowToPlugins: true,
+			LogLevel:      &loglevel,
+		}))
+	})
 	return stack, upResult, err
 }
 
+// deployedStackResult returns the outputs of the stack as the result of an up
+// and true when the last update of the stack succeeded.
+func deployedStackResult(ctx context.Context, stack *auto.Stack) (auto.UpResult, bool, error) {
+	history, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		return auto.UpResult{}, false, err
+	}
+	if !isDeployed(history) {
+		return auto.UpResult{}, false, nil
+	}
+
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return auto.UpResult{}, false, err
+	}
+	return auto.UpResult{Outputs: outputs, Summary: history[0]}, true, nil
+}
+
+// isDeployed returns whether the last operation of the history, the first
+// one, is a successful update. A destroyed stack or a failed update is not
+// deployed.
+func isDeployed(history []auto.UpdateSummary) bool {
+	return len(history) > 0 && history[0].Kind == "update" && history[0].Result == "succeeded"
+}
+
+// upWithRetry calls up until it succeeds, fails with an error that is not
+// retryable or maxRetries retries have been made. The delay between the
+// attempts grows exponentially, with jitter to avoid retrying the concurrent
+// stacks at the same time.
+func upWithRetry(ctx context.Context, maxRetries int, up func(context.Context) (auto.UpResult, error)) (auto.UpResult, error) {
+	if maxRetries == 0 {
+		maxRetries = defaultStackUpMaxRetries
+	}
+
+	interval := stackUpRetryInterval
+	for retry := 0; ; retry++ {
+		upResult, err := up(ctx)
+		if err == nil || retry >= maxRetries || !isRetryableStackUpError(err) {
+			return upResult, err
+		}
+
+		// wait between interval/2 and interval
+		delay := interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+		fmt.Fprintf(os.Stderr, "Stack up failed with a transient error, retrying in %v (%d/%d): %v\n", delay, retry+1, maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return upResult, err
+		case <-time.After(delay):
+		}
+		interval *= 2
+	}
+}
+
+func isRetryableStackUpError(err error) bool {
+	msg := err.Error()
+	for _, retryable := range retryableStackUpErrors {
+		if strings.Contains(msg, retryable) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTestStack is GetStack, destroying the stack at the end of the test as
+// requested by opts.
+func (sm *StackManager) GetTestStack(ctx context.Context, t testing.TB, name string, config runner.ConfigMap, deployFunc pulumi.RunFunc, failOnMissing bool, opts StackOptions) (*auto.Stack, auto.UpResult, error) {
+	t.Cleanup(func() {
+		if t.Failed() && opts.KeepOnFailure {
+			t.Logf("Keeping stack %s of the failed test", name)
+			return
+		}
+		if !t.Failed() && !opts.DestroyOnSuccess {
+			return
+		}
+		if err := sm.DeleteStack(context.Background(), name); err != nil {
+			t.Logf("Failed to delete stack %s: %v", name, err)
+		}
+	})
+	return sm.getStack(ctx, name, config, deployFunc, failOnMissing, opts)
+}
+
+// DeleteStack destroys the stack and removes it. A later GetStack with the same
+// name creates it again.
 func (sm *StackManager) DeleteStack(ctx context.Context, name string) error {
 	sm.lock.Lock()
 	defer sm.lock.Unlock()
 
-	return sm.deleteStack(ctx, name, sm.stacks[name])
+	if err := sm.deleteStack(ctx, name, sm.stacks[name]); err != nil {
+		return err
+	}
+	delete(sm.stacks, name)
+	return nil
 }
 
 func (sm *StackManager) Cleanup(ctx context.Context) []error {
@@ -133,7 +281,9 @@ func (sm *StackManager) Cleanup(ctx context.Context) []error {
 		err := sm.deleteStack(ctx, stackID, stack)
 		if err != nil {
 			errors = append(errors, err)
+			continue
 		}
+		delete(sm.stacks, stackID)
 	}
 
 	return errors
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpWithRetry(t *testing.T) {
+	defer func(interval time.Duration) { stackUpRetryInterval = interval }(stackUpRetryInterval)
+	stackUpRetryInterval = time.Millisecond
+
+	transientErr := errors.New("creating ECS Cluster: ThrottlingException: Rate exceeded")
+	fakeUp := func(failures int, err error) (func(context.Context) (auto.UpResult, error), *int) {
+		calls := 0
+		return func(context.Context) (auto.UpResult, error) {
+			calls++
+			if calls <= failures {
+				return auto.UpResult{}, err
+			}
+			return auto.UpResult{Summary: auto.UpdateSummary{Result: "succeeded"}}, nil
+		}, &calls
+	}
+
+	t.Run("transient errors", func(t *testing.T) {
+		up, calls := fakeUp(2, transientErr)
+		upResult, err := upWithRetry(context.Background(), 0, up)
+		require.NoError(t, err)
+		assert.Equal(t, "succeeded", upResult.Summary.Result)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		up, calls := fakeUp(2, transientErr)
+		_, err := upWithRetry(context.Background(), 1, up)
+		assert.ErrorIs(t, err, transientErr)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		up, calls := fakeUp(2, transientErr)
+		_, err := upWithRetry(context.Background(), -1, up)
+		assert.ErrorIs(t, err, transientErr)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("non-retryable error", func(t *testing.T) {
+		authErr := errors.New("creating ECS Cluster: UnrecognizedClientException: The security token included in the request is invalid")
+		up, calls := fakeUp(2, authErr)
+		_, err := upWithRetry(context.Background(), 0, up)
+		assert.ErrorIs(t, err, authErr)
+		assert.Equal(t, 1, *calls)
+	})
+}
+
+func TestIsDeployed(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		history  []auto.UpdateSummary
+		deployed bool
+	}{
+		{"new stack", nil, false},
+		{"updated", []auto.UpdateSummary{{Kind: "update", Result: "succeeded"}}, true},
+		{"failed update", []auto.UpdateSummary{{Kind: "update", Result: "failed"}, {Kind: "update", Result: "succeeded"}}, false},
+		{"update in progress", []auto.UpdateSummary{{Kind: "update", Result: "in-progress"}}, false},
+		{"destroyed", []auto.UpdateSummary{{Kind: "destroy", Result: "succeeded"}, {Kind: "update", Result: "succeeded"}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.deployed, isDeployed(tc.history))
+		})
+	}
+}
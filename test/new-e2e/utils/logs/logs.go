@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package logs provides helpers to check the logs received by Datadog.
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+const (
+	defaultWindow   = 15 * time.Minute
+	defaultInterval = 20 * time.Second
+	defaultRetries  = 20
+	defaultMaxLogs  = 1000
+
+	// pageSize is the maximum number of logs returned by a query
+	pageSize = 1000
+	// samples is the number of logs quoted when none matches
+	samples = 3
+)
+
+// Client is the subset of the Datadog API client used to search logs.
+type Client interface {
+	GetLogsList(logsRequest *datadog.LogsListRequest) (*datadog.LogsList, error)
+}
+
+// Options tells where WaitForLog looks for logs. The zero value searches the
+// logs of the last 15 minutes, every 20 seconds up to 20 times.
+type Options struct {
+	// Window is how far back the search looks, 15 minutes when not set
+	Window time.Duration
+	// Since, when set, excludes the logs before it
+	Since time.Time
+	// MaxLogs is the maximum number of logs read by a search, across pages,
+	// 1000 when not set
+	MaxLogs int
+
+	// Interval is the delay between two searches, 20 seconds when not set
+	Interval time.Duration
+	// Retries is the number of searches after the first one, 20 when not set
+	Retries uint64
+}
+
+// WaitForLog searches the logs matching the query until some of them satisfy
+// the predicate, and returns those. A nil predicate accepts any log. The error
+// returned after the last retry describes the logs seen by the last search.
+func WaitForLog(client Client, query string, predicate func(datadog.Logs) bool, opts Options) ([]datadog.Logs, error) {
+	opts = opts.withDefaults()
+	if predicate == nil {
+		predicate = func(datadog.Logs) bool { return true }
+	}
+
+	var matching []datadog.Logs
+	attempts := 0
+	err := backoff.Retry(func() error {
+		attempts++
+		to := time.Now()
+		from := to.Add(-opts.Window)
+		if !opts.Since.IsZero() && opts.Since.After(from) {
+			from = opts.Since
+		}
+		logs, err := search(client, query, from, to, opts.MaxLogs)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range logs {
+			if predicate(log) {
+				matching = append(matching, log)
+			}
+		}
+		if len(matching) == 0 {
+			return fmt.Errorf("none of the %d log(s) found matches: %s", len(logs), describeLogs(logs))
+		}
+		return nil
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(opts.Interval), opts.Retries))
+	if err != nil {
+		return nil, fmt.Errorf("query %q did not match after %d attempt(s): %w", query, attempts, err)
+	}
+	return matching, nil
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Window <= 0 {
+		opts.Window = defaultWindow
+	}
+	if opts.MaxLogs <= 0 {
+		opts.MaxLogs = defaultMaxLogs
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.Retries == 0 {
+		opts.Retries = defaultRetries
+	}
+	return opts
+}
+
+// search returns up to maxLogs logs matching the query between from and to,
+// following the pages of the results.
+func search(client Client, query string, from, to time.Time, maxLogs int) ([]datadog.Logs, error) {
+	request := &datadog.LogsListRequest{
+		Query: datadog.String(query),
+		Sort:  datadog.String("desc"),
+		Time: &datadog.LogsListRequestQueryTime{
+			TimeFrom: datadog.String(from.UTC().Format(time.RFC3339)),
+			TimeTo:   datadog.String(to.UTC().Format(time.RFC3339)),
+		},
+	}
+
+	var logs []datadog.Logs
+	for len(logs) < maxLogs {
+		limit := maxLogs - len(logs)
+		if limit > pageSize {
+			limit = pageSize
+		}
+		request.Limit = datadog.Int(limit)
+
+		page, err := client.GetLogsList(request)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, page.Logs...)
+		if page.NextLogID == nil || len(page.Logs) == 0 {
+			break
+		}
+		request.StartAt = page.NextLogID
+	}
+	return logs, nil
+}
+
+// describeLogs returns a human readable summary of the first logs.
+func describeLogs(logs []datadog.Logs) string {
+	descriptions := make([]string, 0, samples)
+	for i, log := range logs {
+		if i == samples {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(logs)-samples))
+			break
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s: %q", log.Content.GetService(), log.Content.GetMessage()))
+	}
+	return "[" + strings.Join(descriptions, ", ") + "]"
+}
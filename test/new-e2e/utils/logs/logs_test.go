@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// fakeClient serves its logs in pages of pageLen logs. ready is the number of
+// searches after which the logs appear.
+type fakeClient struct {
+	logs     []datadog.Logs
+	pageLen  int
+	ready    int
+	searches int
+	requests []datadog.LogsListRequest
+}
+
+func (c *fakeClient) GetLogsList(request *datadog.LogsListRequest) (*datadog.LogsList, error) {
+	c.requests = append(c.requests, *request)
+	start := 0
+	if request.StartAt == nil {
+		c.searches++
+	} else {
+		start, _ = strconv.Atoi(*request.StartAt)
+	}
+	if c.searches <= c.ready {
+		return &datadog.LogsList{}, nil
+	}
+
+	end := start + c.pageLen
+	if end > start+request.GetLimit() {
+		end = start + request.GetLimit()
+	}
+	list := &datadog.LogsList{}
+	if end < len(c.logs) {
+		list.NextLogID = datadog.String(fmt.Sprint(end))
+	} else {
+		end = len(c.logs)
+	}
+	list.Logs = c.logs[start:end]
+	return list, nil
+}
+
+func newLogs(service string, messages ...string) []datadog.Logs {
+	var logs []datadog.Logs
+	for _, message := range messages {
+		logs = append(logs, datadog.Logs{Content: datadog.LogsContent{
+			Service: datadog.String(service),
+			Message: datadog.String(message),
+		}})
+	}
+	return logs
+}
+
+func TestWaitForLog(t *testing.T) {
+	client := &fakeClient{
+		logs:    newLogs("agent", "starting", "running", "ready", "stopping"),
+		pageLen: 2,
+		ready:   2,
+	}
+
+	logs, err := WaitForLog(client, "service:agent", func(log datadog.Logs) bool {
+		return log.Content.GetMessage() == "ready"
+	}, Options{Interval: time.Millisecond})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "ready", logs[0].Content.GetMessage())
+	assert.Equal(t, 3, client.searches)
+	// the pages of the last search were all read
+	assert.Len(t, client.requests, 4)
+	assert.Equal(t, "service:agent", client.requests[3].GetQuery())
+}
+
+func TestWaitForLogMaxLogs(t *testing.T) {
+	client := &fakeClient{logs: newLogs("agent", "a", "b", "c", "d", "e"), pageLen: 2}
+
+	logs, err := WaitForLog(client, "service:agent", nil, Options{MaxLogs: 3})
+	require.NoError(t, err)
+	assert.Len(t, logs, 3)
+	require.Len(t, client.requests, 2)
+	assert.Equal(t, 1, client.requests[1].GetLimit())
+}
+
+func TestWaitForLogError(t *testing.T) {
+	client := &fakeClient{logs: newLogs("agent", "a", "b", "c", "d"), pageLen: 10}
+
+	_, err := WaitForLog(client, "service:agent", func(datadog.Logs) bool { return false }, Options{Interval: time.Millisecond, Retries: 1})
+	require.Error(t, err)
+	assert.Equal(t, 2, client.searches)
+	assert.Contains(t, err.Error(), `query "service:agent" did not match after 2 attempt(s)`)
+	assert.Contains(t, err.Error(), `none of the 4 log(s) found matches: [agent: "a", agent: "b", agent: "c", and 1 more]`)
+}
+
+func TestWaitForLogSince(t *testing.T) {
+	client := &fakeClient{logs: newLogs("agent", "a"), pageLen: 10}
+
+	since := time.Now().Add(-time.Minute).Truncate(time.Second)
+	_, err := WaitForLog(client, "service:agent", nil, Options{Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, since.UTC().Format(time.RFC3339), client.requests[0].Time.GetTimeFrom())
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+const containerNameTag = "ecs_container_name:"
+
+// ContainerCountQuery returns the query of the CPU usage of the containers of
+// an ECS Fargate task, one series per container.
+func ContainerCountQuery(clusterName, family string, version float64) string {
+	return fmt.Sprintf("avg:ecs.fargate.cpu.user{ecs_cluster_name:%s,ecs_task_family:%s,ecs_task_version:%.0f} by {ecs_container_name}", clusterName, family, version)
+}
+
+// ContainerCountTags returns the tags of the series of ContainerCountQuery.
+func ContainerCountTags(clusterName, family string, version float64) map[string]string {
+	return map[string]string{
+		"ecs_cluster_name":   clusterName,
+		"ecs_task_family":    family,
+		"ecs_task_version":   fmt.Sprintf("%.0f", version),
+		"ecs_container_name": "",
+	}
+}
+
+// ContainerCountCheck returns an Options.Check accepting the series of
+// exactly expected distinct containers. Its errors list the containers seen.
+func ContainerCountCheck(expected int) func([]datadog.Series) error {
+	return func(series []datadog.Series) error {
+		names := ContainerNames(series)
+		if len(names) != expected {
+			return fmt.Errorf("expected %d containers, got %d: %s", expected, len(names), strings.Join(names, ", "))
+		}
+		return nil
+	}
+}
+
+// WaitForContainerCount waits until Datadog reports the CPU usage of exactly
+// expected distinct containers for the given ECS Fargate task, and returns
+// their names.
+func WaitForContainerCount(client Client, clusterName, family string, version float64, expected int, opts MetricQueryOptions) ([]string, error) {
+	series, err := WaitForMetric(client, ContainerCountQuery(clusterName, family, version), Options{
+		MetricQueryOptions: opts,
+		MinSeries:          expected,
+		Tags:               ContainerCountTags(clusterName, family, version),
+		Check:              ContainerCountCheck(expected),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ContainerNames(series), nil
+}
+
+// ContainerNames returns the sorted distinct ecs_container_name tag values of
+// the series scopes.
+func ContainerNames(series []datadog.Series) []string {
+	seen := make(map[string]struct{}, len(series))
+	names := make([]string, 0, len(series))
+	for _, s := range series {
+		for _, tag := range strings.Split(s.GetScope(), ",") {
+			name := strings.TrimPrefix(tag, containerNameTag)
+			if name == tag {
+				continue
+			}
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+func TestContainerCountCheck(t *testing.T) {
+	series := []datadog.Series{
+		newSeries("ecs_cluster_name:c,ecs_container_name:datadog-agent", 1),
+		newSeries("ecs_cluster_name:c,ecs_container_name:aws-firelens", 1),
+		newSeries("ecs_cluster_name:c,ecs_container_name:datadog-agent", 2),
+	}
+
+	assert.Equal(t, []string{"aws-firelens", "datadog-agent"}, ContainerNames(series))
+	assert.NoError(t, ContainerCountCheck(2)(series))
+	err := ContainerCountCheck(3)(series)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 3 containers, got 2: aws-firelens, datadog-agent")
+}
+
+func TestContainerCountQuery(t *testing.T) {
+	assert.Equal(t,
+		"avg:ecs.fargate.cpu.user{ecs_cluster_name:cluster,ecs_task_family:agent,ecs_task_version:3} by {ecs_container_name}",
+		ContainerCountQuery("cluster", "agent", 3))
+}
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package metrics provides helpers to check the metrics received by Datadog.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+const (
+	defaultLookback = 2 * time.Minute
+	defaultInterval = 20 * time.Second
+	defaultRetries  = 20
+)
+
+// Client is the subset of the Datadog API client used to query metrics.
+type Client interface {
+	QueryMetrics(from, to int64, query string) ([]datadog.Series, error)
+}
+
+// MetricQueryOptions tells how the metrics are queried. The zero value
+// queries the last 2 minutes every 20 seconds, up to 20 times.
+type MetricQueryOptions struct {
+	// Lookback is how far back the query looks, 2 minutes when not set
+	Lookback time.Duration
+	// Retries is the number of queries after the first one, 20 when not set
+	Retries int
+	// Interval is the delay between two queries, 20 seconds when not set
+	Interval time.Duration
+}
+
+// Options tells what WaitForMetric waits for. The zero value waits for at
+// least one series with a positive point, with the MetricQueryOptions
+// defaults.
+type Options struct {
+	MetricQueryOptions
+
+	// MinSeries is the minimum number of series the query must return, 1
+	// when not set
+	MinSeries int
+	// Threshold is the value every series must exceed in at least one point
+	Threshold float64
+	// Since, when set, excludes the points before it
+	Since time.Time
+	// Tags are the tags every series must carry, see Series.AssertTags
+	Tags map[string]string
+	// Check, when set, is an additional predicate over the series matching
+	// all the other options
+	Check func([]datadog.Series) error
+}
+
+// WaitForMetric queries Datadog until the series returned by the query match
+// opts, and returns them. The error returned after the last retry describes
+// the series seen by the last query.
+func WaitForMetric(client Client, query string, opts Options) ([]datadog.Series, error) {
+	opts = opts.withDefaults()
+
+	var matching []datadog.Series
+	attempts := 0
+	err := backoff.Retry(func() error {
+		attempts++
+		to := time.Now()
+		from := to.Add(-opts.Lookback)
+		if !opts.Since.IsZero() && opts.Since.After(from) {
+			from = opts.Since
+		}
+		series, err := client.QueryMetrics(from.Unix(), to.Unix(), query)
+		if err != nil {
+			return err
+		}
+		if err := opts.match(series); err != nil {
+			return err
+		}
+		matching = series
+		return nil
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(opts.Interval), uint64(opts.Retries)))
+	if err != nil {
+		return nil, fmt.Errorf("query %q did not match after %d attempt(s): %w", query, attempts, err)
+	}
+	return matching, nil
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.MinSeries <= 0 {
+		opts.MinSeries = 1
+	}
+	opts.MetricQueryOptions = opts.MetricQueryOptions.withDefaults()
+	return opts
+}
+
+func (opts MetricQueryOptions) withDefaults() MetricQueryOptions {
+	if opts.Lookback <= 0 {
+		opts.Lookback = defaultLookback
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = defaultRetries
+	}
+	return opts
+}
+
+// match returns an error describing the series unless they match opts.
+func (opts Options) match(series []datadog.Series) error {
+	if len(series) == 0 {
+		return errors.New("no data yet")
+	}
+	if len(series) < opts.MinSeries {
+		return fmt.Errorf("expected at least %d series, got %d: %s", opts.MinSeries, len(series), DescribeSeries(series))
+	}
+	for _, s := range series {
+		if !exceeds(s, opts.Threshold) {
+			return fmt.Errorf("expected a point greater than %v in every series, got: %s", opts.Threshold, DescribeSeries(series))
+		}
+	}
+	if err := Series(series).AssertTags(opts.Tags); err != nil {
+		return err
+	}
+	if opts.Check != nil {
+		if err := opts.Check(series); err != nil {
+			return fmt.Errorf("%w, got: %s", err, DescribeSeries(series))
+		}
+	}
+	return nil
+}
+
+// exceeds returns whether a point of the series is greater than threshold.
+func exceeds(s datadog.Series, threshold float64) bool {
+	for _, p := range s.Points {
+		if p[1] != nil && *p[1] > threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeSeries returns a human readable summary of the series: their scope
+// and the value of their last point.
+func DescribeSeries(series []datadog.Series) string {
+	descriptions := make([]string, 0, len(series))
+	for _, s := range series {
+		last := "no point"
+		if len(s.Points) > 0 && s.Points[len(s.Points)-1][1] != nil {
+			last = fmt.Sprintf("%v", *s.Points[len(s.Points)-1][1])
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s (last: %s)", s.GetScope(), last))
+	}
+	return "[" + strings.Join(descriptions, ", ") + "]"
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// fakeClient returns its responses in turn, repeating the last one.
+type fakeClient struct {
+	responses [][]datadog.Series
+	queries   int
+	from      []int64
+}
+
+func (c *fakeClient) QueryMetrics(from, to int64, query string) ([]datadog.Series, error) {
+	c.from = append(c.from, from)
+	i := c.queries
+	if i >= len(c.responses) {
+		i = len(c.responses) - 1
+	}
+	c.queries++
+	return c.responses[i], nil
+}
+
+func newSeries(scope string, values ...float64) datadog.Series {
+	s := datadog.Series{Scope: datadog.String(scope)}
+	for i := range values {
+		s.Points = append(s.Points, datadog.DataPoint{datadog.Float64(float64(i)), &values[i]})
+	}
+	return s
+}
+
+func TestWaitForMetric(t *testing.T) {
+	client := &fakeClient{responses: [][]datadog.Series{
+		nil,
+		{newSeries("container:a", 1)},
+		{newSeries("container:a", 1), newSeries("container:b", 0)},
+		{newSeries("container:a", 1), newSeries("container:b", 0, 2)},
+	}}
+
+	series, err := WaitForMetric(client, "query", Options{MinSeries: 2, MetricQueryOptions: MetricQueryOptions{Interval: time.Millisecond}})
+	require.NoError(t, err)
+	assert.Len(t, series, 2)
+	assert.Equal(t, 4, client.queries)
+}
+
+func TestWaitForMetricError(t *testing.T) {
+	client := &fakeClient{responses: [][]datadog.Series{
+		{newSeries("container:a", 1), newSeries("container:b", 0)},
+	}}
+
+	_, err := WaitForMetric(client, "query", Options{Threshold: 0.5, MetricQueryOptions: MetricQueryOptions{Interval: time.Millisecond, Retries: 2}})
+	require.Error(t, err)
+	assert.Equal(t, 3, client.queries)
+	assert.Contains(t, err.Error(), `query "query" did not match after 3 attempt(s)`)
+	assert.Contains(t, err.Error(), "container:a (last: 1), container:b (last: 0)")
+}
+
+func TestWaitForMetricCheck(t *testing.T) {
+	client := &fakeClient{responses: [][]datadog.Series{
+		{newSeries("container:a", 1)},
+	}}
+
+	_, err := WaitForMetric(client, "query", Options{
+		Check:              func([]datadog.Series) error { return errors.New("not the right container") },
+		MetricQueryOptions: MetricQueryOptions{Interval: time.Millisecond, Retries: 1},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not the right container, got: [container:a (last: 1)]")
+}
+
+func TestWaitForMetricSince(t *testing.T) {
+	client := &fakeClient{responses: [][]datadog.Series{{newSeries("container:a", 1)}}}
+
+	since := time.Now().Add(-time.Minute)
+	_, err := WaitForMetric(client, "query", Options{Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{since.Unix()}, client.from)
+
+	client.from = nil
+	_, err = WaitForMetric(client, "query", Options{Since: since, MetricQueryOptions: MetricQueryOptions{Lookback: 10 * time.Second}})
+	require.NoError(t, err)
+	assert.True(t, client.from[0] > since.Unix())
+}
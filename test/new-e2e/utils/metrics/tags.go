@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// Series are the series returned by a query.
+type Series []datadog.Series
+
+// AssertTags returns an error unless every series carries the tags. A tag with
+// an empty value only has to be present, with any value. The error lists the
+// series with missing or different tags.
+func (series Series) AssertTags(tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var mismatches []string
+	for _, s := range series {
+		seriesTags := Tags(s)
+		var problems []string
+		for _, key := range keys {
+			value, ok := seriesTags[key]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("missing %s", key))
+			case tags[key] != "" && value != tags[key]:
+				problems = append(problems, fmt.Sprintf("%s is %q, not %q", key, value, tags[key]))
+			}
+		}
+		if len(problems) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s (%s)", s.GetScope(), strings.Join(problems, ", ")))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("unexpected tags in %d of %d series: %s", len(mismatches), len(series), strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// Tags returns the tags of the scope of the series. Tags without a value, like
+// "env", are returned with an empty value.
+func Tags(s datadog.Series) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(s.GetScope(), ",") {
+		if tag == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(tag, ":")
+		tags[key] = value
+	}
+	return tags
+}
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+func TestAssertTags(t *testing.T) {
+	series := Series{
+		newSeries("ecs_cluster_name:c,ecs_container_name:datadog-agent", 1),
+		newSeries("ecs_cluster_name:other,ecs_container_name:redis", 1),
+		newSeries("ecs_cluster_name:c", 1),
+	}
+
+	assert.NoError(t, series[:1].AssertTags(map[string]string{"ecs_cluster_name": "c", "ecs_container_name": ""}))
+
+	err := series.AssertTags(map[string]string{"ecs_cluster_name": "c", "ecs_container_name": ""})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected tags in 2 of 3 series")
+	assert.Contains(t, err.Error(), `ecs_cluster_name:other,ecs_container_name:redis (ecs_cluster_name is "other", not "c")`)
+	assert.Contains(t, err.Error(), "ecs_cluster_name:c (missing ecs_container_name)")
+}
+
+func TestWaitForMetricTags(t *testing.T) {
+	client := &fakeClient{responses: [][]datadog.Series{
+		{newSeries("ecs_cluster_name:c", 1)},
+		{newSeries("ecs_cluster_name:c,ecs_container_name:datadog-agent", 1)},
+	}}
+
+	_, err := WaitForMetric(client, "query", Options{
+		Tags:               map[string]string{"ecs_container_name": "datadog-agent"},
+		MetricQueryOptions: MetricQueryOptions{Interval: time.Millisecond},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.queries)
+}